@@ -6,26 +6,28 @@ package diskio
 
 import (
 	"bufio"
-	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/user"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
-const (
-	path = "tmp/"
-)
-
-var database string
-
 // ColumnDef is a name & type pair that represents a single col header in a table
 type ColumnDef struct {
 	ColumnName string
 	TypeName   string
+
+	// Table is the name (or alias) of the table a column was read from, when it's known
+	Table string
+
+	// Collation is how the column's text is compared, empty for the default of ignoring case
+	Collation string
 }
 
 // Set is essentially an in-memory soft copy of a table,
@@ -47,6 +49,9 @@ func SerializeColumnDefs(columnDefs []ColumnDef) string {
 
 	for index, columnDef := range columnDefs {
 		serializedColumnDef += columnDef.ColumnName + " " + columnDef.TypeName
+		if columnDef.Collation != "" {
+			serializedColumnDef += " COLLATE " + columnDef.Collation
+		}
 		if (index + 1) < len(columnDefs) {
 			serializedColumnDef += "|"
 		}
@@ -63,7 +68,14 @@ func ConstructColumnDefs(columnDefsLine string) []ColumnDef {
 
 	for _, columnDefsPair := range columnDefsPairs {
 		columnDefsPair := strings.Fields(columnDefsPair)
-		columnDefs = append(columnDefs, ColumnDef{ColumnName: columnDefsPair[0], TypeName: columnDefsPair[1]})
+		columnDef := ColumnDef{ColumnName: columnDefsPair[0], TypeName: columnDefsPair[1]}
+
+		// a column can be declared with a collation, name type COLLATE collation
+		if len(columnDefsPair) == 4 && strings.EqualFold(columnDefsPair[2], "COLLATE") {
+			columnDef.Collation = strings.ToUpper(columnDefsPair[3])
+		}
+
+		columnDefs = append(columnDefs, columnDef)
 	}
 
 	return columnDefs
@@ -75,60 +87,195 @@ func SerializeRecords(records [][]string) string {
 	var recordsSerialized string
 
 	for _, row := range records {
-		for colIndex, col := range row {
-			recordsSerialized += strings.Replace(col, "\n", "", -1)
-			if (colIndex + 1) < len(row) {
-				recordsSerialized += "|"
-			} else {
-				recordsSerialized += "\n"
-			}
-		}
+		recordsSerialized += EncodeRecord(row) + "\n"
 	}
 
 	return recordsSerialized
 }
 
-// ConstructRecords parses serialized records into ta 2D matrix
-func ConstructRecords(reader *bufio.Reader, recordAmount int) [][]string {
-	records := make([][]string, recordAmount)
+// Null is how a missing value is persisted in a record. It's the only field that's written
+// as it is with a \ in it, so text that's exactly \N can't be written to a table at all
+const Null = "\\N"
+
+// EncodeRecord writes a record out as a line of a table. A \, | or line break in a field is
+// escaped with a \, so no value can end its field or its record early
+func EncodeRecord(record []string) string {
+	fields := make([]string, len(record))
+	for i, field := range record {
+		fields[i] = encodeField(field)
+	}
+	return strings.Join(fields, "|")
+}
+
+// DecodeRecord splits a line of a table into its fields, undoing EncodeRecord
+func DecodeRecord(line string) []string {
+	if strings.Contains(line, "\\") == false {
+		return strings.Split(line, "|")
+	}
+
+	var fields []string
+	var field strings.Builder
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		if c == '\\' && i+1 < len(line) {
+			if unescaped, ok := unescapes[line[i+1]]; ok {
+				field.WriteString(unescaped)
+				i++
+				continue
+			}
+		}
 
-	for i := range records {
-		recordsLine, _ := reader.ReadString('\n')
-		recordsPair := strings.Split(recordsLine, "|")
-		records[i] = make([]string, 2)
-		records[i][0] = recordsPair[0]
-		records[i][1] = recordsPair[1]
+		if c == '|' {
+			fields = append(fields, field.String())
+			field.Reset()
+			continue
+		}
+		field.WriteByte(c)
 	}
 
-	return records
+	return append(fields, field.String())
 }
 
-// SelectSet fully parses a table into a set, it's basically an in-memory SelectAll
-func SelectSet(tableName string) Set {
-	// open the table file contents
-	f, err := os.Open(path + database + "/" + tableName)
-	check(err)
-	defer f.Close()
+// A TableReader streams a table's records one line at a time,
+// so reading a table never needs more than a record's worth of memory
+type TableReader struct {
+	ColumnDefs []ColumnDef
+	file       *os.File
+	reader     *bufio.Reader
+
+	// header is the column def line as it's written, so a rewritten table keeps it exactly
+	header string
+
+	// position is the byte offset the reader is at, and offset where the last record started
+	position int64
+	offset   int64
+}
+
+// OpenTable opens a table for reading and parses its column defs,
+// the reader is positioned at the first record
+func OpenTable(session *Session, tableName string) (*TableReader, error) {
+	f, err := os.Open(session.tablePath(tableName))
+	if err != nil {
+		return nil, err
+	}
 
-	// read in the metadata line, parse the table's col names
 	reader := bufio.NewReader(f)
+	columnDefsLine, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+
+	header := strings.TrimRight(columnDefsLine, "\r\n")
+
+	return &TableReader{ColumnDefs: ConstructColumnDefs(header), header: header, file: f, reader: reader, position: int64(len(columnDefsLine))}, nil
+}
 
-	columnDefsLine, _ := reader.ReadString('\n')
+// Next reads the next record, skipping blank lines, and returns io.EOF at the end of the table.
+// Records are always as wide as the header, ones written before a column was added are padded out with Null
+func (t *TableReader) Next() ([]string, error) {
+	for {
+		line, err := t.reader.ReadString('\n')
 
-	recordAmount := getAmountOfRecordsInTable(tableName)
+		t.offset = t.position
+		t.position += int64(len(line))
 
-	columnDefs := ConstructColumnDefs(columnDefsLine)
+		line = strings.TrimRight(line, "\r\n")
+		if len(strings.TrimSpace(line)) > 0 {
+			record := DecodeRecord(line)
+			for len(record) < len(t.ColumnDefs) {
+				record = append(record, Null)
+			}
+			return record[:len(t.ColumnDefs)], nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
 
-	records := ConstructRecords(reader, recordAmount)
+// Offset is where the record last returned by Next starts in the table's file,
+// it identifies the record for as long as the file isn't rewritten
+func (t *TableReader) Offset() int64 {
+	return t.offset
+}
 
-	set := Set{Name: tableName, ColumnDefs: columnDefs, Records: records}
+// SeekRecord moves the reader to a record's offset, so that Next reads it
+func (t *TableReader) SeekRecord(offset int64) error {
+	_, err := t.file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
 
-	return set
+	t.reader.Reset(t.file)
+	t.position = offset
+	return nil
+}
+
+// TableVersion identifies the current contents of a table by its file's size and modification time,
+// anything derived from the table is stale once its version changes
+func TableVersion(session *Session, tableName string) (string, error) {
+	info, err := os.Stat(session.tablePath(tableName))
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(info.Size(), 10) + ":" + strconv.FormatInt(info.ModTime().UnixNano(), 10), nil
+}
+
+// TableSize is the size of a table's file in bytes
+func TableSize(session *Session, tableName string) (int64, error) {
+	info, err := os.Stat(session.tablePath(tableName))
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Close closes the table's file
+func (t *TableReader) Close() error {
+	return t.file.Close()
+}
+
+// CountRecords counts the records in a table by reading through it
+func CountRecords(session *Session, tableName string) (int, error) {
+	table, err := OpenTable(session, tableName)
+	if err != nil {
+		return 0, err
+	}
+	defer table.Close()
+
+	count := 0
+	for {
+		_, err := table.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// ReadColumnDefs reads just the column def header of a table
+func ReadColumnDefs(session *Session, tableName string) ([]ColumnDef, error) {
+	table, err := OpenTable(session, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer table.Close()
+
+	return table.ColumnDefs, nil
 }
 
 // CheckIfDatabaseExists checks if a database directory exists
-func CheckIfDatabaseExists(name string) bool {
-	_, err := os.Stat(path + name)
+func CheckIfDatabaseExists(session *Session, name string) bool {
+	_, err := os.Stat(session.databasePath(name))
 	if os.IsNotExist(err) {
 		return false
 	}
@@ -136,14 +283,14 @@ func CheckIfDatabaseExists(name string) bool {
 }
 
 // CreateDatabase creates a database directory
-func CreateDatabase(name string) error {
-	err := os.Mkdir(path+name, os.ModePerm)
+func CreateDatabase(session *Session, name string) error {
+	err := os.Mkdir(session.databasePath(name), os.ModePerm)
 	return err
 }
 
 // CreateDatabaseMeta places a dotfile inside the database with brief details
-func CreateDatabaseMeta(name string) {
-	f, err := os.Create(path + name + "/" + ".meta")
+func CreateDatabaseMeta(session *Session, name string) {
+	f, err := os.Create(session.databasePath(name) + "/" + ".meta")
 	check(err)
 	defer f.Close()
 
@@ -155,29 +302,29 @@ func CreateDatabaseMeta(name string) {
 	f.WriteString("createdAt" + "|" + createdAt)
 }
 
-// UseDatabase stores the name of the database in memory
-func UseDatabase(name string) {
-	database = name
+// UseDatabase stores the name of the database in the session
+func UseDatabase(session *Session, name string) {
+	session.Database = name
 	return
 }
 
-// DeleteDatabase removes a database directory
-func DeleteDatabase(name string) error {
-	err := os.Remove(path + database + "/" + name)
+// DeleteDatabase removes a database directory, along with its tables and metadata
+func DeleteDatabase(session *Session, name string) error {
+	err := os.RemoveAll(session.databasePath(name))
 	return err
 }
 
-// CheckIfAnyDatabaseIsInUse checks if a database name is stored in mem
-func CheckIfAnyDatabaseIsInUse() bool {
-	if database == "" {
+// CheckIfAnyDatabaseIsInUse checks if a database name is stored in the session
+func CheckIfAnyDatabaseIsInUse(session *Session) bool {
+	if session.Database == "" {
 		return false
 	}
 	return true
 }
 
 // CheckIfTableExists does as named
-func CheckIfTableExists(name string) bool {
-	_, err := os.Stat(path + database + "/" + name)
+func CheckIfTableExists(session *Session, name string) bool {
+	_, err := os.Stat(session.tablePath(name))
 	if os.IsNotExist(err) {
 		return false
 	}
@@ -185,8 +332,8 @@ func CheckIfTableExists(name string) bool {
 }
 
 // CreateTable creates a table file and initializes its metadata
-func CreateTable(name string, columns []string, constraints []string) {
-	f, err := os.Create(path + database + "/" + name)
+func CreateTable(session *Session, name string, columns []string, constraints []string) {
+	f, err := os.Create(session.tablePath(name))
 	check(err)
 
 	defer f.Close()
@@ -199,399 +346,570 @@ func CreateTable(name string, columns []string, constraints []string) {
 	}
 }
 
-// DropTable deletes a table's file
-func DropTable(name string) {
-	err := os.Remove(path + database + "/" + name)
+// DropTable deletes a table's file, and the last row id it gave out
+func DropTable(session *Session, name string) {
+	err := os.Remove(session.tablePath(name))
 	check(err)
+
+	err = os.Remove(rowIDPath(session, name))
+	if err != nil && os.IsNotExist(err) == false {
+		check(err)
+	}
 }
 
-// AlterTable modifies a table's metadata
-func AlterTable(name string, method string, column string, constraint string) string {
-	fileContents, err := ioutil.ReadFile(path + database + "/" + name)
-	check(err)
+// AlterTable modifies a table's metadata. The header is rewritten into a copy
+// of the table that the records are streamed into, which then replaces the table
+func AlterTable(session *Session, name string, method string, column string, constraint string) {
+	if method != "ADD" {
+		return
+	}
 
-	f, err := os.Create(path + database + "/" + name)
+	f, err := os.Open(session.tablePath(name))
 	check(err)
 	defer f.Close()
 
-	if method == "ADD" {
-		f.WriteString(string(fileContents) + "|" + column + " " + constraint)
+	reader := bufio.NewReader(f)
+	columnDefsLine, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		check(err)
 	}
 
-	return SelectAll(name)
-}
+	altered, err := os.Create(session.tablePath(name) + ".alter")
+	check(err)
+
+	header := strings.TrimRight(columnDefsLine, "\r\n") + "|" + column + " " + constraint
+	if strings.HasSuffix(columnDefsLine, "\n") {
+		header += "\n"
+	}
 
-// SelectAll selects all records in a table
-func SelectAll(name string) string {
-	fileContents, err := ioutil.ReadFile(path + database + "/" + name)
+	altered.WriteString(header)
+	_, err = io.Copy(altered, reader)
 	check(err)
-	return string(fileContents)
+	check(altered.Close())
+
+	check(os.Rename(session.tablePath(name)+".alter", session.tablePath(name)))
 }
 
-// SelectWhere selects a subset of records in a table, provided a clause
-func SelectWhere(table string, colNames []string, whereColName string, whereColVal string) string {
+// InsertRecords appends records to a table, returning the row id of the last. Row ids count up from 1,
+// and one is never handed out twice by a table, even once the record it was given to is deleted.
+// The records' row ids are handed out together, and they're written with one append
+func InsertRecords(session *Session, name string, records [][]string) (int, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
 
-	// open the table file contents
-	f, err := os.Open(path + database + "/" + table)
-	check(err)
-	defer f.Close()
+	first, err := reserveRowIDs(session, name, len(records))
+	if err != nil {
+		return 0, err
+	}
 
-	// read in the metadata line, parse the table's col names
-	reader := bufio.NewReader(f)
-	tableMetaLine, _ := reader.ReadString('\n')
-	colDefs := strings.Split(tableMetaLine, "|")
+	err = AppendRecords(session, name, records)
+	if err != nil {
+		return 0, err
+	}
 
-	// find pertinent offsets of clause
-	var firstColOffset int
-	var secondColOffset int
-	var whereColOffset int
+	return first + len(records) - 1, nil
+}
 
-	for i := range colDefs {
-		if strings.EqualFold(strings.Fields(colDefs[i])[0], whereColName) {
-			whereColOffset = i
-		}
-		if strings.EqualFold(strings.Fields(colDefs[i])[0], colNames[0]) {
-			firstColOffset = i
-		}
-		if strings.EqualFold(strings.Fields(colDefs[i])[0], colNames[1]) {
-			secondColOffset = i
-		}
+// AppendRecords writes records to the end of a table, without giving them row ids like InsertRecords
+func AppendRecords(session *Session, name string, records [][]string) error {
+
+	// Open the table in append mode
+	f, err := os.OpenFile(session.tablePath(name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	check(err)
+	defer f.Close()
+
+	// construct the new records, each on a line of its own
+	var writeBuffer strings.Builder
+	for _, record := range records {
+		writeBuffer.WriteString("\n" + EncodeRecord(record))
 	}
 
-	// initialize our selection subset
-	var selection string
+	// write the new records to the end of the table
+	_, err = f.Write([]byte(writeBuffer.String()))
+	return err
+}
+
+// A TableRewriter steps through a table's records like a TableReader, and writes each one to a copy of the
+// table as it moves past it. The record it's at can be replaced first, or left out of the copy. The copy
+// replaces the table once it's committed, but only if a record was replaced, and it's thrown away if the
+// rewriter is closed first, so a failure part way leaves the table alone
+type TableRewriter struct {
+	reader *TableReader
+	path   string
+	file   *os.File
+	writer *bufio.Writer
 
-	// populate selection by iterating through records
-	for i := 0; i < getAmountOfRecordsInTable(table); i++ {
-		record, _ := reader.ReadString('\n')
-		records := strings.Split(record, "|")
+	// record is what's written to the copy for the record the rewriter is at, nil for nothing
+	record  []string
+	changed bool
+	done    bool
+}
 
-		if strings.EqualFold(strings.TrimSpace(records[whereColOffset]), strings.TrimSpace(whereColVal)) == false {
-			selection = records[firstColOffset] + "|" + records[secondColOffset]
-		}
+// RewriteTable opens a table for rewriting, the rewriter is positioned before the first record
+func RewriteTable(session *Session, table string) (*TableRewriter, error) {
+	reader, err := OpenTable(session, table)
+	if err != nil {
+		return nil, err
 	}
 
-	// make new table meta based on queried cols
-	newMeta := strings.TrimSpace(colDefs[firstColOffset]) + "|" + colDefs[secondColOffset]
+	path := session.tablePath(table)
+	file, err := os.Create(path + ".rewrite")
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
 
-	// append selection meta to selection's resultant records
-	result := newMeta + selection
+	writer := bufio.NewWriter(file)
+	writer.WriteString(reader.header)
 
-	return string(result)
+	return &TableRewriter{reader: reader, path: path, file: file, writer: writer}, nil
 }
 
-// InsertRecord inserts a single record to a table
-func InsertRecord(name string, records []string) error {
+// Next writes the record the rewriter is at to the copy, and reads the next one. It returns io.EOF at the end of the table
+func (t *TableRewriter) Next() ([]string, error) {
+	t.write()
 
-	// Open the table in append mode
-	f, err := os.OpenFile(path+database+"/"+name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	check(err)
-	defer f.Close()
+	record, err := t.reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	t.record = record
+	return record, nil
+}
 
-	var writeBuffer string
+// Offset is where the record last returned by Next starts in the table's file
+func (t *TableRewriter) Offset() int64 {
+	return t.reader.Offset()
+}
 
-	writeBuffer += "\n"
+// Replace writes a record to the copy in place of the one the rewriter is at, or leaves it out if that's nil
+func (t *TableRewriter) Replace(record []string) {
+	t.record = record
+	t.changed = true
+}
 
-	// construct new record from records
-	for i := 0; i < len(records); i++ {
-		if i > 0 {
-			writeBuffer += "|"
+// write writes the record the rewriter is at to the copy, once
+func (t *TableRewriter) write() {
+	if t.record != nil {
+		t.writer.WriteString("\n" + EncodeRecord(t.record))
+	}
+	t.record = nil
+}
+
+// Commit writes the rest of the table to the copy, and swaps the copy in for the table if a record was replaced
+func (t *TableRewriter) Commit() error {
+	for {
+		_, err := t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Close()
+			return err
 		}
-		writeBuffer += records[i]
 	}
 
-	// write the new record to the end of the table
-	f2, err2 := f.Write([]byte(writeBuffer))
-	if f2 == 0 {
-		return err
+	if t.changed == false {
+		return t.Close()
 	}
-	check(err2)
 
+	t.done = true
+	t.reader.Close()
+
+	err := t.writer.Flush()
+	if err == nil {
+		err = t.file.Close()
+	}
+	if err == nil {
+		err = os.Rename(t.path+".rewrite", t.path)
+	}
+	if err != nil {
+		os.Remove(t.path + ".rewrite")
+	}
 	return err
 }
 
-// UpdateRecord updates a record
-// get line 0, count how many cols it takes to find whereCol, store as colOffset
-// iterate thru lines 1 - n until row[colOffset] == whereValue
-// store that line (record) -- like it's selected
-// look in selected record for toCol, replace record[toCol] with toValue
-func UpdateRecord(table string, whereCol string, whereValue string, toCol string, toValue string) int {
+// Close closes the table, throwing the copy away unless it was committed
+func (t *TableRewriter) Close() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
 
-	recordAmount := getAmountOfRecordsInTable(table)
-	colNames := getTableColNames(table)
-	toColOffset := getIndexOfColName(colNames, toCol)
-	recordsModified := 0
+	t.reader.Close()
+	t.file.Close()
+	return os.Remove(t.path + ".rewrite")
+}
 
-	// for each record
-	for i := 0; i < recordAmount; i++ {
+// A RowSet identifies some records of a table by the offsets they start at in its file, in order.
+// Offsets only identify records in the version of the table they were read from, as a rewrite moves them
+type RowSet struct {
+	Table   string
+	Version string
+	Offsets []int64
+}
 
-		// open table file
-		f, err := os.Open(path + database + "/" + table)
-		check(err)
-		defer f.Close()
+// MatchRecords reads through a table, collecting the offset of every record match accepts.
+// match sees each whole record, padded out to the width of the header
+func MatchRecords(session *Session, table string, match func(record []string) (bool, error)) (*RowSet, error) {
+	version, err := TableVersion(session, table)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := OpenTable(session, table)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
 
-		// open reader on table file contents
-		reader := bufio.NewReader(f)
-		for rp := 0; rp <= i; rp++ {
-			reader.ReadString('\n')
+	rows := &RowSet{Table: table, Version: version}
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
 		}
 
-		// read record and split by colVals
-		record, _ := reader.ReadString('\n')
-		records := strings.Split(record, "|")
+		matched, err := match(record)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			rows.Offsets = append(rows.Offsets, reader.Offset())
+		}
+	}
+}
+
+// UpdateRecords replaces each record of a row set with what update makes of it, returning how many were updated
+func UpdateRecords(session *Session, rows *RowSet, update func(record []string) ([]string, error)) (int, error) {
+	return rewriteRecords(session, rows, update)
+}
 
-		// for each colVal in record
-		for j := range records {
+// DeleteRecords deletes each record of a row set, returning how many were deleted
+func DeleteRecords(session *Session, rows *RowSet) (int, error) {
+	return rewriteRecords(session, rows, func(record []string) ([]string, error) {
+		return nil, nil
+	})
+}
 
-			// if colVal matches whereValue
-			if strings.EqualFold(strings.TrimSpace(records[j]), strings.TrimSpace(whereValue)) {
+// TruncateTable empties a table without reading its records, leaving just its column def header.
+// The header is written to a copy which replaces the table, so indexes of it are rebuilt once they're used
+func TruncateTable(session *Session, table string) error {
+	reader, err := OpenTable(session, table)
+	if err != nil {
+		return err
+	}
+	header := reader.header
+	reader.Close()
 
-				// replace colVal with toValue
-				newRecords := records
-				newRecords[toColOffset] = toValue
+	err = ioutil.WriteFile(session.tablePath(table)+".rewrite", []byte(header), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(session.tablePath(table)+".rewrite", session.tablePath(table))
+}
+
+//
+//			Helper functions
+//
 
-				// rebuild record
-				newRecord := strings.Join(newRecords, "|")
+// rewriteRecords streams a table's records into a copy of the table, which then replaces it like AlterTable's does.
+// A record of the row set is written as what rewrite gives back for it, or left out if that's nil, every other
+// record is written as it was. Nothing is replaced if rewrite fails, or if the table has changed since the
+// row set was read from it, and nothing is written at all for an empty row set
+func rewriteRecords(session *Session, rows *RowSet, rewrite func(record []string) ([]string, error)) (int, error) {
+	if len(rows.Offsets) == 0 {
+		return 0, nil
+	}
 
-				if strings.Contains(newRecord, "\n") == false {
-					newRecord += "\n"
-				}
+	version, err := TableVersion(session, rows.Table)
+	if err != nil {
+		return 0, err
+	}
+	if version != rows.Version {
+		return 0, errors.New("!Failed to write table " + rows.Table + " because it changed after its records were matched.")
+	}
 
-				replaceRecord(table, record, newRecord)
+	rewriter, err := RewriteTable(session, rows.Table)
+	if err != nil {
+		return 0, err
+	}
+	defer rewriter.Close()
+
+	// both the row set and the rewriter go through the file in order, so next is the offset of the next record to rewrite
+	next := 0
+	for next < len(rows.Offsets) {
+		record, err := rewriter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
 
-				// save the last updated index
-				recordsModified++
+		if rewriter.Offset() == rows.Offsets[next] {
+			record, err = rewrite(record)
+			if err != nil {
+				return 0, err
 			}
+			rewriter.Replace(record)
+			next++
 		}
 	}
 
-	return recordsModified
+	return next, rewriter.Commit()
 }
 
-// DeleteRecord deletes a record that matches a clause
-func DeleteRecord(table string, whereCol string, whereValue string, comparator string) int {
+// escapes are what EncodeRecord writes in place of each character that has to be escaped, and unescapes undo them.
+// A \N is left as it is, it's only ever a whole field
+var escapes = map[rune]string{'\\': "\\\\", '|': "\\|", '\n': "\\n", '\r': "\\r"}
+var unescapes = map[byte]string{'\\': "\\", '|': "|", 'n': "\n", 'r': "\r"}
 
-	recordAmount := getAmountOfRecordsInTable(table)
+func encodeField(field string) string {
+	if field == Null || strings.ContainsAny(field, "\\|\n\r") == false {
+		return field
+	}
 
-	f, err := os.Open(path + database + "/" + table)
-	check(err)
-	defer f.Close()
+	var encoded strings.Builder
+	for _, r := range field {
+		if escaped, ok := escapes[r]; ok {
+			encoded.WriteString(escaped)
+		} else {
+			encoded.WriteRune(r)
+		}
+	}
+	return encoded.String()
+}
 
-	reader := bufio.NewReader(f)
-	tableMetaLine, _ := reader.ReadString('\n')
-	colDefs := strings.Split(tableMetaLine, "|")
+// reserveRowIDs hands out n row ids after the last one a table gave out, which is kept in a file next to it,
+// and returns the first. A table without one, like one written before row ids were kept, starts counting
+// after the records it has
+func reserveRowIDs(session *Session, table string, n int) (int, error) {
+	var last int
 
-	// break the constraint off the colName
-	colNames := make([]string, 0, len(colDefs))
-	for i := range colDefs {
-		colNames = append(colNames, strings.Fields(colDefs[i])[0])
+	contents, err := ioutil.ReadFile(rowIDPath(session, table))
+	switch {
+	case err == nil:
+		last, err = strconv.Atoi(strings.TrimSpace(string(contents)))
+	case os.IsNotExist(err):
+		last, err = CountRecords(session, table)
+	}
+	if err != nil {
+		return 0, err
 	}
 
-	recordsModified := 0
+	err = ioutil.WriteFile(rowIDPath(session, table), []byte(strconv.Itoa(last+n)), 0644)
+	if err != nil {
+		return 0, err
+	}
 
-	// for each record
-	for i := 0; i < recordAmount; i++ {
+	return last + 1, nil
+}
 
-		// open table file
-		f, err := os.Open(path + database + "/" + table)
-		check(err)
-		defer f.Close()
+func rowIDPath(session *Session, table string) string {
+	return session.tablePath(table) + ".rowid"
+}
 
-		// open reader on table file contents
-		reader := bufio.NewReader(f)
-		for rp := 0; rp <= i; rp++ {
-			reader.ReadString('\n')
-		}
+// lock modes, persisted alongside the owning session in a .lock file
+const (
+	sharedLock    = "shared"
+	exclusiveLock = "exclusive"
+)
 
-		// read record and split by colVals
-		record, _ := reader.ReadString('\n')
-		records := strings.Split(record, "|")
+// A lockHolder is a single line of a .lock file
+type lockHolder struct {
+	owner string
+	mode  string
+}
 
-		var whereColOffset int
-		for i := range colNames {
-			if strings.EqualFold(colNames[i], whereCol) {
-				whereColOffset = i
-			}
-		}
+// LockTable takes an exclusive lock on a table, upgrading any shared lock the session holds
+func LockTable(session *Session, tableName string) {
+	writeLock(session, tableName, exclusiveLock)
+}
 
-		// for each colVal in record
-		for j := range records {
-
-			// if colVal matches whereValue
-			if strings.EqualFold(strings.TrimSpace(comparator), "GREATER_THAN") {
-
-				if j == whereColOffset {
-					curVal := strings.TrimSpace(records[j])
-					whereVal := strings.TrimSpace(whereValue)
-
-					// parse existing records into floats to allow comparison
-					curValFloat, err := strconv.ParseFloat(curVal, 32)
-					if err != nil {
-						fmt.Println(err)
-						os.Exit(1)
-					}
-
-					whereValFloat, err2 := strconv.ParseFloat(whereVal, 32)
-					if err2 != nil {
-						fmt.Println(err)
-						os.Exit(1)
-					}
-
-					if curValFloat > whereValFloat {
-						removeRecord(table, record)
-						// save the last updated index
-						recordsModified++
-					}
-				}
-
-			} else if strings.EqualFold(strings.TrimSpace(comparator), "EQUALS") {
-
-				if strings.EqualFold(strings.TrimSpace(records[j]), strings.TrimSpace(whereValue)) {
-					removeRecord(table, record)
-					recordsModified++
-				}
-			}
+// LockTableShared takes a shared lock on a table, unless the session already holds a lock on it
+func LockTableShared(session *Session, tableName string) {
+	for _, holder := range readLockHolders(session, tableName) {
+		if holder.owner == session.Owner {
+			return
 		}
 	}
 
-	return recordsModified
+	writeLock(session, tableName, sharedLock)
 }
 
-//
-//			Helper functions
-//
+// UnlockTable releases the session's lock on a table,
+// the .lock file is removed once nobody holds it anymore
+func UnlockTable(session *Session, tableName string) {
+	if CheckIfTableIsLocked(session, tableName) == false {
+		return
+	}
 
-func getIndexOfColName(colNames []string, colName string) int {
-	var index int
+	holders := otherLockHolders(session, tableName)
 
-	for i := range colNames {
-		if colNames[i] == colName {
-			index = i
-		}
+	if len(holders) == 0 {
+		err := os.Remove(lockPath(session, tableName))
+		check(err)
+	} else {
+		writeLockHolders(session, tableName, holders)
 	}
 
-	return index
+	delete(session.heldLocks, tableName)
 }
 
-func getTableColNames(table string) []string {
-	f, err := os.Open(path + database + "/" + table)
-	check(err)
-	defer f.Close()
-
-	reader := bufio.NewReader(f)
-	tableMetaLine, _ := reader.ReadString('\n')
-	colDefs := strings.Split(tableMetaLine, "|")
-
-	colNames := make([]string, 0, len(colDefs))
-	for i := range colDefs {
-		colNames = append(colNames, strings.Fields(colDefs[i])[0])
+// UnlockAllTables releases every lock held by the session
+func UnlockAllTables(session *Session) {
+	for tableName := range session.heldLocks {
+		UnlockTable(session, tableName)
 	}
-	return colNames
 }
 
-func replaceRecord(table string, record string, newRecord string) {
+// CheckIfTableIsLocked checks if any session holds a lock on a table
+func CheckIfTableIsLocked(session *Session, tableName string) bool {
+	_, err := os.Stat(lockPath(session, tableName))
 
-	// open up another instance of table file
-	input, err := ioutil.ReadFile(path + database + "/" + table)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if os.IsNotExist(err) {
+		return false
 	}
 
-	// replace the record with our new record
-	output := bytes.Replace(input, []byte(record), []byte(newRecord), -1)
+	return true
+}
 
-	// save write to the new file
-	if err = ioutil.WriteFile(path+database+"/"+table, output, 0666); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+// CheckIfTableIsLockedByOtherSession checks if another session holds any lock on a table,
+// meaning this session may not write to it
+func CheckIfTableIsLockedByOtherSession(session *Session, tableName string) bool {
+	return len(otherLockHolders(session, tableName)) > 0
 }
 
-func removeRecord(table string, record string) {
-	// open up another instance of table file
-	input, err := ioutil.ReadFile(path + database + "/" + table)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// CheckIfTableIsExclusivelyLockedByOtherSession checks if another session holds an
+// exclusive lock on a table, meaning this session may not take a shared lock on it
+func CheckIfTableIsExclusivelyLockedByOtherSession(session *Session, tableName string) bool {
+	for _, holder := range otherLockHolders(session, tableName) {
+		if holder.mode == exclusiveLock {
+			return true
+		}
 	}
 
-	// replace the record with our new record
-	output := bytes.Replace(input, []byte(record), []byte(""), -1)
+	return false
+}
 
-	// save write to the new file
-	if err = ioutil.WriteFile(path+database+"/"+table, output, 0666); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+//
+//			Lock helpers
+//
+
+func lockPath(session *Session, tableName string) string {
+	return session.tablePath(tableName) + ".lock"
 }
 
-func getAmountOfRecordsInTable(table string) int {
-	file, _ := os.Open(path + database + "/" + table)
-	fileScanner := bufio.NewScanner(file)
-	recordCount := -1
-	for fileScanner.Scan() {
-		recordCount++
-	}
-	return recordCount
+// writeLock replaces the session's entry in a table's .lock file
+func writeLock(session *Session, tableName string, mode string) {
+	holders := append(otherLockHolders(session, tableName), lockHolder{owner: session.Owner, mode: mode})
+	writeLockHolders(session, tableName, holders)
+	session.heldLocks[tableName] = true
 }
 
-// LockTable ...
-func LockTable(tableName string) {
-	f, err := os.Create(path + database + "/" + tableName + ".lock")
+func writeLockHolders(session *Session, tableName string, holders []lockHolder) {
+	f, err := os.Create(lockPath(session, tableName))
 	check(err)
-
 	defer f.Close()
 
-	f.WriteString(strconv.Itoa(os.Getpid()))
-	f.WriteString("\n")
+	for _, holder := range holders {
+		f.WriteString(holder.owner + " " + holder.mode + "\n")
+	}
 }
 
-// UnlockTable ...
-func UnlockTable(tableName string) {
-	if CheckIfTableIsLocked(tableName) {
-		err := os.Remove(path + database + "/" + tableName + ".lock")
-		check(err)
+// readLockHolders reads every live entry of a table's .lock file. An entry whose owner's
+// process has exited is stale, as nothing is left to release it, so it's reclaimed
+func readLockHolders(session *Session, tableName string) []lockHolder {
+	holders := scanLockHolders(session, tableName)
+
+	var live []lockHolder
+	for _, holder := range holders {
+		if ownerIsAlive(holder.owner) {
+			live = append(live, holder)
+		}
+	}
+
+	if len(live) == len(holders) {
+		return live
+	}
+
+	if len(live) == 0 {
+		err := os.Remove(lockPath(session, tableName))
+		if err != nil && os.IsNotExist(err) == false {
+			check(err)
+		}
+	} else {
+		writeLockHolders(session, tableName, live)
 	}
+
+	return live
 }
 
-// CheckIfTableIsLocked ...
-func CheckIfTableIsLocked(tableName string) bool {
-	_, err := os.Stat(path + database + "/" + tableName + ".lock")
+// scanLockHolders reads every entry of a table's .lock file,
+// a line holding only an owner is treated as an exclusive lock
+func scanLockHolders(session *Session, tableName string) []lockHolder {
+	var holders []lockHolder
 
+	f, err := os.Open(lockPath(session, tableName))
 	if os.IsNotExist(err) {
-		return false
+		return holders
 	}
+	check(err)
+	defer f.Close()
 
-	return true
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		holder := lockHolder{owner: fields[0], mode: exclusiveLock}
+		if len(fields) > 1 {
+			holder.mode = fields[1]
+		}
+
+		holders = append(holders, holder)
+	}
+
+	return holders
 }
 
-// CheckIfTableIsLockedByOtherProcess ...
-func CheckIfTableIsLockedByOtherProcess(tableName string) bool {
-	_, err := os.Stat(path + database + "/" + tableName + ".lock")
+// ownerIsAlive checks if the process that wrote a lock is still running. Owners start with the pid
+// of their process, one that doesn't, like a lock written by an older sqlit, is taken to be alive
+func ownerIsAlive(owner string) bool {
+	pid, err := strconv.Atoi(strings.SplitN(owner, ":", 2)[0])
+	if err != nil || pid == os.Getpid() {
+		return true
+	}
 
-	if os.IsNotExist(err) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
 		return false
-	} else {
-		// open table file
-		f, err := os.Open(path + database + "/" + tableName + ".lock")
-		check(err)
-		defer f.Close()
+	}
 
-		// open reader on lock file contents
-		reader := bufio.NewReader(f)
-		str, err := reader.ReadString('\n')
-		check(err)
+	// signal 0 only checks the process exists, it's denied when it belongs to another user
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
 
-		// if the process ID stored in the lock file is the same, it's locked by this process so we can ignore it
-		if str == strconv.Itoa(os.Getpid()) {
-			return false
-		} else {
-			return true
+func otherLockHolders(session *Session, tableName string) []lockHolder {
+	var others []lockHolder
+
+	for _, holder := range readLockHolders(session, tableName) {
+		if holder.owner != session.Owner {
+			others = append(others, holder)
 		}
 	}
 
-	return true
+	return others
 }
 
 // func dropTable() {}
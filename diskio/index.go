@@ -0,0 +1,302 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package diskio
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IndexDef names an index and the column of the table it's on.
+// Every index of a database is listed in its .indexes file
+type IndexDef struct {
+	Name   string
+	Table  string
+	Column string
+}
+
+// An index's file starts with the version of the table it was built from, followed by
+// one "key|offset" entry per record, sorted by key. The offset is where the record
+// starts in the table's file. Nothing keeps an index up to date as its table is
+// written, instead it's rebuilt whenever it's used and its version is stale
+
+// ReadIndexDefs reads every index def of the database in use
+func ReadIndexDefs(session *Session) []IndexDef {
+	contents, err := ioutil.ReadFile(session.indexCatalogPath())
+	if err != nil {
+		return nil
+	}
+
+	var indexes []IndexDef
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			continue
+		}
+		indexes = append(indexes, IndexDef{Name: fields[0], Table: fields[1], Column: fields[2]})
+	}
+
+	return indexes
+}
+
+// ReadIndexDefsOfTable reads the index defs on one table
+func ReadIndexDefsOfTable(session *Session, tableName string) []IndexDef {
+	var indexes []IndexDef
+	for _, index := range ReadIndexDefs(session) {
+		if index.Table == tableName {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// CheckIfIndexExists does as named, index names are case insensitive
+func CheckIfIndexExists(session *Session, name string) bool {
+	_, found := FindIndexDef(session, name)
+	return found
+}
+
+// FindIndexDef looks up an index by name
+func FindIndexDef(session *Session, name string) (IndexDef, bool) {
+	for _, index := range ReadIndexDefs(session) {
+		if strings.EqualFold(index.Name, name) {
+			return index, true
+		}
+	}
+	return IndexDef{}, false
+}
+
+// CreateIndexMeta adds an index def to the database's catalog, its file is built separately
+func CreateIndexMeta(session *Session, index IndexDef) {
+	writeIndexDefs(session, append(ReadIndexDefs(session), index))
+}
+
+// DropIndex removes an index def and its file
+func DropIndex(session *Session, name string) {
+	var remaining []IndexDef
+	for _, index := range ReadIndexDefs(session) {
+		if strings.EqualFold(index.Name, name) {
+			os.Remove(session.indexPath(index))
+			continue
+		}
+		remaining = append(remaining, index)
+	}
+
+	writeIndexDefs(session, remaining)
+}
+
+// DropIndexesOfTable removes every index on a table, for when it's dropped
+func DropIndexesOfTable(session *Session, tableName string) {
+	for _, index := range ReadIndexDefsOfTable(session, tableName) {
+		DropIndex(session, index.Name)
+	}
+}
+
+// An IndexWriter writes out a new file for an index, which replaces the old one once it's closed
+type IndexWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	path   string
+}
+
+// CreateIndexFile starts writing an index's file for a version of its table
+func CreateIndexFile(session *Session, index IndexDef, version string) (*IndexWriter, error) {
+	path := session.indexPath(index)
+
+	f, err := os.Create(path + ".new")
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(f)
+	writer.WriteString(version + "\n")
+
+	return &IndexWriter{file: f, writer: writer, path: path}, nil
+}
+
+// Write adds the next entry, entries must be written in order
+func (w *IndexWriter) Write(key string, offset int64) error {
+	_, err := w.writer.WriteString(encodeField(key) + "|" + strconv.FormatInt(offset, 10) + "\n")
+	return err
+}
+
+// Close finishes the file and moves it into place
+func (w *IndexWriter) Close() error {
+	err := w.writer.Flush()
+	if err != nil {
+		w.file.Close()
+		os.Remove(w.file.Name())
+		return err
+	}
+
+	err = w.file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(w.file.Name(), w.path)
+}
+
+// An IndexReader looks up entries in an index's file by byte position. Positions
+// are where entries start, from Start up to the end of the file
+type IndexReader struct {
+	Version string
+	file    *os.File
+	start   int64
+	size    int64
+}
+
+// OpenIndex opens an index's file and reads its version
+func OpenIndex(session *Session, index IndexDef) (*IndexReader, error) {
+	f, err := os.Open(session.indexPath(index))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r := &IndexReader{file: f, size: info.Size()}
+
+	version, next, err := r.readLine(0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r.Version = version
+	r.start = next
+	return r, nil
+}
+
+// Start is the position of the first entry
+func (r *IndexReader) Start() int64 {
+	return r.start
+}
+
+// Entry reads the entry at a position, along with the position of the one after it.
+// It returns io.EOF past the last entry
+func (r *IndexReader) Entry(position int64) (string, int64, int64, error) {
+	if position >= r.size {
+		return "", 0, position, io.EOF
+	}
+
+	line, next, err := r.readLine(position)
+	if err != nil {
+		return "", 0, next, err
+	}
+
+	separator := strings.LastIndex(line, "|")
+	if separator < 0 {
+		return "", 0, next, io.ErrUnexpectedEOF
+	}
+
+	offset, err := strconv.ParseInt(line[separator+1:], 10, 64)
+	return DecodeRecord(line[:separator])[0], offset, next, err
+}
+
+// Search binary searches the sorted entries for the first one whose key isn't less
+// than what's looked for, and returns its position. Entries are variable length, so
+// the search probes byte positions and reads the first whole entry after each one
+func (r *IndexReader) Search(less func(key string) bool) (int64, error) {
+	low, high := r.start, r.size
+
+	for low < high {
+		middle := low + (high-low)/2
+
+		position, err := r.entryStart(middle)
+		if err != nil {
+			return 0, err
+		}
+
+		// no entry starts between the middle and high, so only the one at low is left to check
+		if position >= high {
+			position = low
+		}
+
+		key, _, next, err := r.Entry(position)
+		if err != nil {
+			return 0, err
+		}
+
+		if less(key) {
+			low = next
+		} else {
+			high = position
+		}
+	}
+
+	return low, nil
+}
+
+// Close ...
+func (r *IndexReader) Close() error {
+	return r.file.Close()
+}
+
+// entryStart finds the first entry that starts at or after a position
+func (r *IndexReader) entryStart(position int64) (int64, error) {
+	if position <= r.start {
+		return r.start, nil
+	}
+
+	// an entry starts right after a newline
+	_, next, err := r.readLine(position - 1)
+	return next, err
+}
+
+// readLine reads from a position up to the end of its line, and returns the position after it
+func (r *IndexReader) readLine(position int64) (string, int64, error) {
+	var line []byte
+	buffer := make([]byte, 256)
+
+	for {
+		n, err := r.file.ReadAt(buffer, position+int64(len(line)))
+
+		if newline := bytes.IndexByte(buffer[:n], '\n'); newline >= 0 {
+			line = append(line, buffer[:newline]...)
+			return string(line), position + int64(len(line)) + 1, nil
+		}
+
+		line = append(line, buffer[:n]...)
+
+		if err == io.EOF {
+			return string(line), position + int64(len(line)), nil
+		}
+		if err != nil {
+			return "", position, err
+		}
+	}
+}
+
+//
+//			Helper functions
+//
+
+func writeIndexDefs(session *Session, indexes []IndexDef) {
+	var lines string
+	for _, index := range indexes {
+		lines += index.Name + "|" + index.Table + "|" + index.Column + "\n"
+	}
+
+	err := ioutil.WriteFile(session.indexCatalogPath(), []byte(lines), 0644)
+	check(err)
+}
+
+// indexCatalogPath is where the database in use lists its indexes
+func (session *Session) indexCatalogPath() string {
+	return session.databasePath(session.Database) + "/.indexes"
+}
+
+// indexPath is where an index's file lives, next to its table
+func (session *Session) indexPath(index IndexDef) string {
+	return session.tablePath(index.Table) + "." + strings.ToLower(index.Name) + ".idx"
+}
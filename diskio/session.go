@@ -0,0 +1,88 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package diskio
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Isolation levels a transaction can run under. Locks are table-granular:
+// READ COMMITTED only locks tables it writes, REPEATABLE READ also holds a
+// shared lock on every table it reads, and SERIALIZABLE reads under an
+// exclusive lock so that no two transactions ever touch the same table at once.
+const (
+	ReadCommitted  = "READ COMMITTED"
+	RepeatableRead = "REPEATABLE READ"
+	Serializable   = "SERIALIZABLE"
+)
+
+// IsolationLevels lists every supported isolation level
+var IsolationLevels = []string{ReadCommitted, RepeatableRead, Serializable}
+
+// sessionCount numbers the sessions opened by this process
+var sessionCount int64
+
+// Session is a single connection's view of the disk: the data directory it
+// works in, the database it's using and the transaction it has in progress.
+// Every disk operation is performed on behalf of a session.
+type Session struct {
+	// Directory is the data directory holding every database
+	Directory string
+
+	// Database is the name of the database in use, if any
+	Database string
+
+	InTransactionMode bool
+
+	// IsolationLevel is the session's level, applied to every transaction begun after it's set
+	IsolationLevel string
+
+	// TransactionIsolationLevel is the level the current transaction runs under
+	TransactionIsolationLevel string
+
+	// Owner identifies the session in .lock files, it's unique across processes
+	Owner string
+
+	// heldLocks remembers every table the session currently holds a lock on
+	heldLocks map[string]bool
+}
+
+// NewSession starts a session on a data directory
+func NewSession(directory string) *Session {
+	id := atomic.AddInt64(&sessionCount, 1)
+
+	return &Session{
+		Directory:      directory,
+		IsolationLevel: ReadCommitted,
+		Owner:          strconv.Itoa(os.Getpid()) + ":" + strconv.FormatInt(id, 10),
+		heldLocks:      map[string]bool{},
+	}
+}
+
+// Snapshot copies the session as it is now, for reading a query's rows after the session has
+// moved on: they're read from the database that was in use, even once another one is. The copy
+// doesn't share the session's locks, so it can be read from without holding the session
+func (session *Session) Snapshot() *Session {
+	return &Session{
+		Directory:                 session.Directory,
+		Database:                  session.Database,
+		InTransactionMode:         session.InTransactionMode,
+		IsolationLevel:            session.IsolationLevel,
+		TransactionIsolationLevel: session.TransactionIsolationLevel,
+		Owner:                     session.Owner,
+		heldLocks:                 map[string]bool{},
+	}
+}
+
+// databasePath is where a database's directory lives
+func (session *Session) databasePath(name string) string {
+	return strings.TrimSuffix(session.Directory, "/") + "/" + name
+}
+
+// tablePath is where a table's file lives inside the database in use
+func (session *Session) tablePath(name string) string {
+	return session.databasePath(session.Database) + "/" + name
+}
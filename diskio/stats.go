@@ -0,0 +1,118 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package diskio
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+// TableStats is what ANALYZE learned about a table, for the planner to estimate with
+type TableStats struct {
+	Table   string
+	Rows    int
+	Columns []ColumnStats
+}
+
+// ColumnStats describes the values of one column. Histogram is an equi-depth histogram
+// of the non-NULL values: the smallest value, then the last value of each bucket holding
+// an equal share of them, all in their persisted form
+type ColumnStats struct {
+	Column    string
+	Distinct  int
+	Nulls     int
+	Histogram []string
+}
+
+// Column finds the stats of a column by name, or nil if there are none
+func (stats *TableStats) Column(name string) *ColumnStats {
+	for i := range stats.Columns {
+		if strings.EqualFold(stats.Columns[i].Column, name) {
+			return &stats.Columns[i]
+		}
+	}
+	return nil
+}
+
+// ReadStats reads the stats catalog of the database in use, keyed by table name.
+// Tables that were never analyzed have no stats
+func ReadStats(session *Session) map[string]*TableStats {
+	catalog := map[string]*TableStats{}
+
+	contents, err := ioutil.ReadFile(session.statsPath())
+	if err != nil {
+		return catalog
+	}
+
+	var tables []TableStats
+	if json.Unmarshal(contents, &tables) != nil {
+		return catalog
+	}
+
+	for i := range tables {
+		catalog[tables[i].Table] = &tables[i]
+	}
+
+	return catalog
+}
+
+// WriteTableStats replaces a table's entry in the stats catalog
+func WriteTableStats(session *Session, stats TableStats) {
+	catalog := ReadStats(session)
+	catalog[stats.Table] = &stats
+	writeStats(session, catalog)
+}
+
+// DropTableStats removes a table's entry from the stats catalog, for when it's dropped
+func DropTableStats(session *Session, tableName string) {
+	catalog := ReadStats(session)
+	if _, ok := catalog[tableName]; ok == false {
+		return
+	}
+
+	delete(catalog, tableName)
+	writeStats(session, catalog)
+}
+
+// ListTables lists the tables of the database in use
+func ListTables(session *Session) []string {
+	files, err := ioutil.ReadDir(session.databasePath(session.Database))
+	if err != nil {
+		return nil
+	}
+
+	// tables are the only files named without a dot, dotfiles, locks and indexes all have one
+	var tables []string
+	for _, file := range files {
+		if file.IsDir() == false && strings.Contains(file.Name(), ".") == false {
+			tables = append(tables, file.Name())
+		}
+	}
+
+	return tables
+}
+
+//
+//			Helper functions
+//
+
+func writeStats(session *Session, catalog map[string]*TableStats) {
+	var tables []TableStats
+	for _, table := range ListTables(session) {
+		if stats, ok := catalog[table]; ok {
+			tables = append(tables, *stats)
+		}
+	}
+
+	contents, err := json.MarshalIndent(tables, "", "  ")
+	check(err)
+
+	err = ioutil.WriteFile(session.statsPath(), contents, 0644)
+	check(err)
+}
+
+// statsPath is where the database in use keeps its stats catalog
+func (session *Session) statsPath() string {
+	return session.databasePath(session.Database) + "/.stats"
+}
@@ -0,0 +1,65 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func queryStrings(t *testing.T, db *DB, query string) []string {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+
+	var read []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		read = append(read, value)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return read
+}
+
+// NOW() is taken once per statement, to the second, and written the way a stored TIMESTAMP is
+func TestNowIsTakenOncePerStatement(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (id int, ts timestamp)")
+
+	var values []string
+	for i := 0; i < 200; i++ {
+		values = append(values, "(1, NOW())")
+	}
+	mustExec(t, db, "INSERT INTO t VALUES "+strings.Join(values, ", "))
+
+	if read := queryStrings(t, db, "SELECT COUNT(DISTINCT ts) FROM t"); len(read) != 1 || read[0] != "1" {
+		t.Errorf("an INSERT wrote %v different NOW()s, expected 1", read)
+	}
+
+	mustExec(t, db, "UPDATE t SET ts = NOW() WHERE ts <= NOW()")
+	if read := queryStrings(t, db, "SELECT COUNT(DISTINCT ts) FROM t"); len(read) != 1 || read[0] != "1" {
+		t.Errorf("an UPDATE wrote %v different NOW()s, expected 1", read)
+	}
+
+	read := queryStrings(t, db, "SELECT NOW() FROM t")
+	for _, value := range read {
+		if value != read[0] {
+			t.Fatalf("a query read NOW() as %s and %s", read[0], value)
+		}
+	}
+
+	stored := queryStrings(t, db, "SELECT ts FROM t WHERE id = 1")
+	for _, value := range append(read[:1], stored[0]) {
+		if _, err := time.Parse("2006-01-02 15:04:05", value); err != nil {
+			t.Errorf("NOW() is %s, expected a timestamp to the second", value)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+// Package engine exposes sqlit as an embeddable library. A DB is a single
+// session, the same as one sqlit shell: it remembers the database in use
+// and any transaction in progress, and pipes each statement it's given
+// through the tokenizer, parser and generator into a disk operation.
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sqlit/diskio"
+	"sqlit/generator"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DB is a session on a sqlit data directory
+type DB struct {
+	// Debug toggles printing of each statement's tokens, and the program it compiles to
+	Debug bool
+
+	// mu serializes statements run on the session from different goroutines,
+	// the session and everything below it are only touched while it's held
+	mu sync.Mutex
+
+	session *diskio.Session
+
+	// transactionStack holds the operations a transaction will invoke once committed
+	transactionStack []generator.Operation
+
+	// savepoints are ordered from oldest to newest
+	savepoints []savepoint
+
+	// transaction counts the transactions begun on the session, a Tx only works on the one it began
+	transaction int
+
+	// prepared holds the statements PREPARE has named, by lowercased name
+	prepared map[string]*Stmt
+}
+
+// A savepoint marks how much of the transaction stack existed when it was declared
+type savepoint struct {
+	name  string
+	depth int
+}
+
+// Result describes what a statement did. Rows is only set for statements that select
+type Result struct {
+	Message      string
+	RowsAffected int64
+	LastInsertID int64
+	Rows         *Rows
+
+	// Queued is set for a write queued in a transaction, which hasn't done anything yet
+	// so RowsAffected and LastInsertID are 0. The transaction's COMMIT reports what it did
+	Queued bool
+}
+
+// Open starts a session on a data directory, creating the directory if it doesn't exist
+func Open(dir string) (*DB, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{session: diskio.NewSession(dir), prepared: map[string]*Stmt{}}, nil
+}
+
+// Close aborts any transaction in progress, releasing its locks. ROLLBACK does nothing without one
+func (db *DB) Close() error {
+	_, err := db.Exec("ROLLBACK")
+	return err
+}
+
+// Exec executes a single statement
+func (db *DB) Exec(query string) (Result, error) {
+	return db.run(func() (generator.Result, error) {
+		return db.processStatement(db.parse(query))
+	})
+}
+
+// Query executes a single statement, returning the rows it selects
+func (db *DB) Query(query string) (*Rows, error) {
+	result, err := db.Exec(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Rows == nil {
+		return &Rows{}, nil
+	}
+
+	return result.Rows, nil
+}
+
+// Begin starts a transaction on the session
+func (db *DB) Begin() (*Tx, error) {
+	tx := &Tx{db: db}
+
+	_, err := db.run(func() (generator.Result, error) {
+		if db.session.InTransactionMode {
+			return generator.Result{}, errors.New("!Failed to begin transaction because one is already in progress.")
+		}
+
+		result, err := db.processStatement(db.parse("BEGIN TRANSACTION"))
+		tx.id = db.transaction
+		return result, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// run executes a statement on the session, turning any panic along the way into an error
+func (db *DB) run(execute func() (generator.Result, error)) (Result, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	queued := len(db.transactionStack)
+
+	result, err := func() (result generator.Result, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("!Failed to execute statement: %v", r)
+			}
+		}()
+
+		return execute()
+	}()
+
+	return Result{
+		Message:      result.Message,
+		RowsAffected: int64(result.RowsAffected),
+		LastInsertID: int64(result.LastInsertID),
+		Rows:         newRows(result),
+		Queued:       len(db.transactionStack) > queued,
+	}, err
+}
+
+// parse tokenizes a statement and gives its tokens some syntactical meaning
+func (db *DB) parse(query string) tokenizer.Statement {
+	// Break our line of input up into tokens
+	statement := tokenizer.TokenizeStatement(query)
+
+	if db.Debug {
+		tokenizer.PrintStatement(statement)
+	}
+
+	// Give them some syntactical meaning
+	return parser.ParseStatement(statement)
+}
+
+// processStatement goes through all the main functionality by transforming a statement into operations
+func (db *DB) processStatement(statement tokenizer.Statement) (generator.Result, error) {
+
+	// interpert isolation level changes, which only apply to transactions that haven't begun yet
+	if statement.Type == "SET_TRANSACTION" {
+		err := db.setIsolationLevel(statement)
+		if err != nil {
+			return generator.Result{}, err
+		}
+		return generator.Result{Message: "Isolation level set to " + db.session.IsolationLevel + "."}, nil
+	}
+
+	// interpert transaction mode entry, break if entering
+	if statement.Type == "BEGIN" {
+		if db.session.InTransactionMode == false {
+			db.transaction++
+		}
+		db.session.InTransactionMode = true
+		db.session.TransactionIsolationLevel = db.session.IsolationLevel
+		return generator.Result{Message: "Transaction starts."}, nil
+	}
+
+	// interpert savepoint declaration, release and partial rollback
+	if statement.Type == "SAVEPOINT" || statement.Type == "RELEASE" || statement.Type == "ROLLBACK_TO" {
+		message, err := db.applySavepoint(statement)
+		if err != nil && db.session.InTransactionMode {
+			return generator.Result{}, db.abortTransaction(err)
+		}
+		return generator.Result{Message: message}, err
+	}
+
+	// interpert a full rollback, which discards the transaction like an abort
+	if statement.Type == "ROLLBACK" {
+		if db.session.InTransactionMode == false {
+			return generator.Result{}, nil
+		}
+		db.discardTransaction()
+		return generator.Result{Message: "Transaction rolled back."}, nil
+	}
+
+	// interpert transaction commit
+	if statement.Type == "COMMIT" {
+
+		// an aborted transaction has nothing left to commit
+		if db.session.InTransactionMode == false {
+			return generator.Result{}, nil
+		}
+
+		// 1. assert that all operations in the transaction stack are valid
+		//	(including table lock checks)
+		//	return on error
+		for _, operation := range db.transactionStack {
+			err := operation.Assert()
+
+			if err != nil {
+				return generator.Result{}, db.abortTransaction(err)
+			}
+		}
+
+		// 2. invoke all operations in transaction, summing up what they did
+		var committed generator.Result
+		var successes []string
+		var failures []string
+
+		for _, operation := range db.transactionStack {
+			result, err := operation.Invoke()
+			if err != nil {
+				failures = append(failures, err.Error())
+				continue
+			}
+
+			successes = append(successes, result.Message)
+			committed.RowsAffected += result.RowsAffected
+			if result.LastInsertID != 0 {
+				committed.LastInsertID = result.LastInsertID
+			}
+		}
+
+		db.discardTransaction()
+
+		successes = append(successes, "Transaction committed.")
+		committed.Message = strings.Join(successes, "\n")
+
+		if len(failures) > 0 {
+			return committed, errors.New(strings.Join(failures, "\n"))
+		}
+		return committed, nil
+	}
+
+	// interpert prepared statements, which are kept on the session until they're deallocated
+	if statement.Type == "PREPARE" || statement.Type == "EXECUTE" || statement.Type == "DEALLOCATE" {
+		return db.applyPrepared(statement)
+	}
+
+	if db.Debug {
+		tokenizer.PrintStatement(statement)
+	}
+
+	// Generate a function of assertions and a function of operations for our query
+	operation := generator.Generate(db.session, statement)
+
+	if operation.Parameters > 0 {
+		return generator.Result{}, errors.New("!Failed to execute statement because it has parameters, prepare it to bind them.")
+	}
+
+	return db.execute(statement, operation)
+}
+
+// execute asserts an operation is valid and invokes it, or queues it in the transaction
+func (db *DB) execute(statement tokenizer.Statement, operation generator.Operation) (generator.Result, error) {
+	if operation.Assert == nil {
+		return generator.Result{}, errors.New("!Failed to execute statement because it isn't recognized.")
+	}
+
+	// Make sure our query is valid before we request resources
+	err := operation.Assert()
+	if err != nil {
+		if db.session.InTransactionMode {
+			return generator.Result{}, db.abortTransaction(err)
+		}
+		return generator.Result{}, err
+	}
+
+	if db.Debug && operation.Program != nil {
+		if program, err := operation.Program(); err == nil {
+			fmt.Println(strings.Join(program.Dump(), "\n"))
+		}
+	}
+
+	// if we're in transaction mode, and assertions pass, we store the operation on the transaction stack rather then executing it immediately.
+	// reads don't modify anything, so they're executed right away under the locks their assertion took
+	if db.session.InTransactionMode && isRead(statement) == false {
+		db.transactionStack = append(db.transactionStack, operation)
+		return generator.Result{}, nil
+	}
+
+	// Finally, execute our query
+	return operation.Invoke()
+}
+
+// abortTransaction discards a failed transaction, reporting why it failed
+func (db *DB) abortTransaction(err error) error {
+	db.discardTransaction()
+	return errors.New(err.Error() + "\nTransaction abort.")
+}
+
+// discardTransaction empties the transaction stack and releases the transaction's locks
+func (db *DB) discardTransaction() {
+	// empty out our transaction stack
+	db.transactionStack = []generator.Operation{}
+	db.savepoints = []savepoint{}
+
+	// unlock all associated resources
+	diskio.UnlockAllTables(db.session)
+
+	// exit transaction mode
+	db.session.InTransactionMode = false
+}
+
+// setIsolationLevel changes the session's isolation level from a SET TRANSACTION statement
+func (db *DB) setIsolationLevel(statement tokenizer.Statement) error {
+	if db.session.InTransactionMode {
+		return errors.New("!Failed to set isolation level because a transaction is in progress.")
+	}
+
+	var words []string
+	for _, token := range statement.Tokens {
+		if token.Name == "ISOLATION_LEVEL" {
+			words = append(words, strings.ToUpper(token.Special))
+		}
+	}
+	level := strings.Join(words, " ")
+
+	for _, supportedLevel := range diskio.IsolationLevels {
+		if level == supportedLevel {
+			db.session.IsolationLevel = level
+			return nil
+		}
+	}
+
+	return errors.New("!Failed to set isolation level because " + level + " is not supported.")
+}
+
+// applySavepoint declares, releases or rolls back to a named savepoint.
+// Rolling back keeps the savepoint (and the locks taken since), so it can be rolled back to again
+func (db *DB) applySavepoint(statement tokenizer.Statement) (string, error) {
+	var name string
+	for _, token := range statement.Tokens {
+		if token.Name == "SAVEPOINT_NAME" {
+			name = token.Special
+		}
+	}
+
+	if db.session.InTransactionMode == false {
+		return "", errors.New("!Failed to use savepoint " + name + " because no transaction is in progress.")
+	}
+
+	if statement.Type == "SAVEPOINT" {
+		db.savepoints = append(db.savepoints, savepoint{name: name, depth: len(db.transactionStack)})
+		return "Savepoint " + name + " created.", nil
+	}
+
+	// the newest savepoint of a name wins, as names may be reused
+	index := -1
+	for i := range db.savepoints {
+		if strings.EqualFold(db.savepoints[i].name, name) {
+			index = i
+		}
+	}
+
+	if index == -1 {
+		return "", errors.New("!Failed to use savepoint " + name + " because it does not exist.")
+	}
+
+	if statement.Type == "RELEASE" {
+		db.savepoints = db.savepoints[:index]
+		return "Savepoint " + name + " released.", nil
+	}
+
+	depth := db.savepoints[index].depth
+	discarded := len(db.transactionStack) - depth
+	db.transactionStack = db.transactionStack[:depth]
+	db.savepoints = db.savepoints[:index+1]
+
+	return "Rolled back to savepoint " + name + ", " + strconv.Itoa(discarded) + " operation(s) discarded.", nil
+}
+
+//
+//			Helper functions
+//
+
+func isRead(statement tokenizer.Statement) bool {
+	return statement.Type == "SELECT" || statement.Type == "EXPLAIN"
+}
@@ -0,0 +1,40 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import "testing"
+
+func lastInsertID(t *testing.T, db *DB, query string) int64 {
+	t.Helper()
+	result, err := db.Exec(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return result.LastInsertID
+}
+
+// Row ids count up from 1, and aren't handed out again once their records are deleted
+func TestLastInsertIDIsNeverReused(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (a int)")
+
+	for i := int64(1); i <= 3; i++ {
+		if id := lastInsertID(t, db, "INSERT INTO t VALUES (1)"); id != i {
+			t.Errorf("inserted row id %d, expected %d", id, i)
+		}
+	}
+
+	mustExec(t, db, "DELETE FROM t WHERE a = 1")
+	if id := lastInsertID(t, db, "INSERT INTO t VALUES (2)"); id != 4 {
+		t.Errorf("inserted row id %d after a DELETE, expected 4", id)
+	}
+
+	if id := lastInsertID(t, db, "INSERT INTO t VALUES (3), (4), (5)"); id != 7 {
+		t.Errorf("inserted row id %d for the last of three records, expected 7", id)
+	}
+
+	mustExec(t, db, "DROP TABLE t", "CREATE TABLE t (a int)")
+	if id := lastInsertID(t, db, "INSERT INTO t VALUES (1)"); id != 1 {
+		t.Errorf("inserted row id %d into a recreated table, expected 1", id)
+	}
+}
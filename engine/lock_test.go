@@ -0,0 +1,135 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestSessions opens two sessions on the same fresh data directory, both using a database with a table t
+func openTestSessions(t *testing.T) (*DB, *DB, string) {
+	dir, err := ioutil.TempDir("", "sqlit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions []*DB
+	for i := 0; i < 2; i++ {
+		db, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, db)
+	}
+
+	t.Cleanup(func() {
+		for _, db := range sessions {
+			db.Close()
+		}
+		os.RemoveAll(dir)
+	})
+
+	mustExec(t, sessions[0], "CREATE DATABASE test", "USE test", "CREATE TABLE t (seat int, status int)", "INSERT INTO t VALUES (22, 0)")
+	mustExec(t, sessions[1], "USE test")
+
+	return sessions[0], sessions[1], filepath.Join(dir, "test")
+}
+
+func mustExec(t *testing.T, db *DB, queries ...string) {
+	t.Helper()
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+}
+
+func expectLocked(t *testing.T, db *DB, query string) {
+	t.Helper()
+	_, err := db.Exec(query)
+	if err == nil || strings.Contains(err.Error(), "is locked") == false {
+		t.Fatalf("%s: expected the table to be locked, got %v", query, err)
+	}
+}
+
+// A session's transaction locks the tables it writes until it commits
+func TestTransactionLocksOutOtherSession(t *testing.T) {
+	p1, p2, _ := openTestSessions(t)
+
+	mustExec(t, p1, "BEGIN TRANSACTION", "UPDATE t SET status = 1 WHERE seat = 22")
+
+	expectLocked(t, p2, "UPDATE t SET status = 2 WHERE seat = 22")
+	expectLocked(t, p2, "DELETE FROM t")
+	expectLocked(t, p2, "TRUNCATE TABLE t")
+	expectLocked(t, p2, "INSERT INTO t VALUES (23, 1)")
+
+	mustExec(t, p1, "COMMIT")
+	mustExec(t, p2, "UPDATE t SET status = 2 WHERE seat = 22")
+
+	rows, err := p1.Query("SELECT status FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status int
+	for rows.Next() {
+		rows.Scan(&status)
+	}
+	if status != 2 {
+		t.Errorf("status is %d, expected 2", status)
+	}
+}
+
+// A statement that fails its checks leaves nothing locked behind
+func TestFailedStatementsDontLock(t *testing.T) {
+	p1, p2, database := openTestSessions(t)
+
+	for _, query := range []string{
+		"UPDATE later SET a = 1",
+		"DELETE FROM later",
+		"TRUNCATE TABLE later",
+		"INSERT INTO later VALUES (1)",
+		"INSERT INTO t SELECT * FROM missing",
+		"INSERT INTO t SELECT nope, status FROM t",
+		"INSERT INTO t SELECT seat FROM t",
+	} {
+		if _, err := p1.Exec(query); err == nil {
+			t.Fatalf("%s should fail", query)
+		}
+	}
+
+	locks, _ := filepath.Glob(filepath.Join(database, "*.lock"))
+	if len(locks) > 0 {
+		t.Errorf("failed statements left locks %v", locks)
+	}
+
+	mustExec(t, p2, "CREATE TABLE later (a int)", "INSERT INTO later VALUES (1)", "UPDATE later SET a = 2", "DELETE FROM later", "TRUNCATE TABLE later", "INSERT INTO t VALUES (23, 1)")
+}
+
+// A lock whose owner's process has exited is reclaimed, while a live owner's is kept
+func TestStaleLockIsReclaimed(t *testing.T) {
+	_, p2, database := openTestSessions(t)
+	lock := filepath.Join(database, "t.lock")
+
+	// no process has the largest pid, so this lock was left behind by one that exited
+	err := ioutil.WriteFile(lock, []byte("2147483647:1 exclusive\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustExec(t, p2, "UPDATE t SET status = 1 WHERE seat = 22")
+	if _, err := os.Stat(lock); os.IsNotExist(err) == false {
+		t.Errorf("the stale lock wasn't removed")
+	}
+
+	// pid 1 is always running
+	err = ioutil.WriteFile(lock, []byte("1:1 exclusive\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectLocked(t, p2, "UPDATE t SET status = 2 WHERE seat = 22")
+}
@@ -0,0 +1,122 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"reflect"
+	"sqlit/generator"
+	"sqlit/vm"
+	"testing"
+)
+
+// compile generates a statement's program, as the --debug flag prints it
+func compile(t *testing.T, db *DB, query string) *vm.Program {
+	t.Helper()
+	operation := generator.Generate(db.session, db.parse(query))
+	if err := operation.Assert(); err != nil {
+		t.Fatal(err)
+	}
+	program, err := operation.Program()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func opcodes(program *vm.Program) []vm.Opcode {
+	var opcodes []vm.Opcode
+	for _, instruction := range program.Instructions {
+		opcodes = append(opcodes, instruction.Opcode)
+	}
+	return opcodes
+}
+
+// A filtered scan compiles to a loop over a cursor, an INSERT to a record written through one,
+// and an UPDATE or DELETE to a loop over the records of a write cursor
+func TestStatementsCompileToPrograms(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE Product (pid int, name varchar(20), price float)")
+
+	program := compile(t, db, "SELECT name FROM Product WHERE price > 10")
+	loop := []vm.Opcode{vm.OpenRead, vm.Rewind, vm.Column, vm.Integer, vm.Compare, vm.IfNot, vm.Column, vm.ResultRow, vm.Next, vm.Halt}
+	if reflect.DeepEqual(opcodes(program), loop) == false {
+		t.Errorf("the query compiled to %v, expected %v", opcodes(program), loop)
+	}
+
+	// the loop ends past Next, and Next goes back to the top of it
+	if program.Instructions[1].P2 != 9 || program.Instructions[8].P2 != 2 {
+		t.Errorf("the loop's jumps go to %d and %d, expected 9 and 2", program.Instructions[1].P2, program.Instructions[8].P2)
+	}
+
+	program = compile(t, db, "INSERT INTO Product VALUES (1, 'Gizmo', 19.99), (2, 'PowerGizmo', 29.99)")
+	for _, opcode := range []vm.Opcode{vm.OpenWrite, vm.MakeRecord, vm.Insert} {
+		found := false
+		for _, compiled := range opcodes(program) {
+			found = found || compiled == opcode
+		}
+		if found == false {
+			t.Errorf("the insert compiled to %v, without %v", opcodes(program), opcode)
+		}
+	}
+
+	result, err := db.Exec("INSERT INTO Product VALUES (1, 'Gizmo', 19.99), (2, 'PowerGizmo', 29.99)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 2 {
+		t.Errorf("inserted %d records, expected 2", result.RowsAffected)
+	}
+
+	rows, err := db.Query("SELECT name FROM Product WHERE price > 20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if reflect.DeepEqual(names, []string{"PowerGizmo"}) == false {
+		t.Errorf("selected %v, expected [PowerGizmo]", names)
+	}
+
+	program = compile(t, db, "UPDATE Product SET price = price * 2 WHERE pid = 2")
+	update := []vm.Opcode{vm.OpenWrite, vm.Rewind, vm.Column, vm.Integer, vm.Compare, vm.IfNot, vm.Function, vm.Update, vm.Next, vm.Halt}
+	if reflect.DeepEqual(opcodes(program), update) == false {
+		t.Errorf("the update compiled to %v, expected %v", opcodes(program), update)
+	}
+
+	program = compile(t, db, "DELETE FROM Product WHERE name LIKE 'Power%'")
+	del := []vm.Opcode{vm.OpenWrite, vm.Rewind, vm.Function, vm.IfNot, vm.Delete, vm.Next, vm.Halt}
+	if reflect.DeepEqual(opcodes(program), del) == false {
+		t.Errorf("the delete compiled to %v, expected %v", opcodes(program), del)
+	}
+
+	result, err = db.Exec("UPDATE Product SET price = price * 2 WHERE pid = 2")
+	if err != nil || result.RowsAffected != 1 {
+		t.Errorf("updated %d records, %v, expected 1", result.RowsAffected, err)
+	}
+
+	result, err = db.Exec("DELETE FROM Product WHERE name LIKE 'Power%'")
+	if err != nil || result.RowsAffected != 1 {
+		t.Errorf("deleted %d records, %v, expected 1", result.RowsAffected, err)
+	}
+
+	rows, err = db.Query("SELECT pid, price FROM Product")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records [][]interface{}
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, values)
+	}
+	want := [][]interface{}{{int64(1), 19.99}}
+	if reflect.DeepEqual(records, want) == false {
+		t.Errorf("the table is %v, expected %v", records, want)
+	}
+}
@@ -0,0 +1,215 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"sqlit/generator"
+	"strconv"
+	"strings"
+)
+
+// Column describes a single column of a result
+type Column struct {
+	Name string
+	Type string
+}
+
+// Rows is a cursor over the records a query selected. Records are read as it's stepped
+// through, outside of the session's lock, so statements run on the session meanwhile don't
+// wait for it. They're still read from the database that was in use when the query ran.
+// A Rows itself isn't safe to step through from several goroutines at once
+type Rows struct {
+	columns []Column
+	rows    generator.RowIterator
+	record  []string
+	err     error
+}
+
+// newRows wraps the rows of a result, if it has any
+func newRows(result generator.Result) *Rows {
+	if result.Rows == nil {
+		return nil
+	}
+
+	rows := &Rows{rows: result.Rows}
+
+	for _, columnDef := range result.ColumnDefs {
+		rows.columns = append(rows.columns, Column{Name: columnDef.ColumnName, Type: columnDef.TypeName})
+	}
+
+	return rows
+}
+
+// Columns returns the name and type of each column
+func (rows *Rows) Columns() []Column {
+	return rows.columns
+}
+
+// Next advances to the next record, returning false once there are none left or reading one fails
+func (rows *Rows) Next() bool {
+	rows.record = nil
+
+	if rows.rows == nil || rows.err != nil {
+		return false
+	}
+
+	record, err := rows.rows.Next()
+	if err != nil {
+		if err != io.EOF {
+			rows.err = err
+		}
+		return false
+	}
+
+	rows.record = record
+	return true
+}
+
+// Err returns the error that stopped Next, if any
+func (rows *Rows) Err() error {
+	return rows.err
+}
+
+// Strings returns the current record as it's stored, without converting its values. NULLs are empty
+func (rows *Rows) Strings() []string {
+	if rows.record == nil {
+		return nil
+	}
+
+	record := make([]string, len(rows.record))
+	for i, value := range rows.record {
+		if value != diskio.Null {
+			record[i] = value
+		}
+	}
+	return record
+}
+
+// Values returns the current record, each value typed by its column:
+// int64 for int columns, float64 for float columns and string otherwise.
+// NULLs (such as the missing side of a left join) are nil
+func (rows *Rows) Values() ([]interface{}, error) {
+	if rows.record == nil {
+		return nil, errors.New("!Failed to read row because the cursor isn't on one.")
+	}
+
+	record := rows.record
+	values := make([]interface{}, len(rows.columns))
+
+	for i := range rows.columns {
+		if i >= len(record) {
+			continue
+		}
+
+		value, err := convertValue(record[i], rows.columns[i].Type)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// Scan copies the current record into dest, which may point to
+// int, int64, float64, string or interface{} values
+func (rows *Rows) Scan(dest ...interface{}) error {
+	values, err := rows.Values()
+	if err != nil {
+		return err
+	}
+
+	if len(dest) != len(values) {
+		return errors.New("!Failed to scan row because it has " + strconv.Itoa(len(values)) + " columns, not " + strconv.Itoa(len(dest)) + ".")
+	}
+
+	for i, value := range values {
+		err := assignValue(dest[i], value)
+		if err != nil {
+			return errors.New("!Failed to scan column " + rows.columns[i].Name + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Close releases the rows
+func (rows *Rows) Close() error {
+	rows.record = nil
+
+	if rows.rows == nil {
+		return nil
+	}
+	return rows.rows.Close()
+}
+
+//
+//			Helper functions
+//
+
+func convertValue(value string, typeName string) (interface{}, error) {
+	if value == diskio.Null {
+		return nil, nil
+	}
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(typeName), "int"):
+		if strings.TrimSpace(value) == "" {
+			return nil, nil
+		}
+		return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	case strings.HasPrefix(strings.ToLower(typeName), "float"):
+		if strings.TrimSpace(value) == "" {
+			return nil, nil
+		}
+		return strconv.ParseFloat(strings.TrimSpace(value), 64)
+	}
+
+	return value, nil
+}
+
+func assignValue(dest interface{}, value interface{}) error {
+	switch d := dest.(type) {
+	case *interface{}:
+		*d = value
+	case *string:
+		switch v := value.(type) {
+		case nil:
+			*d = ""
+		case string:
+			*d = v
+		case int64:
+			*d = strconv.FormatInt(v, 10)
+		case float64:
+			*d = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case *int64:
+		v, ok := value.(int64)
+		if ok == false {
+			return errors.New("value is not an int")
+		}
+		*d = v
+	case *int:
+		v, ok := value.(int64)
+		if ok == false {
+			return errors.New("value is not an int")
+		}
+		*d = int(v)
+	case *float64:
+		switch v := value.(type) {
+		case float64:
+			*d = v
+		case int64:
+			*d = float64(v)
+		default:
+			return errors.New("value is not a float")
+		}
+	default:
+		return errors.New("destination type is not supported")
+	}
+
+	return nil
+}
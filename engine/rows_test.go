@@ -0,0 +1,82 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Values are typed by their columns, and scan into the Go types they convert to
+func TestRowsAreTyped(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (id int, price float, name varchar(20))", "INSERT INTO t VALUES (1, 2.5, 'one'), (2, NULL, NULL)")
+
+	rows, err := db.Query("SELECT id, price, name FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Column{{Name: "id", Type: "int"}, {Name: "price", Type: "float"}, {Name: "name", Type: "varchar(20)"}}
+	if reflect.DeepEqual(rows.Columns(), expected) == false {
+		t.Errorf("columns are %v, expected %v", rows.Columns(), expected)
+	}
+
+	var read [][]interface{}
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		read = append(read, values)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]interface{}{{int64(1), 2.5, "one"}, {int64(2), nil, nil}}
+	if reflect.DeepEqual(read, want) == false {
+		t.Errorf("read back %v, expected %v", read, want)
+	}
+
+	rows, err = db.Query("SELECT name, id FROM t WHERE id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err == nil {
+			t.Errorf("scanning text into an int should fail")
+		}
+		if err := rows.Scan(&name); err == nil {
+			t.Errorf("scanning two columns into one value should fail")
+		}
+		if err := rows.Scan(&name, &id); err != nil || name != "one" || id != 1 {
+			t.Errorf("scanned %q %d, %v", name, id, err)
+		}
+	}
+}
+
+// Rows keep reading from the database in use when their query ran
+func TestRowsOutliveUse(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (name varchar(10))", "INSERT INTO t VALUES ('test')",
+		"CREATE DATABASE other", "USE other", "CREATE TABLE t (name varchar(10))", "INSERT INTO t VALUES ('other')", "USE test")
+
+	rows, err := db.Query("SELECT name FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, "USE other")
+
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if reflect.DeepEqual(names, []string{"test"}) == false {
+		t.Errorf("read %v, expected [test]", names)
+	}
+}
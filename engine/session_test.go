@@ -0,0 +1,80 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Sessions keep their own data directory and database in use, even when they're run side by side
+func TestSessionsDontShareState(t *testing.T) {
+	var sessions []*DB
+	for i := 0; i < 4; i++ {
+		db := openTestDB(t)
+		mustExec(t, db, "CREATE DATABASE other"+strconv.Itoa(i), "CREATE TABLE t (id int)")
+		sessions = append(sessions, db)
+	}
+
+	var wg sync.WaitGroup
+	for i, db := range sessions {
+		wg.Add(1)
+		go func(i int, db *DB) {
+			defer wg.Done()
+			for j := 0; j <= i; j++ {
+				db.Exec("INSERT INTO t VALUES (" + strconv.Itoa(i) + ")")
+			}
+		}(i, db)
+	}
+	wg.Wait()
+
+	for i, db := range sessions {
+		rows, err := db.Query("SELECT count(*), min(id), max(id) FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var count, min, max int
+		for rows.Next() {
+			rows.Scan(&count, &min, &max)
+		}
+		if count != i+1 || min != i || max != i {
+			t.Errorf("session %d's table has %d records from %d to %d, expected %d of %d", i, count, min, max, i+1, i)
+		}
+	}
+
+	if _, err := sessions[0].Exec("USE other1"); err == nil {
+		t.Errorf("a session could use a database from another session's data directory")
+	}
+	mustExec(t, sessions[0], "USE other0")
+	if _, err := sessions[0].Exec("SELECT * FROM t"); err == nil {
+		t.Errorf("USE didn't switch the session's database")
+	}
+	if _, err := sessions[1].Exec("SELECT * FROM t"); err != nil {
+		t.Errorf("another session's USE switched this one's database: %v", err)
+	}
+}
+
+// Dropping a database deletes its tables too, and a failed drop doesn't report one
+func TestDropDatabase(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (id int)", "INSERT INTO t VALUES (1)", "CREATE DATABASE other", "USE other")
+
+	result, err := db.Exec("DROP DATABASE test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Message != "Database test deleted." {
+		t.Errorf("dropping reported %q", result.Message)
+	}
+
+	if _, err := db.Exec("USE test"); err == nil {
+		t.Errorf("the dropped database can still be used")
+	}
+
+	result, err = db.Exec("DROP DATABASE test")
+	if err == nil || result.Message != "" {
+		t.Errorf("dropping it again reported %q, %v", result.Message, err)
+	}
+}
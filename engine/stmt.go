@@ -0,0 +1,215 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sqlit/generator"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stmt is a statement prepared on a DB. It's tokenized, parsed and generated once, and each
+// execution binds its parameters, ? or $1, $2 and so on, to the values it's given. Values are
+// bound into the parsed statement as constants, never spliced into its SQL
+type Stmt struct {
+	db        *DB
+	statement tokenizer.Statement
+	operation generator.Operation
+}
+
+// Prepare prepares a statement to be executed any number of times
+func (db *DB) Prepare(query string) (*Stmt, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.prepare(query)
+}
+
+// NumInput is how many parameters the statement takes
+func (s *Stmt) NumInput() int {
+	return s.operation.Parameters
+}
+
+// Exec executes the statement with its parameters bound to args, in order
+func (s *Stmt) Exec(args ...interface{}) (Result, error) {
+	literals, err := literalsOf(args)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return s.db.run(func() (generator.Result, error) {
+		return s.execute(literals)
+	})
+}
+
+// Query executes the statement with its parameters bound to args, returning the rows it selects
+func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
+	result, err := s.Exec(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Rows == nil {
+		return &Rows{}, nil
+	}
+
+	return result.Rows, nil
+}
+
+// Close ...
+func (s *Stmt) Close() error {
+	return nil
+}
+
+// prepare generates a statement's operation, statements handled by the session itself, like
+// BEGIN, are only parsed and are processed again each time they're executed
+func (db *DB) prepare(query string) (*Stmt, error) {
+	statement := db.parse(query)
+
+	switch statement.Type {
+	case "PREPARE", "EXECUTE", "DEALLOCATE":
+		return nil, errors.New("!Failed to prepare statement because " + statement.Type + " can't be prepared.")
+	}
+
+	return &Stmt{db: db, statement: statement, operation: generator.Generate(db.session, statement)}, nil
+}
+
+// execute binds the statement's parameters and executes it
+func (s *Stmt) execute(args []*parser.Literal) (generator.Result, error) {
+	if len(args) != s.operation.Parameters {
+		return generator.Result{}, errors.New("!Failed to execute statement because it takes " + strconv.Itoa(s.operation.Parameters) +
+			" parameter(s), not " + strconv.Itoa(len(args)) + ".")
+	}
+
+	if s.operation.Assert == nil {
+		return s.db.processStatement(s.statement)
+	}
+
+	operation := s.operation
+	if operation.Bind != nil {
+		operation = operation.Bind(args)
+	}
+
+	return s.db.execute(s.statement, operation)
+}
+
+// applyPrepared prepares, executes or deallocates a named statement
+func (db *DB) applyPrepared(statement tokenizer.Statement) (generator.Result, error) {
+	switch statement.Type {
+	case "PREPARE":
+		prepare, err := parser.ParsePrepare(statement.Raw)
+		if err != nil {
+			return generator.Result{}, err
+		}
+
+		if db.prepared[strings.ToLower(prepare.Name)] != nil {
+			return generator.Result{}, errors.New("!Failed to prepare statement " + prepare.Name + " because it already exists.")
+		}
+
+		stmt, err := db.prepare(prepare.Statement)
+		if err != nil {
+			return generator.Result{}, err
+		}
+
+		db.prepared[strings.ToLower(prepare.Name)] = stmt
+		return generator.Result{Message: "Statement " + prepare.Name + " prepared."}, nil
+
+	case "EXECUTE":
+		execute, err := parser.ParseExecute(statement.Raw)
+		if err != nil {
+			return generator.Result{}, err
+		}
+
+		stmt := db.prepared[strings.ToLower(execute.Name)]
+		if stmt == nil {
+			return generator.Result{}, errors.New("!Failed to execute statement " + execute.Name + " because it does not exist.")
+		}
+
+		var args []*parser.Literal
+		for _, arg := range execute.Args {
+			literal, err := constantLiteral(arg)
+			if err != nil {
+				return generator.Result{}, err
+			}
+			args = append(args, literal)
+		}
+
+		return stmt.execute(args)
+	}
+
+	var name string
+	for _, token := range statement.Tokens {
+		if token.Name == "STATEMENT_NAME" {
+			name = token.Special
+		}
+	}
+
+	if db.prepared[strings.ToLower(name)] == nil {
+		return generator.Result{}, errors.New("!Failed to deallocate statement " + name + " because it does not exist.")
+	}
+
+	delete(db.prepared, strings.ToLower(name))
+	return generator.Result{Message: "Statement " + name + " deallocated."}, nil
+}
+
+//
+//			Helper functions
+//
+
+// literalsOf turns Go values into the constants they're bound as
+func literalsOf(args []interface{}) ([]*parser.Literal, error) {
+	var literals []*parser.Literal
+
+	for _, arg := range args {
+		literal := &parser.Literal{Kind: tokenizer.Number}
+
+		switch value := arg.(type) {
+		case nil:
+			literal = &parser.Literal{Kind: "NULL", Value: "NULL"}
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			literal.Value = fmt.Sprint(value)
+		case float32:
+			literal.Value = strconv.FormatFloat(float64(value), 'f', -1, 32)
+		case float64:
+			literal.Value = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			literal.Value = "0"
+			if value {
+				literal.Value = "1"
+			}
+		case string:
+			literal = &parser.Literal{Kind: tokenizer.String, Value: value}
+		case []byte:
+			literal = &parser.Literal{Kind: tokenizer.String, Value: string(value)}
+		case time.Time:
+			literal = &parser.Literal{Kind: tokenizer.String, Value: value.Format("2006-01-02 15:04:05")}
+		default:
+			return nil, fmt.Errorf("!Failed to bind parameter because %T values aren't supported.", arg)
+		}
+
+		literals = append(literals, literal)
+	}
+
+	return literals, nil
+}
+
+// constantLiteral reads an EXECUTE argument, which has to be a constant
+func constantLiteral(expr parser.Expr) (*parser.Literal, error) {
+	switch e := expr.(type) {
+	case *parser.Literal:
+		return e, nil
+	case *parser.UnaryExpr:
+		if literal, ok := e.Operand.(*parser.Literal); ok && literal.Kind == tokenizer.Number {
+			if e.Operator == "-" {
+				return &parser.Literal{Kind: tokenizer.Number, Value: "-" + literal.Value}, nil
+			}
+		}
+	}
+
+	return nil, errors.New("!Failed to execute statement because " + expr.String() + " is not a constant.")
+}
@@ -0,0 +1,159 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// openTestDB opens a DB on a fresh data directory, using a fresh database
+func openTestDB(t *testing.T) *DB {
+	dir, err := ioutil.TempDir("", "sqlit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	for _, query := range []string{"CREATE DATABASE test", "USE test"} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+// Values bound to a prepared statement are written as they are, whatever they contain
+func TestStmtRoundTripsHostileValues(t *testing.T) {
+	db := openTestDB(t)
+
+	_, err := db.Exec("CREATE TABLE t (id int, s varchar(40))")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insert, err := db.Prepare("INSERT INTO t VALUES (?, ?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []string{
+		"x\n999|injected",
+		"a|b",
+		"back\\slash",
+		"ends with a \\",
+		"\\|\\n not escapes",
+		"carriage\r\nreturn",
+		"'; DROP TABLE t; --",
+	}
+
+	for i, value := range values {
+		if _, err := insert.Exec(i, value); err != nil {
+			t.Fatalf("inserting %q: %v", value, err)
+		}
+	}
+
+	if _, err := insert.Exec(len(values), "\\N"); err == nil {
+		t.Errorf("inserting the text \\N should fail, it would read back as NULL")
+	}
+
+	if _, err := insert.Exec(len(values), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := db.Prepare("SELECT id, s FROM t WHERE s = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, value := range values {
+		rows, err := query.Query(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := 0
+		for rows.Next() {
+			var id int
+			var s string
+			if err := rows.Scan(&id, &s); err != nil {
+				t.Fatal(err)
+			}
+			if id != i || s != value {
+				t.Errorf("read back %d %q, wrote %d %q", id, s, i, value)
+			}
+			found++
+		}
+		if found != 1 {
+			t.Errorf("found %q %d times", value, found)
+		}
+	}
+
+	rows, err := db.Query("SELECT count(*), count(s) FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count, notNull int
+	for rows.Next() {
+		rows.Scan(&count, &notNull)
+	}
+	if count != len(values)+1 || notNull != len(values) {
+		t.Errorf("table has %d records, %d not NULL, expected %d and %d", count, notNull, len(values)+1, len(values))
+	}
+}
+
+// Values keep round tripping once an UPDATE rewrites the table, and through an index
+func TestStmtRoundTripsHostileValuesThroughRewrites(t *testing.T) {
+	db := openTestDB(t)
+
+	for _, query := range []string{
+		"CREATE TABLE t (id int, s varchar(40))",
+		"CREATE INDEX ts ON t (s)",
+		"INSERT INTO t VALUES (1, 'plain')",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	update, err := db.Prepare("UPDATE t SET s = ? WHERE id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := "line\nbreak|pipe\\"
+	result, err := update.Exec(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 1 {
+		t.Errorf("updated %d records", result.RowsAffected)
+	}
+
+	rows, err := db.Query("SELECT s FROM t WHERE s = 'line\nbreak|pipe\\'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read []string
+	for rows.Next() {
+		var s string
+		rows.Scan(&s)
+		read = append(read, s)
+	}
+	if len(read) != 1 || read[0] != value {
+		t.Errorf("read back %q, wrote %q", read, value)
+	}
+}
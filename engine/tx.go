@@ -0,0 +1,78 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"errors"
+	"sqlit/generator"
+)
+
+// Tx is a transaction in progress on a DB. Writes are queued until Commit,
+// reads are executed immediately under the session's isolation level. So a
+// write's Result is Queued, without the rows it affects or its insert id,
+// and reads in the transaction don't see what it will write.
+type Tx struct {
+	db   *DB
+	done bool
+
+	// id is the transaction on the session this Tx began, once it ends a new one may take its place
+	id int
+}
+
+// Exec queues a statement in the transaction
+func (tx *Tx) Exec(query string) (Result, error) {
+	return tx.run(query, false)
+}
+
+// Query executes a read inside the transaction
+func (tx *Tx) Query(query string) (*Rows, error) {
+	result, err := tx.Exec(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Rows == nil {
+		return &Rows{}, nil
+	}
+
+	return result.Rows, nil
+}
+
+// Commit executes every queued statement and releases the transaction's locks
+func (tx *Tx) Commit() error {
+	_, err := tx.run("COMMIT", true)
+	return err
+}
+
+// Rollback discards every queued statement and releases the transaction's locks
+func (tx *Tx) Rollback() error {
+	_, err := tx.run("ROLLBACK", true)
+	return err
+}
+
+// run executes a statement on the transaction's session, the transaction is done once it finishes it
+func (tx *Tx) run(query string, finish bool) (Result, error) {
+	return tx.db.run(func() (generator.Result, error) {
+		if err := tx.check(); err != nil {
+			return generator.Result{}, err
+		}
+		tx.done = finish
+
+		return tx.db.processStatement(tx.db.parse(query))
+	})
+}
+
+// check makes sure the transaction is still usable, any failed statement aborts it.
+// It's called with the session's mutex held, so the transaction can't end while it's used
+func (tx *Tx) check() error {
+	if tx.done {
+		return errors.New("!Failed to use transaction because it has already been committed or rolled back.")
+	}
+
+	if tx.db.session.InTransactionMode == false || tx.db.transaction != tx.id {
+		tx.done = true
+		return errors.New("!Failed to use transaction because it was aborted.")
+	}
+
+	return nil
+}
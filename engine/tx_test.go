@@ -0,0 +1,115 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Of two goroutines beginning a transaction on one session at once, only one gets it
+func TestConcurrentTransactions(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (id int)")
+
+	for round := 0; round < 10; round++ {
+		var wg sync.WaitGroup
+		var begun []*Tx
+		var errs []error
+		var mu sync.Mutex
+		start := make(chan bool)
+
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				tx, err := db.Begin()
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+				begun = append(begun, tx)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if len(begun) != 1 || len(errs) != 1 {
+			t.Fatalf("%d transactions began and %d failed, expected one of each", len(begun), len(errs))
+		}
+		if strings.Contains(errs[0].Error(), "already in progress") == false {
+			t.Errorf("the second Begin failed with %v", errs[0])
+		}
+
+		tx := begun[0]
+		result, err := tx.Exec("INSERT INTO t VALUES (1)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Queued == false || result.RowsAffected != 0 {
+			t.Errorf("a write in a transaction reported %+v, expected it to be queued", result)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := db.Query("SELECT count(*) FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for rows.Next() {
+		rows.Scan(&count)
+	}
+	if count != 10 {
+		t.Errorf("table has %d records, expected 10", count)
+	}
+}
+
+// A Tx whose transaction ended can't be used on the transaction begun after it
+func TestStaleTxIsRejected(t *testing.T) {
+	db := openTestDB(t)
+	mustExec(t, db, "CREATE TABLE t (id int)")
+
+	stale, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, "ROLLBACK")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stale.Exec("INSERT INTO t VALUES (1)"); err == nil {
+		t.Errorf("a rolled back Tx queued a write in the next transaction")
+	}
+	if err := stale.Commit(); err == nil {
+		t.Errorf("a rolled back Tx committed the next transaction")
+	}
+	if err := stale.Rollback(); err == nil {
+		t.Errorf("a rolled back Tx discarded the next transaction")
+	}
+
+	if _, err := tx.Exec("INSERT INTO t VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed statement aborts the transaction, leaving the Tx stale too
+	if _, err := tx.Exec("INSERT INTO missing VALUES (1)"); err == nil {
+		t.Fatal("inserting into a missing table should fail")
+	}
+	if _, err := db.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Errorf("an aborted Tx committed the next transaction")
+	}
+}
@@ -0,0 +1,374 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strings"
+)
+
+// Aggregate folds the rows of each group into one row of grouped values followed by
+// aggregate results. Its child must already be sorted by the grouped expressions, so
+// a group is finished as soon as a row with a different key turns up. Without any
+// grouped expressions the whole input is one group, which exists even if it's empty.
+// Grouped expressions keep their column's name, aggregates are named after their SQL
+type Aggregate struct {
+	child      Operator
+	groupBy    []evaluator
+	collations []string
+	aggregates []aggregateCall
+	columns    []diskio.ColumnDef
+
+	// Groups is how many groups are expected, the planner may know better than the default guess
+	Groups float64
+
+	// the row that started the next group, and whether the child has run out
+	pending []string
+	done    bool
+	emitted bool
+}
+
+// an aggregateCall is a compiled aggregate function, a nil arg is COUNT(*).
+// A distinct one folds each value of its arg once, typeName is what it reads them back as.
+// MIN and MAX compare text under the arg's collation, and it's what makes values distinct
+type aggregateCall struct {
+	name      string
+	arg       evaluator
+	distinct  bool
+	typeName  string
+	collation string
+}
+
+// NewAggregate ...
+func NewAggregate(child Operator, groupBy []parser.Expr, aggregates []*parser.FuncCall) (*Aggregate, error) {
+	a := &Aggregate{child: child, Groups: 1}
+	if len(groupBy) > 0 {
+		a.Groups = -1
+	}
+
+	for _, expr := range groupBy {
+		compiled, typeName, err := compile(expr, child.Columns())
+		if err != nil {
+			return nil, err
+		}
+
+		column := diskio.ColumnDef{ColumnName: expr.String(), TypeName: typeName, Collation: collationOf(expr, child.Columns())}
+		if ref, ok := expr.(*parser.ColumnRef); ok {
+			index, _ := ResolveColumn(ref, child.Columns())
+			column = child.Columns()[index]
+		}
+
+		a.groupBy = append(a.groupBy, compiled)
+		a.collations = append(a.collations, column.Collation)
+		a.columns = append(a.columns, column)
+	}
+
+	for _, call := range aggregates {
+		if len(call.Args) != 1 {
+			return nil, errors.New("!Failed to query because " + call.Name + " takes exactly one argument.")
+		}
+
+		aggregate := aggregateCall{name: call.Name, distinct: call.Distinct}
+		typeName := intType
+
+		if _, star := call.Args[0].(*parser.Star); star == false {
+			compiled, argType, err := compile(call.Args[0], child.Columns())
+			if err != nil {
+				return nil, err
+			}
+			aggregate.arg = compiled
+			aggregate.typeName = argType
+			aggregate.collation = collationOf(call.Args[0], child.Columns())
+			typeName = argType
+		} else if call.Name != "COUNT" {
+			return nil, errors.New("!Failed to query because " + call.String() + " can't be used here.")
+		}
+
+		switch call.Name {
+		case "COUNT":
+			typeName = intType
+		case "AVG":
+			typeName = floatType
+		case "SUM":
+			typeName = numericType(typeName, typeName)
+		}
+
+		a.aggregates = append(a.aggregates, aggregate)
+		a.columns = append(a.columns, diskio.ColumnDef{ColumnName: call.String(), TypeName: typeName})
+	}
+
+	return a, nil
+}
+
+// Open ...
+func (a *Aggregate) Open() error {
+	a.pending = nil
+	a.done = false
+	a.emitted = false
+	return a.child.Open()
+}
+
+// Next ...
+func (a *Aggregate) Next() ([]string, error) {
+	if a.pending == nil && a.done == false {
+		row, err := a.child.Next()
+		if err == io.EOF {
+			a.done = true
+		} else if err != nil {
+			return nil, err
+		} else {
+			a.pending = row
+		}
+	}
+
+	if a.pending == nil {
+		// an ungrouped aggregate still has a row for an empty input, e.g. COUNT(*) is 0
+		if len(a.groupBy) == 0 && a.emitted == false {
+			a.emitted = true
+			return a.result(nil, a.newAccumulators())
+		}
+		return nil, io.EOF
+	}
+
+	key, err := a.key(a.pending)
+	if err != nil {
+		return nil, err
+	}
+
+	accumulators := a.newAccumulators()
+
+	// fold rows in until one belongs to the next group
+	row := a.pending
+	a.pending = nil
+
+	for {
+		for i, aggregate := range a.aggregates {
+			err = accumulators[i].add(aggregate, row)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		row, err = a.child.Next()
+		if err == io.EOF {
+			a.done = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rowKey, err := a.key(row)
+		if err != nil {
+			return nil, err
+		}
+
+		if sameKey(key, rowKey, a.collations) == false {
+			a.pending = row
+			break
+		}
+	}
+
+	a.emitted = true
+	return a.result(key, accumulators)
+}
+
+// Close ...
+func (a *Aggregate) Close() error {
+	return a.child.Close()
+}
+
+// Columns ...
+func (a *Aggregate) Columns() []diskio.ColumnDef {
+	return a.columns
+}
+
+// Explain ...
+func (a *Aggregate) Explain() string {
+	var grouped []string
+	var folded []string
+
+	for i, column := range a.columns {
+		if i < len(a.groupBy) {
+			grouped = append(grouped, column.ColumnName)
+		} else {
+			folded = append(folded, column.ColumnName)
+		}
+	}
+
+	explanation := "Aggregate"
+	if len(grouped) > 0 {
+		explanation += " GROUP BY " + strings.Join(grouped, ", ")
+	}
+	if len(folded) > 0 {
+		explanation += ": " + strings.Join(folded, ", ")
+	}
+	return explanation
+}
+
+// Children ...
+func (a *Aggregate) Children() []Operator {
+	return []Operator{a.child}
+}
+
+// EstimatedRows guesses a tenth of the input forms distinct groups, unless Groups is known
+func (a *Aggregate) EstimatedRows() float64 {
+	if a.Groups >= 0 {
+		return a.Groups
+	}
+	return math.Max(1, a.child.EstimatedRows()/10)
+}
+
+func (a *Aggregate) key(row []string) ([]Value, error) {
+	key := make([]Value, len(a.groupBy))
+
+	for i, evaluate := range a.groupBy {
+		value, err := evaluate(row)
+		if err != nil {
+			return nil, err
+		}
+		key[i] = value
+	}
+
+	return key, nil
+}
+
+func (a *Aggregate) newAccumulators() []accumulator {
+	return make([]accumulator, len(a.aggregates))
+}
+
+func (a *Aggregate) result(key []Value, accumulators []accumulator) ([]string, error) {
+	var row []string
+
+	for _, value := range key {
+		row = append(row, FormatValue(value))
+	}
+
+	for i, aggregate := range a.aggregates {
+		err := accumulators[i].finish(aggregate)
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, FormatValue(accumulators[i].result(aggregate)))
+	}
+
+	return row, nil
+}
+
+// an accumulator is the running state of one aggregate over one group,
+// a distinct aggregate's values go through a hashDistinct first
+type accumulator struct {
+	count    int64
+	sum      Value
+	best     Value
+	distinct *hashDistinct
+}
+
+func (acc *accumulator) add(aggregate aggregateCall, row []string) error {
+	if aggregate.arg == nil {
+		acc.count++
+		return nil
+	}
+
+	value, err := aggregate.arg(row)
+	if err != nil || value == nil {
+		return err
+	}
+
+	if aggregate.distinct {
+		if acc.distinct == nil {
+			acc.distinct = newHashDistinct([]diskio.ColumnDef{{TypeName: aggregate.typeName, Collation: aggregate.collation}}, 0)
+		}
+
+		fresh, err := acc.distinct.add([]string{FormatValue(value)})
+		if err != nil || fresh == false {
+			return err
+		}
+	}
+
+	return acc.fold(aggregate, value)
+}
+
+// finish folds in the distinct values that were spilled, once the group is done
+func (acc *accumulator) finish(aggregate aggregateCall) error {
+	if acc.distinct == nil {
+		return nil
+	}
+	defer acc.distinct.close()
+
+	for {
+		row, err := acc.distinct.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = acc.fold(aggregate, ReadValue(row[0], aggregate.typeName))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (acc *accumulator) fold(aggregate aggregateCall, value Value) error {
+	var err error
+	acc.count++
+
+	switch aggregate.name {
+	case "SUM", "AVG":
+		if acc.sum == nil {
+			acc.sum = int64(0)
+		}
+		acc.sum, err = Arithmetic("+", acc.sum, value)
+		return err
+
+	case "MIN":
+		if acc.best == nil || compareValues(value, acc.best, aggregate.collation) < 0 {
+			acc.best = value
+		}
+
+	case "MAX":
+		if acc.best == nil || compareValues(value, acc.best, aggregate.collation) > 0 {
+			acc.best = value
+		}
+	}
+
+	return nil
+}
+
+// result is what an aggregate comes to, only COUNT isn't NULL over no values
+func (acc *accumulator) result(aggregate aggregateCall) Value {
+	switch aggregate.name {
+	case "COUNT":
+		return acc.count
+	case "SUM":
+		return acc.sum
+	case "AVG":
+		if acc.count == 0 {
+			return nil
+		}
+		return toFloat(acc.sum) / float64(acc.count)
+	}
+
+	return acc.best
+}
+
+//
+//			Helper functions
+//
+
+// sameKey checks if two keys are equal, comparing text in each under its collation
+func sameKey(a []Value, b []Value, collations []string) bool {
+	for i := range a {
+		if compareNullable(a[i], b[i], collations[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
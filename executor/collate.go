@@ -0,0 +1,96 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strings"
+)
+
+// collations, the ways text can be compared. A column without one uses NOCASE,
+// since sqlit has always ignored case. BINARY compares the bytes of text as they are,
+// and RTRIM does too, except that spaces at the end don't count
+const (
+	binaryCollation = "BINARY"
+	nocaseCollation = "NOCASE"
+	rtrimCollation  = "RTRIM"
+)
+
+// IsCollation checks if a name is a collation sqlit knows
+func IsCollation(name string) bool {
+	switch strings.ToUpper(name) {
+	case binaryCollation, nocaseCollation, rtrimCollation:
+		return true
+	}
+	return false
+}
+
+// collationKey writes text out so that two texts are equal under a collation exactly when their keys are
+func collationKey(text string, collation string) string {
+	switch collation {
+	case binaryCollation:
+		return text
+	case rtrimCollation:
+		return strings.TrimRight(text, " ")
+	}
+	return strings.ToLower(text)
+}
+
+// compareText orders two texts under a collation
+func compareText(a string, b string, collation string) int {
+	return strings.Compare(collationKey(a, collation), collationKey(b, collation))
+}
+
+// collationOf finds the collation an expression's text is compared with: an explicit COLLATE,
+// or the collation of the column it reads. Anything else doesn't have one
+func collationOf(expr parser.Expr, columns []diskio.ColumnDef) string {
+	for index, column := range columns {
+		if column.Table == "" && column.ColumnName == expr.String() {
+			return columns[index].Collation
+		}
+	}
+
+	switch e := expr.(type) {
+	case *parser.CollateExpr:
+		return e.Collation
+	case *parser.ColumnRef:
+		if index, err := ResolveColumn(e, columns); err == nil {
+			return columns[index].Collation
+		}
+	case *ColumnAt:
+		return e.Column.Collation
+	case *OuterRef:
+		if e.Outer != nil {
+			if index, err := ResolveColumn(e.Ref, e.Outer.columns); err == nil {
+				return e.Outer.columns[index].Collation
+			}
+		}
+	}
+
+	return ""
+}
+
+// comparisonCollation is the collation two operands are compared with. An explicit COLLATE
+// comes first, then the collation of a column, the left operand's before the right's
+func comparisonCollation(left parser.Expr, right parser.Expr, columns []diskio.ColumnDef) string {
+	_, leftExplicit := left.(*parser.CollateExpr)
+	_, rightExplicit := right.(*parser.CollateExpr)
+	if rightExplicit && leftExplicit == false {
+		return collationOf(right, columns)
+	}
+
+	if collation := collationOf(left, columns); collation != "" {
+		return collation
+	}
+	return collationOf(right, columns)
+}
+
+// compileCollate compiles x COLLATE collation, which is x itself. The collation only changes how it's compared
+func compileCollate(e *parser.CollateExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	if IsCollation(e.Collation) == false {
+		return nil, "", errors.New("!Failed to query because " + e.Collation + " isn't a collation.")
+	}
+	return compile(e.Operand, columns)
+}
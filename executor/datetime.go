@@ -0,0 +1,338 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the type families of dates and times, whose values are DateTimes
+const (
+	dateType      = "date"
+	timeType      = "time"
+	timestampType = "timestamp"
+)
+
+// A DateTime is a DATE, TIME or TIMESTAMP value, Kind is which. A DATE is at midnight,
+// and a TIME is on the first day of year 0. Times don't have a zone, they're kept in UTC
+type DateTime struct {
+	Time time.Time
+	Kind string
+}
+
+// the ISO-8601 layouts a date or time can be written in. A fraction of a second can follow the seconds of any of them
+var (
+	dateLayouts      = []string{"2006-01-02"}
+	timeLayouts      = []string{"15:04:05", "15:04"}
+	timestampLayouts = []string{"2006-01-02T15:04:05Z07:00", "2006-01-02 15:04:05Z07:00", "2006-01-02T15:04:05", "2006-01-02 15:04:05",
+		"2006-01-02T15:04", "2006-01-02 15:04"}
+)
+
+// ParseField checks a field that's about to be written to a column of some type,
+// and writes it the way it's persisted. Only dates and times are checked, as ISO-8601
+func ParseField(field string, typeName string) (string, error) {
+	family := typeFamily(typeName)
+	if isDateTimeType(family) == false || field == "" || field == diskio.Null || strings.EqualFold(field, "NULL") {
+		return field, nil
+	}
+
+	value, err := parseDateTime(field, family)
+	if err != nil {
+		return "", errors.New("!Failed to write '" + field + "' to a " + typeName + " column because it is not a valid " + family + ".")
+	}
+	return FormatValue(value), nil
+}
+
+func (d DateTime) String() string {
+	switch d.Kind {
+	case dateType:
+		return d.Time.Format("2006-01-02")
+	case timeType:
+		return d.Time.Format("15:04:05.999999")
+	}
+	return d.Time.Format("2006-01-02 15:04:05.999999")
+}
+
+// as converts a date or time to another kind: a TIMESTAMP loses its time as a DATE, or its date as a TIME
+func (d DateTime) as(kind string) DateTime {
+	t := d.Time
+
+	switch kind {
+	case dateType:
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case timeType:
+		t = time.Date(0, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+
+	return DateTime{Time: t, Kind: kind}
+}
+
+// now is NOW(), the current TIMESTAMP in UTC. A statement swaps its NOW() calls for Now
+// before it runs, so this only answers for an expression evaluated on its own
+func now(args []Value) (Value, error) {
+	return Now(time.Now()), nil
+}
+
+// Now is the value of NOW() at some time: a TIMESTAMP in UTC, to the second
+func Now(t time.Time) DateTime {
+	return DateTime{Time: t.UTC().Truncate(time.Second), Kind: timestampType}
+}
+
+// dateAdd is DATE_ADD(x, n, unit), x moved n years, months, weeks, days, hours, minutes or
+// seconds later, or earlier if n is negative. Text is read as a TIMESTAMP. A DATE can only be
+// moved by whole days, so it stays a DATE, and a TIME only by hours, minutes and seconds.
+// A day past the end of the month it's moved to is the last day of that month, so a month
+// after 2024-01-31 is 2024-02-29, and a year after 2024-02-29 is 2025-02-28
+func dateAdd(args []Value) (Value, error) {
+	d, isDateTime := args[0].(DateTime)
+	if isDateTime == false {
+		var err error
+		d, err = toDateTime(args[0], timestampType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	t := d.Time
+	count := int(n)
+
+	unit := strings.TrimSuffix(strings.ToLower(FormatValue(args[2])), "s")
+	if d.Kind == timeType && (unit == "year" || unit == "month" || unit == "week" || unit == "day") {
+		return nil, errors.New("!Failed to query because a time can't be moved by " + unit + "s, it has to be cast to a timestamp first.")
+	}
+
+	switch unit {
+	case "year":
+		t = addMonths(t, 12*count)
+	case "month":
+		t = addMonths(t, count)
+	case "week":
+		t = t.AddDate(0, 0, 7*count)
+	case "day":
+		t = t.AddDate(0, 0, count)
+	case "hour", "minute", "second":
+		if d.Kind == dateType {
+			return nil, errors.New("!Failed to query because a date can't be moved by " + unit + "s, it has to be cast to a timestamp first.")
+		}
+		step := map[string]time.Duration{"hour": time.Hour, "minute": time.Minute, "second": time.Second}[unit]
+		t = t.Add(time.Duration(n) * step)
+	default:
+		return nil, errors.New("!Failed to query because " + FormatValue(args[2]) + " isn't a unit of time.")
+	}
+
+	return DateTime{Time: t, Kind: d.Kind}.as(d.Kind), nil
+}
+
+// addMonths moves a time by whole months, keeping its day unless the month it's moved to is too short for it
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+
+	day := t.Day()
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+// strftime is STRFTIME(format, x), x written out by a format with %Y, %m, %d, %H, %M, %S,
+// %f (seconds with milliseconds), %j (day of the year), %w (day of the week from Sunday as 0),
+// %s (seconds since 1970), %F (%Y-%m-%d), %T (%H:%M:%S) and %% in it
+func strftime(args []Value) (Value, error) {
+	d, err := toDateTime(args[1], "")
+	if err != nil {
+		return nil, err
+	}
+
+	format := FormatValue(args[0])
+	t := d.Time
+	var out strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i == len(format)-1 {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		switch format[i] {
+		case 'Y':
+			out.WriteString(t.Format("2006"))
+		case 'm':
+			out.WriteString(t.Format("01"))
+		case 'd':
+			out.WriteString(t.Format("02"))
+		case 'H':
+			out.WriteString(t.Format("15"))
+		case 'M':
+			out.WriteString(t.Format("04"))
+		case 'S':
+			out.WriteString(t.Format("05"))
+		case 'f':
+			out.WriteString(t.Format("05.000"))
+		case 'j':
+			out.WriteString(t.Format("002"))
+		case 'w':
+			out.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 's':
+			out.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'F':
+			out.WriteString(t.Format("2006-01-02"))
+		case 'T':
+			out.WriteString(t.Format("15:04:05"))
+		case '%':
+			out.WriteByte('%')
+		default:
+			return nil, errors.New("!Failed to query because %" + string(format[i]) + " isn't a STRFTIME format.")
+		}
+	}
+
+	return out.String(), nil
+}
+
+func compileExtract(e *parser.ExtractExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	operand, _, err := compile(e.Operand, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch e.Field {
+	case "YEAR", "MONTH", "DAY", "HOUR", "MINUTE", "SECOND", "DOW", "DOY", "EPOCH":
+	default:
+		return nil, "", errors.New("!Failed to query because " + e.Field + " can't be extracted from a date or time.")
+	}
+
+	return func(row []string) (Value, error) {
+		value, err := operand(row)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return extract(e.Field, value)
+	}, intType, nil
+}
+
+// extract is one part of a date or time as a number
+func extract(field string, value Value) (Value, error) {
+	d, err := toDateTime(value, "")
+	if err != nil {
+		return nil, err
+	}
+	t := d.Time
+
+	switch field {
+	case "YEAR":
+		return int64(t.Year()), nil
+	case "MONTH":
+		return int64(t.Month()), nil
+	case "DAY":
+		return int64(t.Day()), nil
+	case "HOUR":
+		return int64(t.Hour()), nil
+	case "MINUTE":
+		return int64(t.Minute()), nil
+	case "SECOND":
+		return int64(t.Second()), nil
+	case "DOW":
+		return int64(t.Weekday()), nil
+	case "DOY":
+		return int64(t.YearDay()), nil
+	}
+
+	// EPOCH
+	return t.Unix(), nil
+}
+
+//
+//			Helper functions
+//
+
+func isDateTimeType(family string) bool {
+	return family == dateType || family == timeType || family == timestampType
+}
+
+// parseDateTime reads ISO-8601 text as a kind of date or time. A timestamp can be read as a DATE
+// or a TIME, keeping the part that fits. With no kind, it's whatever the text looks like
+func parseDateTime(text string, kind string) (DateTime, error) {
+	text = strings.TrimSpace(text)
+
+	layouts := map[string][]string{dateType: dateLayouts, timeType: timeLayouts, timestampType: timestampLayouts}
+	for _, found := range []string{dateType, timeType, timestampType} {
+		for _, layout := range layouts[found] {
+			t, err := time.Parse(layout, text)
+			if err != nil {
+				continue
+			}
+
+			d := DateTime{Time: t.UTC(), Kind: found}
+			if kind == "" {
+				return d, nil
+			}
+
+			// a time of day has no date to make a DATE or TIMESTAMP of
+			if found == timeType && kind != timeType {
+				break
+			}
+			return d.as(kind), nil
+		}
+	}
+
+	if kind == "" {
+		kind = "date or time"
+	}
+	return DateTime{}, errors.New("!Failed to query because '" + text + "' is not a valid " + kind + ".")
+}
+
+// toDateTime reads a value as a date or time, text is parsed as ISO-8601
+func toDateTime(value Value, kind string) (DateTime, error) {
+	if d, ok := value.(DateTime); ok {
+		if kind == "" {
+			return d, nil
+		}
+		return d.as(kind), nil
+	}
+
+	if text, ok := value.(string); ok {
+		return parseDateTime(text, kind)
+	}
+
+	return DateTime{}, errors.New("!Failed to query because '" + FormatValue(value) + "' is not a date or time.")
+}
+
+// compareDateTimes orders two values by time when either is a date or time, and the other reads as one
+func compareDateTimes(a Value, b Value) (int, bool) {
+	x, aIsDateTime := a.(DateTime)
+	y, bIsDateTime := b.(DateTime)
+
+	var err error
+	switch {
+	case aIsDateTime && bIsDateTime:
+	case aIsDateTime:
+		y, err = toDateTime(b, x.Kind)
+	case bIsDateTime:
+		x, err = toDateTime(a, y.Kind)
+	default:
+		return 0, false
+	}
+
+	if err != nil {
+		return 0, false
+	}
+
+	switch {
+	case x.Time.Before(y.Time):
+		return -1, true
+	case x.Time.After(y.Time):
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,268 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"encoding/gob"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"sqlit/diskio"
+	"strconv"
+	"strings"
+)
+
+// DistinctBufferRows is how many distinct rows are remembered in memory before the rest are spilled to disk
+var DistinctBufferRows = 10000
+
+// distinctPartitions is how many files the rows that don't fit in memory are spread over
+const distinctPartitions = 8
+
+// Distinct drops rows that are the same as one before them, by hashing them. A row is returned
+// as soon as it's first seen, in the order its child returns them, while the distinct rows
+// fit in DistinctBufferRows. Past that, rows are spilled and returned once the child is done
+type Distinct struct {
+	child    Operator
+	distinct *hashDistinct
+	done     bool
+
+	// how many partitions the last run spilled, for EXPLAIN ANALYZE
+	spilled int
+}
+
+// NewDistinct ...
+func NewDistinct(child Operator) *Distinct {
+	return &Distinct{child: child}
+}
+
+// Open ...
+func (d *Distinct) Open() error {
+	if d.distinct != nil {
+		d.distinct.close()
+	}
+
+	d.distinct = newHashDistinct(d.child.Columns(), 0)
+	d.done = false
+	return d.child.Open()
+}
+
+// Next ...
+func (d *Distinct) Next() ([]string, error) {
+	for d.done == false {
+		row, err := d.child.Next()
+		if err == io.EOF {
+			d.done = true
+			d.spilled = len(d.distinct.partitions)
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fresh, err := d.distinct.add(row)
+		if err != nil {
+			return nil, err
+		}
+		if fresh {
+			return row, nil
+		}
+	}
+
+	return d.distinct.next()
+}
+
+// Close removes any partitions that were spilled
+func (d *Distinct) Close() error {
+	if d.distinct != nil {
+		d.distinct.close()
+		d.distinct = nil
+	}
+	return d.child.Close()
+}
+
+// Columns ...
+func (d *Distinct) Columns() []diskio.ColumnDef {
+	return d.child.Columns()
+}
+
+// Explain ...
+func (d *Distinct) Explain() string {
+	if d.spilled > 0 {
+		return "Distinct, spilled " + strconv.Itoa(d.spilled) + " partition(s) to disk"
+	}
+	return "Distinct"
+}
+
+// Children ...
+func (d *Distinct) Children() []Operator {
+	return []Operator{d.child}
+}
+
+// EstimatedRows ...
+func (d *Distinct) EstimatedRows() float64 {
+	return d.child.EstimatedRows()
+}
+
+// a hashDistinct remembers the rows it's been given. Once DistinctBufferRows of them are
+// remembered, a row that isn't one of them is spilled to a partition picked by its hash,
+// so any duplicates of it land in the same one. Each partition is then read back through
+// a hashDistinct of its own, hashed differently at each depth so it splits up again
+type hashDistinct struct {
+	columns []diskio.ColumnDef
+	depth   int
+	seen    map[string]bool
+
+	partitions []*os.File
+	encoders   []*gob.Encoder
+
+	// the partition being read back, and what's left of it
+	partition int
+	decoder   *gob.Decoder
+	child     *hashDistinct
+}
+
+func newHashDistinct(columns []diskio.ColumnDef, depth int) *hashDistinct {
+	return &hashDistinct{columns: columns, depth: depth, seen: map[string]bool{}}
+}
+
+// add checks if a row is new. A row that's spilled isn't known to be new until it's read back by next
+func (h *hashDistinct) add(row []string) (bool, error) {
+	key := distinctKey(row, h.columns)
+	if h.seen[key] {
+		return false, nil
+	}
+
+	if len(h.seen) < DistinctBufferRows {
+		h.seen[key] = true
+		return true, nil
+	}
+
+	if h.partitions == nil {
+		for i := 0; i < distinctPartitions; i++ {
+			f, err := ioutil.TempFile("", "sqlit-distinct-")
+			if err != nil {
+				return false, err
+			}
+			h.partitions = append(h.partitions, f)
+			h.encoders = append(h.encoders, gob.NewEncoder(f))
+		}
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte{byte(h.depth)})
+	hash.Write([]byte(key))
+
+	return false, h.encoders[hash.Sum32()%distinctPartitions].Encode(row)
+}
+
+// next returns the new rows among those that were spilled, a partition at a time
+func (h *hashDistinct) next() ([]string, error) {
+	for {
+		if h.child != nil {
+			if h.decoder != nil {
+				var row []string
+				err := h.decoder.Decode(&row)
+				if err == nil {
+					fresh, err := h.child.add(row)
+					if err != nil {
+						return nil, err
+					}
+					if fresh {
+						return row, nil
+					}
+					continue
+				}
+				if err != io.EOF {
+					return nil, err
+				}
+				h.decoder = nil
+			}
+
+			row, err := h.child.next()
+			if err != io.EOF {
+				return row, err
+			}
+
+			h.child.close()
+			h.child = nil
+		}
+
+		if h.partition >= len(h.partitions) {
+			return nil, io.EOF
+		}
+
+		f := h.partitions[h.partition]
+		h.partition++
+
+		_, err := f.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		h.decoder = gob.NewDecoder(f)
+		h.child = newHashDistinct(h.columns, h.depth+1)
+	}
+}
+
+func (h *hashDistinct) close() {
+	if h.child != nil {
+		h.child.close()
+	}
+
+	for _, f := range h.partitions {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	h.partitions = nil
+	h.encoders = nil
+	h.seen = nil
+}
+
+//
+//			Helper functions
+//
+
+// distinctKey writes a row's values out so equal rows have equal keys, with the same idea
+// of equal as comparisons: numbers by value, so 1 and 1.0 are the same, and text under its column's collation
+func distinctKey(row []string, columns []diskio.ColumnDef) string {
+	var key strings.Builder
+
+	for i, column := range columns {
+		value := ReadValue(row[i], column.TypeName)
+		number, isNumber := toNumber(value)
+
+		switch {
+		case value == nil:
+			key.WriteString("n")
+		case isNumber:
+			if f, isFloat := number.(float64); isFloat && f == float64(int64(f)) {
+				number = int64(f)
+			}
+			key.WriteString("#" + FormatValue(number))
+		default:
+			key.WriteString("'" + collationKey(FormatValue(value), column.Collation))
+		}
+
+		key.WriteByte(0)
+	}
+
+	return key.String()
+}
+
+// ConflictKey writes out some fields of a record like distinctKey, so two records conflict on those
+// fields exactly when their keys are equal. A NULL doesn't conflict with anything, so a record with one has no key
+func ConflictKey(record []string, fields []int, columns []diskio.ColumnDef) (string, bool) {
+	values := make([]string, len(fields))
+	keyColumns := make([]diskio.ColumnDef, len(fields))
+
+	for i, field := range fields {
+		if record[field] == diskio.Null {
+			return "", false
+		}
+		values[i] = record[field]
+		keyColumns[i] = columns[field]
+	}
+
+	return distinctKey(values, keyColumns), true
+}
@@ -0,0 +1,311 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+// Package executor runs queries as a pipeline of operators, in the style of
+// Volcano. Each operator pulls rows from its children one at a time, so a
+// query never holds a whole table in memory: scans stream records off disk,
+// joins re-scan their inner table, and sorts spill runs to temp files once
+// they grow past SortBufferRows.
+package executor
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strings"
+)
+
+// An Operator is a node of an execution pipeline. Open prepares it (and its children),
+// Next returns one row at a time until io.EOF, and Close releases whatever it holds.
+// Rows are fields in their persisted form, described by Columns
+type Operator interface {
+	Open() error
+	Next() ([]string, error)
+	Close() error
+	Columns() []diskio.ColumnDef
+
+	// Explain describes the operator as a line of EXPLAIN, Children are the operators it reads from
+	Explain() string
+	Children() []Operator
+
+	// EstimatedRows guesses how many rows the operator will return, without running it
+	EstimatedRows() float64
+}
+
+// Scan streams the records of a table
+type Scan struct {
+	session *diskio.Session
+	table   string
+	alias   string
+	columns []diskio.ColumnDef
+	reader  *diskio.TableReader
+
+	// Rows is the table's record count, negative until the planner says or it's been counted
+	Rows float64
+}
+
+// NewScan creates a scan of a table, its columns are qualified by the alias if there is one
+func NewScan(session *diskio.Session, table string, alias string) (*Scan, error) {
+	columns, err := diskio.ReadColumnDefs(session, table)
+	if err != nil {
+		return nil, errors.New("!Failed to query table " + table + " because it does not exist.")
+	}
+
+	qualifier := table
+	if alias != "" {
+		qualifier = alias
+	}
+
+	for i := range columns {
+		columns[i].Table = qualifier
+	}
+
+	return &Scan{session: session, table: table, alias: alias, columns: columns, Rows: -1}, nil
+}
+
+// Open starts reading from the first record, a scan may be opened again once closed
+func (s *Scan) Open() error {
+	reader, err := diskio.OpenTable(s.session, s.table)
+	if err != nil {
+		return err
+	}
+
+	s.reader = reader
+	return nil
+}
+
+// Next ...
+func (s *Scan) Next() ([]string, error) {
+	return s.reader.Next()
+}
+
+// Close ...
+func (s *Scan) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+
+	err := s.reader.Close()
+	s.reader = nil
+	return err
+}
+
+// Columns ...
+func (s *Scan) Columns() []diskio.ColumnDef {
+	return s.columns
+}
+
+// Explain ...
+func (s *Scan) Explain() string {
+	if s.alias != "" {
+		return "Full Scan " + s.table + " AS " + s.alias
+	}
+	return "Full Scan " + s.table
+}
+
+// Children ...
+func (s *Scan) Children() []Operator {
+	return nil
+}
+
+// EstimatedRows counts the table's records if the planner didn't know how many there are,
+// which is only done when a plan is explained
+func (s *Scan) EstimatedRows() float64 {
+	if s.Rows < 0 {
+		count, _ := diskio.CountRecords(s.session, s.table)
+		s.Rows = float64(count)
+	}
+	return s.Rows
+}
+
+// Filter passes on the rows of its child that satisfy a predicate
+type Filter struct {
+	child     Operator
+	predicate evaluator
+	expr      parser.Expr
+
+	// Selectivity is the fraction of rows expected to pass
+	Selectivity float64
+}
+
+// NewFilter ...
+func NewFilter(child Operator, predicate parser.Expr) (*Filter, error) {
+	compiled, _, err := compile(predicate, child.Columns())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{child: child, predicate: compiled, expr: predicate, Selectivity: Selectivity(predicate)}, nil
+}
+
+// Open ...
+func (f *Filter) Open() error {
+	return f.child.Open()
+}
+
+// Next ...
+func (f *Filter) Next() ([]string, error) {
+	for {
+		row, err := f.child.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		matches, err := evaluatePredicate(f.predicate, row)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches {
+			return row, nil
+		}
+	}
+}
+
+// Close ...
+func (f *Filter) Close() error {
+	return f.child.Close()
+}
+
+// Columns ...
+func (f *Filter) Columns() []diskio.ColumnDef {
+	return f.child.Columns()
+}
+
+// Predicate is the expression rows are filtered by
+func (f *Filter) Predicate() parser.Expr {
+	return f.expr
+}
+
+// Explain ...
+func (f *Filter) Explain() string {
+	return "Filter " + f.expr.String()
+}
+
+// Children ...
+func (f *Filter) Children() []Operator {
+	return []Operator{f.child}
+}
+
+// EstimatedRows ...
+func (f *Filter) EstimatedRows() float64 {
+	return f.child.EstimatedRows() * f.Selectivity
+}
+
+// Project computes the result columns of a query from each row of its child
+type Project struct {
+	child     Operator
+	exprs     []evaluator
+	exprTrees []parser.Expr
+	columns   []diskio.ColumnDef
+}
+
+// NewProject creates a projection of expressions, named by their alias if they have one.
+// A column keeps its name, anything else is named after its SQL
+func NewProject(child Operator, exprs []parser.Expr, aliases []string) (*Project, error) {
+	project := &Project{child: child, exprTrees: exprs}
+
+	for i, expr := range exprs {
+		compiled, typeName, err := compile(expr, child.Columns())
+		if err != nil {
+			return nil, err
+		}
+
+		collation := collationOf(expr, child.Columns())
+		column := diskio.ColumnDef{ColumnName: expr.String(), TypeName: typeName, Collation: collation}
+
+		if ref, ok := expr.(*parser.ColumnRef); ok {
+			index, _ := ResolveColumn(ref, child.Columns())
+			column = child.Columns()[index]
+		}
+
+		if aliases[i] != "" {
+			column = diskio.ColumnDef{ColumnName: aliases[i], TypeName: typeName, Collation: collation}
+		}
+
+		project.exprs = append(project.exprs, compiled)
+		project.columns = append(project.columns, column)
+	}
+
+	return project, nil
+}
+
+// Open ...
+func (p *Project) Open() error {
+	return p.child.Open()
+}
+
+// Next ...
+func (p *Project) Next() ([]string, error) {
+	row, err := p.child.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	projected := make([]string, len(p.exprs))
+	for i, expr := range p.exprs {
+		value, err := expr(row)
+		if err != nil {
+			return nil, err
+		}
+		projected[i] = FormatValue(value)
+	}
+
+	return projected, nil
+}
+
+// Close ...
+func (p *Project) Close() error {
+	return p.child.Close()
+}
+
+// Columns ...
+func (p *Project) Columns() []diskio.ColumnDef {
+	return p.columns
+}
+
+// Exprs are the expressions each result column is computed from
+func (p *Project) Exprs() []parser.Expr {
+	return p.exprTrees
+}
+
+// Explain ...
+func (p *Project) Explain() string {
+	var names []string
+	for _, column := range p.columns {
+		names = append(names, column.ColumnName)
+	}
+	return "Project " + strings.Join(names, ", ")
+}
+
+// Children ...
+func (p *Project) Children() []Operator {
+	return []Operator{p.child}
+}
+
+// EstimatedRows ...
+func (p *Project) EstimatedRows() float64 {
+	return p.child.EstimatedRows()
+}
+
+//
+//			Helper functions
+//
+
+// drain reads every remaining row of an operator into fn
+func drain(operator Operator, fn func(row []string) error) error {
+	for {
+		row, err := operator.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+}
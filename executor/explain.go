@@ -0,0 +1,138 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"math"
+	"sqlit/parser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile wraps an operator to measure it for EXPLAIN ANALYZE. Loops counts how many
+// times it was opened (the right side of a join is opened once per left row), Rows how
+// many rows it returned over all of them, and Elapsed the time spent in it and its children
+type Profile struct {
+	Operator
+	Loops   int
+	Rows    int
+	Elapsed time.Duration
+}
+
+// NewProfile ...
+func NewProfile(operator Operator) *Profile {
+	return &Profile{Operator: operator}
+}
+
+// Open ...
+func (p *Profile) Open() error {
+	start := time.Now()
+	err := p.Operator.Open()
+	p.Elapsed += time.Since(start)
+	p.Loops++
+	return err
+}
+
+// Next ...
+func (p *Profile) Next() ([]string, error) {
+	start := time.Now()
+	row, err := p.Operator.Next()
+	p.Elapsed += time.Since(start)
+	if err == nil {
+		p.Rows++
+	}
+	return row, err
+}
+
+// Close ...
+func (p *Profile) Close() error {
+	start := time.Now()
+	err := p.Operator.Close()
+	p.Elapsed += time.Since(start)
+	return err
+}
+
+// Explain lays a plan out as an indented tree, one operator per line with its estimated rows.
+// Profiled operators are also annotated with what actually happened when they ran
+func Explain(operator Operator) []string {
+	return explainNode(operator, 0)
+}
+
+// Analyze runs a plan to completion, discarding its rows, so its profiles can be explained
+func Analyze(operator Operator) error {
+	err := operator.Open()
+	if err != nil {
+		operator.Close()
+		return err
+	}
+
+	err = drain(operator, func(row []string) error {
+		return nil
+	})
+
+	operator.Close()
+	return err
+}
+
+func explainNode(operator Operator, depth int) []string {
+	line := strings.Repeat("  ", depth)
+	if depth > 0 {
+		line += "-> "
+	}
+
+	line += operator.Explain() + "  (estimated rows=" + formatEstimate(operator.EstimatedRows()) + ")"
+
+	if profile, ok := operator.(*Profile); ok {
+		line += " (actual rows=" + strconv.Itoa(profile.Rows) +
+			" loops=" + strconv.Itoa(profile.Loops) +
+			" time=" + strconv.FormatFloat(float64(profile.Elapsed)/float64(time.Millisecond), 'f', 3, 64) + "ms)"
+	}
+
+	lines := []string{line}
+	for _, child := range operator.Children() {
+		lines = append(lines, explainNode(child, depth+1)...)
+	}
+
+	return lines
+}
+
+// Selectivity guesses the fraction of rows a predicate lets through, from its shape alone:
+// an equality matches a tenth of rows, a range a third, and anything else half of them
+func Selectivity(expr parser.Expr) float64 {
+	switch e := expr.(type) {
+	case *parser.BinaryExpr:
+		switch e.Operator {
+		case "AND":
+			return Selectivity(e.Left) * Selectivity(e.Right)
+		case "OR":
+			left, right := Selectivity(e.Left), Selectivity(e.Right)
+			return left + right - left*right
+		case "=":
+			return 0.1
+		case "!=":
+			return 0.9
+		case "<", ">", "<=", ">=":
+			return 1.0 / 3
+		}
+	case *parser.UnaryExpr:
+		if e.Operator == "NOT" {
+			return 1 - Selectivity(e.Operand)
+		}
+	case *parser.IsNullExpr:
+		if e.Not {
+			return 0.9
+		}
+		return 0.1
+	}
+
+	return 0.5
+}
+
+//
+//			Helper functions
+//
+
+func formatEstimate(rows float64) string {
+	return strconv.FormatFloat(math.Ceil(rows), 'f', 0, 64)
+}
@@ -0,0 +1,503 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"math"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"strconv"
+	"strings"
+)
+
+// An evaluator computes an expression's value against a row of an operator's input
+type evaluator func(row []string) (Value, error)
+
+// ColumnAt is the column at a position, for referring to columns that might share a name
+type ColumnAt struct {
+	Index  int
+	Column diskio.ColumnDef
+}
+
+func (c *ColumnAt) String() string {
+	return c.Column.ColumnName
+}
+
+// compile binds an expression to the columns of the rows it will be evaluated against,
+// so column names are only looked up once. It also returns the type the expression produces
+func compile(expr parser.Expr, columns []diskio.ColumnDef) (evaluator, string, error) {
+
+	// an expression an operator below already computed (an aggregate or a grouped
+	// expression) is read back out of the column named after it
+	switch expr.(type) {
+	case *parser.ColumnRef, *parser.Literal, *OuterRef, *ColumnAt:
+	default:
+		for index, column := range columns {
+			if column.Table == "" && column.ColumnName == expr.String() {
+				return columnEvaluator(index, column.TypeName), column.TypeName, nil
+			}
+		}
+	}
+
+	switch e := expr.(type) {
+	case *parser.ColumnRef:
+		index, err := ResolveColumn(e, columns)
+		if err != nil {
+			return nil, "", err
+		}
+		return columnEvaluator(index, columns[index].TypeName), columns[index].TypeName, nil
+
+	case *parser.Literal:
+		return compileLiteral(e)
+
+	case *parser.UnaryExpr:
+		return compileUnary(e, columns)
+
+	case *parser.BinaryExpr:
+		return compileBinary(e, columns)
+
+	case *parser.IsNullExpr:
+		operand, _, err := compile(e.Operand, columns)
+		if err != nil {
+			return nil, "", err
+		}
+		return func(row []string) (Value, error) {
+			value, err := operand(row)
+			if err != nil {
+				return nil, err
+			}
+			return boolValue((value == nil) != e.Not), nil
+		}, intType, nil
+
+	case *parser.InExpr:
+		return compileIn(e, columns)
+
+	case *parser.PatternExpr:
+		return compilePattern(e, columns)
+
+	case *parser.CollateExpr:
+		return compileCollate(e, columns)
+
+	case *Subquery:
+		return compileSubquery(e, columns)
+
+	case *OuterRef:
+		return compileOuterRef(e)
+
+	case *ColumnAt:
+		return columnEvaluator(e.Index, e.Column.TypeName), e.Column.TypeName, nil
+
+	case *parser.FuncCall:
+		if e.Over != nil {
+			return nil, "", errors.New("!Failed to query because " + e.String() + " can't be used here.")
+		}
+		if parser.WindowFunctions[e.Name] {
+			return nil, "", errors.New("!Failed to query because " + e.Name + " needs OVER.")
+		}
+		if parser.Aggregates[e.Name] {
+			return nil, "", errors.New("!Failed to query because " + e.String() + " can't be used here.")
+		}
+		if function, ok := scalarFunctions[e.Name]; ok {
+			return compileFunction(e, function, columns)
+		}
+		return nil, "", errors.New("!Failed to query because function " + e.Name + " does not exist.")
+
+	case *parser.CastExpr:
+		return compileCast(e, columns)
+
+	case *parser.CaseExpr:
+		return compileCase(e, columns)
+
+	case *parser.ExtractExpr:
+		return compileExtract(e, columns)
+
+	case *parser.Star:
+		return nil, "", errors.New("!Failed to query because " + e.String() + " can't be used here.")
+	}
+
+	return nil, "", errors.New("!Failed to query because " + expr.String() + " isn't supported.")
+}
+
+// EvaluateConstant evaluates an expression that doesn't read any columns
+func EvaluateConstant(expr parser.Expr) (Value, error) {
+	evaluate, _, err := compile(expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return evaluate(nil)
+}
+
+// CompileFilter compiles a predicate to test the records of a table against,
+// a record passes when the predicate is true, not when it's false or NULL
+func CompileFilter(expr parser.Expr, columns []diskio.ColumnDef) (func(record []string) (bool, error), error) {
+	evaluate, _, err := compile(expr, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record []string) (bool, error) {
+		value, err := evaluate(record)
+		if err != nil {
+			return false, err
+		}
+		result, _ := Truth(value)
+		return result, nil
+	}, nil
+}
+
+// CompileField compiles a value that's written to a column, from the records of a table. It's written the way it's
+// persisted, and a date or time is checked like ParseField checks it. A literal other than NULL is written as it's given.
+// Text that's exactly how NULL is persisted can't be written, it would read back as NULL
+func CompileField(expr parser.Expr, columns []diskio.ColumnDef, column diskio.ColumnDef) (func(record []string) (string, error), error) {
+	if literal, ok := expr.(*parser.Literal); ok && literal.Kind != "NULL" {
+		if literal.Value == diskio.Null {
+			return nil, nullTextError(column)
+		}
+
+		field, err := ParseField(literal.Value, column.TypeName)
+		if err != nil {
+			return nil, err
+		}
+		return func(record []string) (string, error) { return field, nil }, nil
+	}
+
+	evaluate, _, err := compile(expr, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record []string) (string, error) {
+		value, err := evaluate(record)
+		if err != nil {
+			return "", err
+		}
+		if text, ok := value.(string); ok && text == diskio.Null {
+			return "", nullTextError(column)
+		}
+		return ParseField(FormatValue(value), column.TypeName)
+	}, nil
+}
+
+func nullTextError(column diskio.ColumnDef) error {
+	return errors.New("!Failed to write '" + diskio.Null + "' to column " + column.ColumnName + " because it's how NULL is persisted.")
+}
+
+// ResolveColumn finds the index of the column a reference names
+func ResolveColumn(ref *parser.ColumnRef, columns []diskio.ColumnDef) (int, error) {
+	index := -1
+
+	for i, column := range columns {
+		if strings.EqualFold(column.ColumnName, ref.Column) == false {
+			continue
+		}
+		if ref.Table != "" && strings.EqualFold(column.Table, ref.Table) == false {
+			continue
+		}
+
+		if index != -1 {
+			return -1, errors.New("!Failed to query because column " + ref.String() + " is ambiguous.")
+		}
+		index = i
+	}
+
+	if index == -1 {
+		return -1, errors.New("!Failed to query because column " + ref.String() + " does not exist.")
+	}
+
+	return index, nil
+}
+
+func columnEvaluator(index int, typeName string) evaluator {
+	return func(row []string) (Value, error) {
+		if index >= len(row) {
+			return nil, nil
+		}
+		return ReadValue(row[index], typeName), nil
+	}
+}
+
+func compileLiteral(literal *parser.Literal) (evaluator, string, error) {
+	var value Value
+	typeName := textType
+
+	switch literal.Kind {
+	case "NULL":
+		value = nil
+	case tokenizer.Number:
+		if i, err := strconv.ParseInt(literal.Value, 10, 64); err == nil {
+			value = i
+			typeName = intType
+		} else if f, err := strconv.ParseFloat(literal.Value, 64); err == nil {
+			value = f
+			typeName = floatType
+		} else {
+			return nil, "", errors.New("!Failed to query because " + literal.Value + " is not a number.")
+		}
+	default:
+		value = literal.Value
+	}
+
+	return func(row []string) (Value, error) {
+		return value, nil
+	}, typeName, nil
+}
+
+func compileUnary(e *parser.UnaryExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	operand, typeName, err := compile(e.Operand, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if e.Operator == "NOT" {
+		return func(row []string) (Value, error) {
+			value, err := operand(row)
+			if err != nil {
+				return nil, err
+			}
+			return Not(value), nil
+		}, intType, nil
+	}
+
+	return func(row []string) (Value, error) {
+		value, err := operand(row)
+		if err != nil {
+			return nil, err
+		}
+		return Negate(value)
+	}, numericType(typeName, typeName), nil
+}
+
+func compileBinary(e *parser.BinaryExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	left, leftType, err := compile(e.Left, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	right, rightType, err := compile(e.Right, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	operator := e.Operator
+
+	switch operator {
+	case "AND", "OR":
+		return func(row []string) (Value, error) {
+			a, b, err := evaluatePair(left, right, row)
+			if err != nil {
+				return nil, err
+			}
+			return Logic(operator, a, b), nil
+		}, intType, nil
+
+	case "=", "!=", "<", ">", "<=", ">=":
+		collation := comparisonCollation(e.Left, e.Right, columns)
+		return func(row []string) (Value, error) {
+			a, b, err := evaluatePair(left, right, row)
+			if err != nil {
+				return nil, err
+			}
+			return Compare(operator, a, b, collation), nil
+		}, intType, nil
+
+	case "||":
+		return func(row []string) (Value, error) {
+			a, b, err := evaluatePair(left, right, row)
+			if err != nil {
+				return nil, err
+			}
+			return Concat(a, b), nil
+		}, textType, nil
+	}
+
+	typeName := numericType(leftType, rightType)
+	if operator == "/" && typeName == intType {
+		typeName = floatType
+	}
+
+	return func(row []string) (Value, error) {
+		a, b, err := evaluatePair(left, right, row)
+		if err != nil {
+			return nil, err
+		}
+		return Arithmetic(operator, a, b)
+	}, typeName, nil
+}
+
+// Compare compares two values with a comparison operator, giving 1 or 0, or NULL if either is NULL.
+// Text is compared under a collation, an empty one ignores case
+func Compare(operator string, a Value, b Value, collation string) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	return boolValue(satisfies(operator, compareValues(a, b, collation)))
+}
+
+// Logic applies AND or OR with SQL's three valued logic, where NULL is unknown
+func Logic(operator string, a Value, b Value) Value {
+	aTrue, aKnown := Truth(a)
+	bTrue, bKnown := Truth(b)
+
+	if operator == "AND" {
+		if (aKnown && aTrue == false) || (bKnown && bTrue == false) {
+			return boolValue(false)
+		}
+		if aKnown && bKnown {
+			return boolValue(true)
+		}
+		return nil
+	}
+
+	if (aKnown && aTrue) || (bKnown && bTrue) {
+		return boolValue(true)
+	}
+	if aKnown && bKnown {
+		return boolValue(false)
+	}
+	return nil
+}
+
+// Not negates a value's truth, NOT NULL is still NULL
+func Not(value Value) Value {
+	result, known := Truth(value)
+	if known == false {
+		return nil
+	}
+	return boolValue(result == false)
+}
+
+// Negate flips the sign of a number
+func Negate(value Value) (Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	number, ok := toNumber(value)
+	if ok == false {
+		return nil, notANumber(value)
+	}
+	if i, isInt := number.(int64); isInt {
+		return -i, nil
+	}
+	return -toFloat(number), nil
+}
+
+// Concat joins two values as text
+func Concat(a Value, b Value) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	return FormatValue(a) + FormatValue(b)
+}
+
+// Arithmetic keeps integers whole except when dividing, which always gives a float. Anything with NULL is NULL
+func Arithmetic(operator string, a Value, b Value) (Value, error) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+
+	aNumber, ok := toNumber(a)
+	if ok == false {
+		return nil, notANumber(a)
+	}
+
+	bNumber, ok := toNumber(b)
+	if ok == false {
+		return nil, notANumber(b)
+	}
+
+	aInt, aIsInt := aNumber.(int64)
+	bInt, bIsInt := bNumber.(int64)
+
+	if aIsInt && bIsInt && operator != "/" {
+		switch operator {
+		case "+":
+			return aInt + bInt, nil
+		case "-":
+			return aInt - bInt, nil
+		case "*":
+			return aInt * bInt, nil
+		case "%":
+			if bInt == 0 {
+				return nil, nil
+			}
+			return aInt % bInt, nil
+		}
+	}
+
+	x, y := toFloat(aNumber), toFloat(bNumber)
+
+	switch operator {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, nil
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return nil, nil
+		}
+		return math.Mod(x, y), nil
+	}
+
+	return nil, errors.New("!Failed to query because operator " + operator + " isn't supported.")
+}
+
+//
+//			Helper functions
+//
+
+func evaluatePair(left evaluator, right evaluator, row []string) (Value, Value, error) {
+	a, err := left(row)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b, err := right(row)
+	return a, b, err
+}
+
+// satisfies checks a comparison's result against its operator
+func satisfies(operator string, comparison int) bool {
+	switch operator {
+	case "=":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	case "<":
+		return comparison < 0
+	case ">":
+		return comparison > 0
+	case "<=":
+		return comparison <= 0
+	case ">=":
+		return comparison >= 0
+	}
+	return false
+}
+
+func numericType(leftType string, rightType string) string {
+	if typeFamily(leftType) == intType && typeFamily(rightType) == intType {
+		return intType
+	}
+	return floatType
+}
+
+// evaluatePredicate checks whether a row satisfies a predicate, NULL doesn't
+func evaluatePredicate(predicate evaluator, row []string) (bool, error) {
+	value, err := predicate(row)
+	if err != nil {
+		return false, err
+	}
+
+	result, known := Truth(value)
+	return result && known, nil
+}
@@ -0,0 +1,465 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"math"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// a scalarFunction is a built-in function of one row's values, taking minArgs to maxArgs
+// arguments, any number when maxArgs is -1. Unless it takesNull, a NULL argument makes it NULL
+type scalarFunction struct {
+	minArgs   int
+	maxArgs   int
+	takesNull bool
+	typeOf    func(types []string) string
+	apply     func(args []Value) (Value, error)
+}
+
+// scalarFunctions are the functions that can be called in an expression, besides the aggregates
+var scalarFunctions = map[string]scalarFunction{
+	// text
+	"UPPER":     {minArgs: 1, maxArgs: 1, typeOf: textResult, apply: upper},
+	"LOWER":     {minArgs: 1, maxArgs: 1, typeOf: textResult, apply: lower},
+	"LENGTH":    {minArgs: 1, maxArgs: 1, typeOf: intResult, apply: length},
+	"SUBSTR":    {minArgs: 2, maxArgs: 3, typeOf: textResult, apply: substr},
+	"SUBSTRING": {minArgs: 2, maxArgs: 3, typeOf: textResult, apply: substr},
+	"TRIM":      {minArgs: 1, maxArgs: 2, typeOf: textResult, apply: trim(strings.Trim)},
+	"LTRIM":     {minArgs: 1, maxArgs: 2, typeOf: textResult, apply: trim(strings.TrimLeft)},
+	"RTRIM":     {minArgs: 1, maxArgs: 2, typeOf: textResult, apply: trim(strings.TrimRight)},
+	"REPLACE":   {minArgs: 3, maxArgs: 3, typeOf: textResult, apply: replace},
+
+	// math
+	"ABS":     {minArgs: 1, maxArgs: 1, typeOf: numericResult, apply: abs},
+	"ROUND":   {minArgs: 1, maxArgs: 2, typeOf: roundResult, apply: round},
+	"FLOOR":   {minArgs: 1, maxArgs: 1, typeOf: intResult, apply: wholeNumber(math.Floor)},
+	"CEIL":    {minArgs: 1, maxArgs: 1, typeOf: intResult, apply: wholeNumber(math.Ceil)},
+	"CEILING": {minArgs: 1, maxArgs: 1, typeOf: intResult, apply: wholeNumber(math.Ceil)},
+	"MOD":     {minArgs: 2, maxArgs: 2, typeOf: numericResult, apply: mod},
+
+	// NULL handling
+	"COALESCE": {minArgs: 1, maxArgs: -1, takesNull: true, typeOf: commonType, apply: coalesce},
+	"IFNULL":   {minArgs: 2, maxArgs: 2, takesNull: true, typeOf: commonType, apply: coalesce},
+	"NULLIF":   {minArgs: 2, maxArgs: 2, takesNull: true, typeOf: firstType, apply: nullIf},
+
+	// dates and times
+	"NOW":      {minArgs: 0, maxArgs: 0, typeOf: timestampResult, apply: now},
+	"DATE_ADD": {minArgs: 3, maxArgs: 3, typeOf: dateTimeResult, apply: dateAdd},
+	"STRFTIME": {minArgs: 2, maxArgs: 2, typeOf: textResult, apply: strftime},
+}
+
+func compileFunction(e *parser.FuncCall, function scalarFunction, columns []diskio.ColumnDef) (evaluator, string, error) {
+	if len(e.Args) < function.minArgs || (function.maxArgs != -1 && len(e.Args) > function.maxArgs) {
+		return nil, "", errors.New("!Failed to query because " + e.Name + " takes " + describeArgCount(function) + ".")
+	}
+
+	var args []evaluator
+	var types []string
+
+	for _, arg := range e.Args {
+		compiled, typeName, err := compile(arg, columns)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// a NULL on its own doesn't have a type to contribute
+		if isNullLiteral(arg) {
+			typeName = ""
+		}
+
+		args = append(args, compiled)
+		types = append(types, typeName)
+	}
+
+	return func(row []string) (Value, error) {
+		values := make([]Value, len(args))
+
+		for i, arg := range args {
+			value, err := arg(row)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil && function.takesNull == false {
+				return nil, nil
+			}
+			values[i] = value
+		}
+
+		return function.apply(values)
+	}, function.typeOf(types), nil
+}
+
+func compileCast(e *parser.CastExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	operand, _, err := compile(e.Operand, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return func(row []string) (Value, error) {
+		value, err := operand(row)
+		if err != nil {
+			return nil, err
+		}
+		return Cast(value, e.TypeName)
+	}, e.TypeName, nil
+}
+
+// compileCase compiles each branch of a CASE, it's only ever evaluated up to the branch that's taken
+func compileCase(e *parser.CaseExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	var operand, otherwise evaluator
+	var whens, thens []evaluator
+	var types, collations []string
+	var err error
+
+	if e.Operand != nil {
+		operand, _, err = compile(e.Operand, columns)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, when := range e.Whens {
+		compiled, _, err := compile(when.When, columns)
+		if err != nil {
+			return nil, "", err
+		}
+		whens = append(whens, compiled)
+
+		if e.Operand != nil {
+			collations = append(collations, comparisonCollation(e.Operand, when.When, columns))
+		}
+
+		compiled, typeName, err := compile(when.Then, columns)
+		if err != nil {
+			return nil, "", err
+		}
+		thens = append(thens, compiled)
+
+		if isNullLiteral(when.Then) == false {
+			types = append(types, typeName)
+		}
+	}
+
+	if e.Else != nil {
+		var typeName string
+		otherwise, typeName, err = compile(e.Else, columns)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if isNullLiteral(e.Else) == false {
+			types = append(types, typeName)
+		}
+	}
+
+	return func(row []string) (Value, error) {
+		var subject Value
+		if operand != nil {
+			subject, err = operand(row)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for i, when := range whens {
+			value, err := when(row)
+			if err != nil {
+				return nil, err
+			}
+
+			// CASE x WHEN y is x = y, so a NULL never matches
+			if operand != nil {
+				value = Compare("=", subject, value, collations[i])
+			}
+
+			if result, known := Truth(value); result && known {
+				return thens[i](row)
+			}
+		}
+
+		if otherwise != nil {
+			return otherwise(row)
+		}
+		return nil, nil
+	}, commonType(types), nil
+}
+
+// Cast converts a value to a column type's family. Text that doesn't read as a number can't be
+// made one, and a float made an int is truncated toward zero. Dates and times are read from ISO-8601
+func Cast(value Value, typeName string) (Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	family := typeFamily(typeName)
+	if family == textType {
+		return FormatValue(value), nil
+	}
+
+	if isDateTimeType(family) {
+		d, err := toDateTime(value, family)
+		if err != nil {
+			return nil, errors.New("!Failed to query because '" + FormatValue(value) + "' can't be cast to " + typeName + ".")
+		}
+		return d, nil
+	}
+
+	number, ok := toNumber(value)
+	if ok == false {
+		return nil, errors.New("!Failed to query because '" + FormatValue(value) + "' can't be cast to " + typeName + ".")
+	}
+
+	if family == floatType {
+		return toFloat(number), nil
+	}
+
+	if f, isFloat := number.(float64); isFloat {
+		return int64(f), nil
+	}
+	return number, nil
+}
+
+//
+//			Helper functions
+//
+
+func upper(args []Value) (Value, error) {
+	return strings.ToUpper(FormatValue(args[0])), nil
+}
+
+func lower(args []Value) (Value, error) {
+	return strings.ToLower(FormatValue(args[0])), nil
+}
+
+func length(args []Value) (Value, error) {
+	return int64(utf8.RuneCountInString(FormatValue(args[0]))), nil
+}
+
+// substr is SUBSTR(text, start [, length]), counting characters from 1,
+// or back from the end when start is negative
+func substr(args []Value) (Value, error) {
+	runes := []rune(FormatValue(args[0]))
+
+	start, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	begin := int(start) - 1
+	if start < 0 {
+		begin = len(runes) + int(start)
+	}
+
+	end := len(runes)
+	if len(args) > 2 {
+		count, err := toInt(args[2])
+		if err != nil {
+			return nil, err
+		}
+		end = begin + int(count)
+	}
+
+	if begin < 0 {
+		begin = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+	if end <= begin {
+		return "", nil
+	}
+
+	return string(runes[begin:end]), nil
+}
+
+// trim makes TRIM, LTRIM or RTRIM, which take off spaces or whichever characters they're given
+func trim(cut func(string, string) string) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		characters := " "
+		if len(args) > 1 {
+			characters = FormatValue(args[1])
+		}
+		return cut(FormatValue(args[0]), characters), nil
+	}
+}
+
+func replace(args []Value) (Value, error) {
+	text, from := FormatValue(args[0]), FormatValue(args[1])
+	if from == "" {
+		return text, nil
+	}
+	return strings.Replace(text, from, FormatValue(args[2]), -1), nil
+}
+
+func abs(args []Value) (Value, error) {
+	number, ok := toNumber(args[0])
+	if ok == false {
+		return nil, notANumber(args[0])
+	}
+
+	if i, isInt := number.(int64); isInt {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+	return math.Abs(toFloat(number)), nil
+}
+
+// round is ROUND(x [, digits]), rounding halves away from zero. A whole number rounded to
+// no fewer than zero digits stays whole
+func round(args []Value) (Value, error) {
+	number, ok := toNumber(args[0])
+	if ok == false {
+		return nil, notANumber(args[0])
+	}
+
+	digits := int64(0)
+	if len(args) > 1 {
+		var err error
+		digits, err = toInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if i, isInt := number.(int64); isInt && digits >= 0 {
+		return i, nil
+	}
+
+	scale := math.Pow(10, float64(digits))
+	return math.Round(toFloat(number)*scale) / scale, nil
+}
+
+// wholeNumber makes FLOOR or CEIL, which give a whole number
+func wholeNumber(toWhole func(float64) float64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		number, ok := toNumber(args[0])
+		if ok == false {
+			return nil, notANumber(args[0])
+		}
+
+		if i, isInt := number.(int64); isInt {
+			return i, nil
+		}
+		return int64(toWhole(toFloat(number))), nil
+	}
+}
+
+func mod(args []Value) (Value, error) {
+	return Arithmetic("%", args[0], args[1])
+}
+
+func coalesce(args []Value) (Value, error) {
+	for _, value := range args {
+		if value != nil {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
+
+func nullIf(args []Value) (Value, error) {
+	if args[0] != nil && args[1] != nil && compareValues(args[0], args[1], "") == 0 {
+		return nil, nil
+	}
+	return args[0], nil
+}
+
+func textResult(types []string) string {
+	return textType
+}
+
+func intResult(types []string) string {
+	return intType
+}
+
+func firstType(types []string) string {
+	if types[0] == "" {
+		return textType
+	}
+	return types[0]
+}
+
+func numericResult(types []string) string {
+	return numericType(types[0], types[len(types)-1])
+}
+
+func timestampResult(types []string) string {
+	return timestampType
+}
+
+// dateTimeResult is the type of the date or time the first argument is, or a TIMESTAMP if it's text
+func dateTimeResult(types []string) string {
+	if family := typeFamily(types[0]); isDateTimeType(family) {
+		return family
+	}
+	return timestampType
+}
+
+func roundResult(types []string) string {
+	if len(types) == 1 && typeFamily(types[0]) == intType {
+		return intType
+	}
+	return floatType
+}
+
+// commonType is the type that values of some types can all be read as: int if they're all ints,
+// float if they're all numbers, the same type if they're all the same, and text otherwise.
+// A NULL, with no type, fits any of them
+func commonType(types []string) string {
+	common := ""
+
+	for _, typeName := range types {
+		family := typeFamily(typeName)
+
+		switch {
+		case typeName == "", family == common:
+		case common == "":
+			common = family
+		case (common == intType || common == floatType) && (family == intType || family == floatType):
+			common = floatType
+		default:
+			common = textType
+		}
+	}
+
+	if common == "" {
+		return textType
+	}
+	return common
+}
+
+func isNullLiteral(expr parser.Expr) bool {
+	literal, ok := expr.(*parser.Literal)
+	return ok && literal.Kind == "NULL"
+}
+
+// toInt reads a value as a whole number, a float is truncated
+func toInt(value Value) (int64, error) {
+	number, ok := toNumber(value)
+	if ok == false {
+		return 0, notANumber(value)
+	}
+
+	if i, isInt := number.(int64); isInt {
+		return i, nil
+	}
+	return int64(toFloat(number)), nil
+}
+
+func describeArgCount(function scalarFunction) string {
+	switch {
+	case function.maxArgs == -1:
+		return "at least " + strconv.Itoa(function.minArgs) + " argument(s)"
+	case function.minArgs == function.maxArgs:
+		return strconv.Itoa(function.minArgs) + " argument(s)"
+	}
+	return strconv.Itoa(function.minArgs) + " to " + strconv.Itoa(function.maxArgs) + " arguments"
+}
@@ -0,0 +1,312 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strconv"
+)
+
+// IndexScan reads the records of a table whose indexed column compares to a constant,
+// by binary searching the index for the first matching entry and following entries
+// from there until they stop matching. Each entry points at its record in the table
+type IndexScan struct {
+	session  *diskio.Session
+	index    diskio.IndexDef
+	alias    string
+	column   diskio.ColumnDef
+	operator string
+	keyExpr  parser.Expr
+	key      Value
+	columns  []diskio.ColumnDef
+
+	// Rows is how many records are expected to match, negative until the planner says
+	Rows float64
+
+	entries  *diskio.IndexReader
+	reader   *diskio.TableReader
+	position int64
+}
+
+// NewIndexScan creates a scan of the records where column operator key holds, the operator is
+// one of = < <= > >= and the key is a constant. Columns are qualified like a Scan's
+func NewIndexScan(session *diskio.Session, index diskio.IndexDef, alias string, operator string, key parser.Expr) (*IndexScan, error) {
+	scan, err := NewScan(session, index.Table, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluate, _, err := compile(key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := evaluate(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &IndexScan{session: session, index: index, alias: alias, operator: operator, keyExpr: key, key: value, columns: scan.columns, Rows: -1}
+
+	found := false
+	for _, column := range s.columns {
+		if column.ColumnName == index.Column {
+			s.column = column
+			found = true
+		}
+	}
+
+	if found == false {
+		return nil, errors.New("!Failed to query because column " + index.Column + " of index " + index.Name + " does not exist.")
+	}
+
+	return s, nil
+}
+
+// Open rebuilds the index if its table has changed since it was built, and searches it for the first match
+func (s *IndexScan) Open() error {
+	s.Close()
+
+	entries, err := openFreshIndex(s.session, s.index)
+	if err != nil {
+		return err
+	}
+	s.entries = entries
+
+	s.reader, err = diskio.OpenTable(s.session, s.index.Table)
+	if err != nil {
+		return err
+	}
+
+	s.position, err = s.entries.Search(func(field string) bool {
+		key := ReadValue(field, s.column.TypeName)
+
+		switch s.operator {
+		case "<", "<=":
+			return key == nil
+		case ">":
+			return compareNullable(key, s.key, s.column.Collation) <= 0
+		}
+		return compareNullable(key, s.key, s.column.Collation) < 0
+	})
+
+	return err
+}
+
+// Next ...
+func (s *IndexScan) Next() ([]string, error) {
+	// nothing compares to NULL
+	if s.key == nil {
+		return nil, io.EOF
+	}
+
+	field, offset, next, err := s.entries.Entry(s.position)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ReadValue(field, s.column.TypeName)
+	comparison := compareNullable(key, s.key, s.column.Collation)
+
+	switch s.operator {
+	case "=":
+		if comparison != 0 {
+			return nil, io.EOF
+		}
+	case "<":
+		if comparison >= 0 {
+			return nil, io.EOF
+		}
+	case "<=":
+		if comparison > 0 {
+			return nil, io.EOF
+		}
+	}
+
+	s.position = next
+
+	err = s.reader.SeekRecord(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reader.Next()
+}
+
+// Close ...
+func (s *IndexScan) Close() error {
+	if s.entries != nil {
+		s.entries.Close()
+		s.entries = nil
+	}
+
+	if s.reader == nil {
+		return nil
+	}
+
+	err := s.reader.Close()
+	s.reader = nil
+	return err
+}
+
+// Columns ...
+func (s *IndexScan) Columns() []diskio.ColumnDef {
+	return s.columns
+}
+
+// Explain ...
+func (s *IndexScan) Explain() string {
+	explanation := "Index Scan " + s.index.Table
+	if s.alias != "" {
+		explanation += " AS " + s.alias
+	}
+	return explanation + " USING " + s.index.Name + " (" + s.index.Column + " " + s.operator + " " + s.keyExpr.String() + ")"
+}
+
+// Children ...
+func (s *IndexScan) Children() []Operator {
+	return nil
+}
+
+// EstimatedRows guesses from the table's size when the planner hasn't worked it out
+func (s *IndexScan) EstimatedRows() float64 {
+	if s.Rows < 0 {
+		count, _ := diskio.CountRecords(s.session, s.index.Table)
+		s.Rows = float64(count) * Selectivity(&parser.BinaryExpr{Operator: s.operator})
+	}
+	return s.Rows
+}
+
+// BuildIndex writes out an index's file from its table, sorting the entries with a Sort
+// so that building an index of a big table spills to disk rather than filling memory
+func BuildIndex(session *diskio.Session, index diskio.IndexDef) error {
+	version, err := diskio.TableVersion(session, index.Table)
+	if err != nil {
+		return err
+	}
+
+	source, err := newEntryScan(session, index)
+	if err != nil {
+		return err
+	}
+
+	sorted, err := NewSort(source, []parser.OrderingTerm{{Expr: &parser.ColumnRef{Column: index.Column}}})
+	if err != nil {
+		return err
+	}
+
+	err = sorted.Open()
+	if err != nil {
+		sorted.Close()
+		return err
+	}
+	defer sorted.Close()
+
+	writer, err := diskio.CreateIndexFile(session, index, version)
+	if err != nil {
+		return err
+	}
+
+	err = drain(sorted, func(row []string) error {
+		offset, _ := strconv.ParseInt(row[1], 10, 64)
+		return writer.Write(row[0], offset)
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// entryScan reads a table as index entries, each record's field in the indexed column and its offset
+type entryScan struct {
+	session *diskio.Session
+	index   diskio.IndexDef
+	field   int
+	columns []diskio.ColumnDef
+	reader  *diskio.TableReader
+}
+
+func newEntryScan(session *diskio.Session, index diskio.IndexDef) (*entryScan, error) {
+	columns, err := diskio.ReadColumnDefs(session, index.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, column := range columns {
+		if column.ColumnName == index.Column {
+			return &entryScan{session: session, index: index, field: i, columns: []diskio.ColumnDef{column, {ColumnName: "offset", TypeName: intType}}}, nil
+		}
+	}
+
+	return nil, errors.New("!Failed to build index " + index.Name + " because column " + index.Column + " does not exist.")
+}
+
+func (e *entryScan) Open() error {
+	reader, err := diskio.OpenTable(e.session, e.index.Table)
+	e.reader = reader
+	return err
+}
+
+func (e *entryScan) Next() ([]string, error) {
+	record, err := e.reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	return []string{record[e.field], strconv.FormatInt(e.reader.Offset(), 10)}, nil
+}
+
+func (e *entryScan) Close() error {
+	if e.reader == nil {
+		return nil
+	}
+	err := e.reader.Close()
+	e.reader = nil
+	return err
+}
+
+func (e *entryScan) Columns() []diskio.ColumnDef {
+	return e.columns
+}
+
+func (e *entryScan) Explain() string {
+	return "Index Entries " + e.index.Name
+}
+
+func (e *entryScan) Children() []Operator {
+	return nil
+}
+
+func (e *entryScan) EstimatedRows() float64 {
+	return 0
+}
+
+//
+//			Helper functions
+//
+
+// openFreshIndex opens an index, rebuilding it first if it's missing or stale
+func openFreshIndex(session *diskio.Session, index diskio.IndexDef) (*diskio.IndexReader, error) {
+	version, err := diskio.TableVersion(session, index.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := diskio.OpenIndex(session, index)
+	if err == nil && entries.Version == version {
+		return entries, nil
+	}
+	if err == nil {
+		entries.Close()
+	}
+
+	err = BuildIndex(session, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return diskio.OpenIndex(session, index)
+}
@@ -0,0 +1,171 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"io"
+	"sqlit/diskio"
+	"sqlit/parser"
+)
+
+// NestedLoopJoin joins every row of its left child with every row of its right child
+// that satisfies the ON predicate. The right child is re-opened for each left row rather
+// than held in memory. An outer join pads out left rows that match nothing with NULLs
+type NestedLoopJoin struct {
+	left    Operator
+	right   Operator
+	on      evaluator
+	onExpr  parser.Expr
+	outer   bool
+	columns []diskio.ColumnDef
+
+	// Selectivity is the fraction of pairs of rows expected to satisfy ON
+	Selectivity float64
+
+	// the left row being joined, and whether anything on the right has matched it
+	current []string
+	matched bool
+}
+
+// NewNestedLoopJoin creates a join, a nil ON predicate joins every pair of rows
+func NewNestedLoopJoin(left Operator, right Operator, on parser.Expr, outer bool) (*NestedLoopJoin, error) {
+	join := &NestedLoopJoin{left: left, right: right, onExpr: on, outer: outer, Selectivity: 1}
+
+	join.columns = append(join.columns, left.Columns()...)
+	join.columns = append(join.columns, right.Columns()...)
+
+	if on != nil {
+		compiled, _, err := compile(on, join.columns)
+		if err != nil {
+			return nil, err
+		}
+		join.on = compiled
+		join.Selectivity = Selectivity(on)
+	}
+
+	return join, nil
+}
+
+// Open ...
+func (j *NestedLoopJoin) Open() error {
+	j.current = nil
+	return j.left.Open()
+}
+
+// Next ...
+func (j *NestedLoopJoin) Next() ([]string, error) {
+	for {
+		// move on to the next left row, restarting the right side
+		if j.current == nil {
+			row, err := j.left.Next()
+			if err != nil {
+				return nil, err
+			}
+
+			err = j.right.Open()
+			if err != nil {
+				return nil, err
+			}
+
+			j.current = row
+			j.matched = false
+		}
+
+		row, err := j.right.Next()
+
+		if err == io.EOF {
+			j.right.Close()
+
+			left := j.current
+			j.current = nil
+
+			if j.outer && j.matched == false {
+				return j.combine(left, nil), nil
+			}
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		combined := j.combine(j.current, row)
+
+		if j.on != nil {
+			matches, err := evaluatePredicate(j.on, combined)
+			if err != nil {
+				return nil, err
+			}
+			if matches == false {
+				continue
+			}
+		}
+
+		j.matched = true
+		return combined, nil
+	}
+}
+
+// Close ...
+func (j *NestedLoopJoin) Close() error {
+	j.right.Close()
+	return j.left.Close()
+}
+
+// Columns ...
+func (j *NestedLoopJoin) Columns() []diskio.ColumnDef {
+	return j.columns
+}
+
+// On is the join's predicate, nil for a cross join
+func (j *NestedLoopJoin) On() parser.Expr {
+	return j.onExpr
+}
+
+// Outer is whether it's a left join
+func (j *NestedLoopJoin) Outer() bool {
+	return j.outer
+}
+
+// Explain ...
+func (j *NestedLoopJoin) Explain() string {
+	switch {
+	case j.outer:
+		return "Nested Loop Left Join ON " + j.onExpr.String()
+	case j.onExpr != nil:
+		return "Nested Loop Join ON " + j.onExpr.String()
+	}
+	return "Nested Loop Cross Join"
+}
+
+// Children ...
+func (j *NestedLoopJoin) Children() []Operator {
+	return []Operator{j.left, j.right}
+}
+
+// EstimatedRows ...
+func (j *NestedLoopJoin) EstimatedRows() float64 {
+	left := j.left.EstimatedRows()
+	rows := left * j.right.EstimatedRows() * j.Selectivity
+
+	// every left row comes out of a left join at least once
+	if j.outer && rows < left {
+		return left
+	}
+	return rows
+}
+
+// combine concatenates a left and right row, a nil right row is all NULLs
+func (j *NestedLoopJoin) combine(left []string, right []string) []string {
+	row := make([]string, 0, len(j.columns))
+	row = append(row, left...)
+
+	if right == nil {
+		for range j.right.Columns() {
+			row = append(row, diskio.Null)
+		}
+		return row
+	}
+
+	return append(row, right...)
+}
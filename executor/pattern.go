@@ -0,0 +1,202 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"regexp"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strings"
+	"unicode/utf8"
+)
+
+// compilePattern compiles x LIKE, ILIKE, GLOB or REGEXP pattern. Every pattern is translated
+// to a regular expression, which is only compiled again when the pattern changes from row to row.
+// LIKE follows the collation its operands are compared with, so it ignores case unless that's BINARY or RTRIM,
+// and RTRIM also ignores spaces at the end of the text. ILIKE always ignores case, GLOB and REGEXP never do.
+// LIKE, ILIKE and GLOB match the whole text, REGEXP matches anywhere in it
+func compilePattern(e *parser.PatternExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	operand, _, err := compile(e.Operand, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pattern, _, err := compile(e.Pattern, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	escape := evaluator(func(row []string) (Value, error) { return "", nil })
+	if e.Escape != nil {
+		escape, _, err = compile(e.Escape, columns)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	collation := ""
+	if e.Operator == "LIKE" {
+		collation = comparisonCollation(e.Operand, e.Pattern, columns)
+	}
+	foldCase := collation != binaryCollation && collation != rtrimCollation
+
+	var compiled *regexp.Regexp
+	var compiledFrom string
+
+	return func(row []string) (Value, error) {
+		value, err := operand(row)
+		if err != nil || value == nil {
+			return nil, err
+		}
+
+		p, err := pattern(row)
+		if err != nil || p == nil {
+			return nil, err
+		}
+
+		escapeValue, err := escape(row)
+		if err != nil || escapeValue == nil {
+			return nil, err
+		}
+
+		expression, err := patternExpression(e.Operator, FormatValue(p), FormatValue(escapeValue), foldCase)
+		if err != nil {
+			return nil, err
+		}
+
+		if compiled == nil || expression != compiledFrom {
+			compiled, err = regexp.Compile(expression)
+			if err != nil {
+				return nil, errors.New("!Failed to query because '" + FormatValue(p) + "' is not a valid regular expression.")
+			}
+			compiledFrom = expression
+		}
+
+		text := FormatValue(value)
+		if collation == rtrimCollation {
+			text = strings.TrimRight(text, " ")
+		}
+
+		return boolValue(compiled.MatchString(text) != e.Not), nil
+	}, intType, nil
+}
+
+//
+//			Helper functions
+//
+
+// patternExpression translates a pattern into the regular expression it means, foldCase is whether LIKE ignores case
+func patternExpression(operator string, pattern string, escape string, foldCase bool) (string, error) {
+	switch operator {
+	case "LIKE":
+		return likeExpression(pattern, escape, foldCase)
+	case "ILIKE":
+		return likeExpression(pattern, escape, true)
+	case "GLOB":
+		return globExpression(pattern), nil
+	}
+	return pattern, nil
+}
+
+// likeExpression translates a LIKE pattern, where % is any run of characters and _ is
+// any one character. The escape character, if there is one, makes the next character literal
+func likeExpression(pattern string, escape string, foldCase bool) (string, error) {
+	var escapeRune rune = -1
+	if escape != "" {
+		if utf8.RuneCountInString(escape) != 1 {
+			return "", errors.New("!Failed to query because the ESCAPE of LIKE has to be a single character.")
+		}
+		escapeRune, _ = utf8.DecodeRuneInString(escape)
+	}
+
+	var out strings.Builder
+	if foldCase {
+		out.WriteString("(?is)^")
+	} else {
+		out.WriteString("(?s)^")
+	}
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == escapeRune:
+			if i == len(runes)-1 {
+				return "", errors.New("!Failed to query because the LIKE pattern '" + pattern + "' ends with its ESCAPE character.")
+			}
+			i++
+			out.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '%':
+			out.WriteString(".*")
+		case r == '_':
+			out.WriteString(".")
+		default:
+			out.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	out.WriteString("$")
+	return out.String(), nil
+}
+
+// globExpression translates a GLOB pattern, where * is any run of characters, ? is any one
+// character, and [...] is any one of a set of characters, or any character not in it with [^...] or [!...]
+func globExpression(pattern string) string {
+	var out strings.Builder
+	out.WriteString("(?s)^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			out.WriteString(".*")
+		case '?':
+			out.WriteString(".")
+		case '[':
+			end := globSetEnd(runes, i)
+			if end < 0 {
+				out.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+
+			set := runes[i+1 : end]
+			out.WriteString("[")
+			if set[0] == '^' || set[0] == '!' {
+				out.WriteString("^")
+				set = set[1:]
+			}
+			for _, c := range set {
+				if c == '\\' || c == '[' || c == ']' || c == '^' {
+					out.WriteString("\\")
+				}
+				out.WriteRune(c)
+			}
+			out.WriteString("]")
+			i = end
+		default:
+			out.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	out.WriteString("$")
+	return out.String()
+}
+
+// globSetEnd finds the ] closing a set that starts at a [, -1 if it isn't closed.
+// A ] right after the [ (or after its ^ or !) is part of the set
+func globSetEnd(runes []rune, start int) int {
+	i := start + 1
+	if i < len(runes) && (runes[i] == '^' || runes[i] == '!') {
+		i++
+	}
+	if i < len(runes) && runes[i] == ']' {
+		i++
+	}
+
+	for ; i < len(runes); i++ {
+		if runes[i] == ']' {
+			return i
+		}
+	}
+	return -1
+}
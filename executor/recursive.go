@@ -0,0 +1,224 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"strconv"
+)
+
+// RecursionLimit is how many times a recursive query's step can run before it's taken to never finish
+var RecursionLimit = 10000
+
+// Recursive evaluates WITH RECURSIVE to a fixpoint. The anchor's rows are the first working set,
+// then the step runs over the rows of the last working set, through its WorkingTables, and
+// whatever it returns is the next one. It's done once a step comes up with nothing new.
+// Without ALL a row that's already been returned isn't new, and is dropped
+type Recursive struct {
+	name    string
+	anchor  Operator
+	step    Operator
+	all     bool
+	columns []diskio.ColumnDef
+
+	// the rows the step is reading, and the ones it's returned so far
+	working *diskio.Set
+	next    [][]string
+
+	seen   map[string]bool
+	source Operator
+	steps  int
+}
+
+// NewRecursive creates a recursive query from its anchor, the step is set once it's been
+// planned with WorkingTables to read. Its columns can be renamed by names
+func NewRecursive(name string, anchor Operator, all bool, names []string) (*Recursive, error) {
+	if len(names) > 0 && len(names) != len(anchor.Columns()) {
+		return nil, errors.New("!Failed to query because " + name + " names " + strconv.Itoa(len(names)) + " columns, but selects " +
+			strconv.Itoa(len(anchor.Columns())) + ".")
+	}
+
+	r := &Recursive{name: name, anchor: anchor, all: all}
+
+	for i, column := range anchor.Columns() {
+		column.Table = name
+		if len(names) > 0 {
+			column.ColumnName = names[i]
+		}
+		r.columns = append(r.columns, column)
+	}
+
+	r.working = &diskio.Set{Name: name, ColumnDefs: r.columns}
+	return r, nil
+}
+
+// SetStep checks the step's columns line up with the anchor's, like a UNION
+func (r *Recursive) SetStep(step Operator) error {
+	operator := "UNION"
+	if r.all {
+		operator += " ALL"
+	}
+
+	_, err := NewSetOperation(r.anchor, step, operator, r.all)
+	if err != nil {
+		return err
+	}
+
+	r.step = step
+	return nil
+}
+
+// WorkingTable makes a reader of the working set, for the step to read the query's own rows under an alias
+func (r *Recursive) WorkingTable(alias string) *WorkingTable {
+	w := &WorkingTable{set: r.working, alias: alias}
+
+	for _, column := range r.columns {
+		column.Table = alias
+		w.columns = append(w.columns, column)
+	}
+
+	return w
+}
+
+// Open ...
+func (r *Recursive) Open() error {
+	r.Close()
+
+	r.seen = map[string]bool{}
+	r.steps = 0
+	r.source = r.anchor
+	return r.anchor.Open()
+}
+
+// Next ...
+func (r *Recursive) Next() ([]string, error) {
+	for {
+		row, err := r.source.Next()
+		if err == io.EOF {
+			r.source.Close()
+			r.source = nil
+
+			if len(r.next) == 0 {
+				return nil, io.EOF
+			}
+
+			r.steps++
+			if r.steps > RecursionLimit {
+				return nil, errors.New("!Failed to query because " + r.name + " was still recursing after " + strconv.Itoa(RecursionLimit) + " steps.")
+			}
+
+			// the rows from the last round become what the step reads
+			r.working.Records = r.next
+			r.next = nil
+
+			r.source = r.step
+			err = r.step.Open()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if r.all == false {
+			key := distinctKey(row, r.columns)
+			if r.seen[key] {
+				continue
+			}
+			r.seen[key] = true
+		}
+
+		r.next = append(r.next, row)
+		return row, nil
+	}
+}
+
+// Close ...
+func (r *Recursive) Close() error {
+	if r.source != nil {
+		r.source.Close()
+		r.source = nil
+	}
+
+	r.working.Records = nil
+	r.next = nil
+	r.seen = nil
+	return nil
+}
+
+// Columns ...
+func (r *Recursive) Columns() []diskio.ColumnDef {
+	return r.columns
+}
+
+// Explain ...
+func (r *Recursive) Explain() string {
+	explanation := "Recursive Union"
+	if r.all {
+		explanation += " All"
+	}
+	return explanation + " AS " + r.name
+}
+
+// Children ...
+func (r *Recursive) Children() []Operator {
+	return []Operator{r.anchor, r.step}
+}
+
+// EstimatedRows guesses the step runs ten times
+func (r *Recursive) EstimatedRows() float64 {
+	return r.anchor.EstimatedRows() + 10*r.step.EstimatedRows()
+}
+
+// WorkingTable reads the rows a recursive query returned in its last round
+type WorkingTable struct {
+	set     *diskio.Set
+	alias   string
+	columns []diskio.ColumnDef
+	cursor  int
+}
+
+// Open ...
+func (w *WorkingTable) Open() error {
+	w.cursor = 0
+	return nil
+}
+
+// Next ...
+func (w *WorkingTable) Next() ([]string, error) {
+	if w.cursor >= len(w.set.Records) {
+		return nil, io.EOF
+	}
+
+	w.cursor++
+	return w.set.Records[w.cursor-1], nil
+}
+
+// Close ...
+func (w *WorkingTable) Close() error {
+	return nil
+}
+
+// Columns ...
+func (w *WorkingTable) Columns() []diskio.ColumnDef {
+	return w.columns
+}
+
+// Explain ...
+func (w *WorkingTable) Explain() string {
+	return "Working Table " + w.set.Name + " AS " + w.alias
+}
+
+// Children ...
+func (w *WorkingTable) Children() []Operator {
+	return nil
+}
+
+// EstimatedRows ...
+func (w *WorkingTable) EstimatedRows() float64 {
+	return 10
+}
@@ -0,0 +1,266 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strconv"
+	"strings"
+)
+
+// SetOperation combines the rows of two queries with UNION, INTERSECT or EXCEPT. UNION ALL
+// reads one query and then the other, everything else needs both children sorted on every
+// column, see NewRowSort, and merges them the way a sort merges its runs. Equal rows then
+// come together, which is how duplicates are dropped, or for ALL, matched up one for one
+type SetOperation struct {
+	left     Operator
+	right    Operator
+	operator string
+	all      bool
+	columns  []diskio.ColumnDef
+
+	// the current row of each child and its values, nil once it's run out
+	leftRow  []string
+	leftKey  []Value
+	rightRow []string
+	rightKey []Value
+
+	// the last row returned, so a duplicate of it can be skipped
+	last []Value
+}
+
+// NewSetOperation checks that both queries have the same number of columns, and that each
+// column can be compared to the one it lines up with. Columns are named after the left query's
+func NewSetOperation(left Operator, right Operator, operator string, all bool) (*SetOperation, error) {
+	name := operator
+	if all {
+		name += " ALL"
+	}
+
+	if len(left.Columns()) != len(right.Columns()) {
+		return nil, errors.New("!Failed to query because the queries of " + name + " select " + strconv.Itoa(len(left.Columns())) +
+			" and " + strconv.Itoa(len(right.Columns())) + " columns.")
+	}
+
+	s := &SetOperation{left: left, right: right, operator: operator, all: all}
+
+	for i, column := range left.Columns() {
+		other := right.Columns()[i]
+
+		// columns combine when they're of the same family, or are both numbers. An int combined with a float is a float.
+		// Dates, times and timestamps only combine with their own kind, as none of them is read as another when compared
+		leftFamily, rightFamily := typeFamily(column.TypeName), typeFamily(other.TypeName)
+		if leftFamily != rightFamily {
+			if isNumericFamily(leftFamily) == false || isNumericFamily(rightFamily) == false {
+				return nil, errors.New("!Failed to query because column " + column.ColumnName + " (" + column.TypeName + ") of " + name +
+					" can't be combined with " + other.ColumnName + " (" + other.TypeName + ").")
+			}
+			column.TypeName = floatType
+		}
+		column.Collation = SetCollations(left.Columns(), right.Columns())[i]
+		s.columns = append(s.columns, column)
+	}
+
+	return s, nil
+}
+
+// NewRowSort sorts rows on every column in turn, which puts equal rows next to each other.
+// The text of each column is compared under a collation, see SetCollations
+func NewRowSort(child Operator, collations []string) (*Sort, error) {
+	var terms []parser.OrderingTerm
+	for i, column := range child.Columns() {
+		if i < len(collations) {
+			column.Collation = collations[i]
+		}
+		terms = append(terms, parser.OrderingTerm{Expr: &ColumnAt{Index: i, Column: column}})
+	}
+
+	return NewSort(child, terms)
+}
+
+// SetCollations is the collation each column of a set operation is compared with,
+// the left query's column's unless only the right's has one
+func SetCollations(left []diskio.ColumnDef, right []diskio.ColumnDef) []string {
+	collations := make([]string, len(left))
+	for i := range left {
+		collations[i] = left[i].Collation
+		if collations[i] == "" && i < len(right) {
+			collations[i] = right[i].Collation
+		}
+	}
+	return collations
+}
+
+// Open ...
+func (s *SetOperation) Open() error {
+	s.last = nil
+
+	err := s.left.Open()
+	if err != nil {
+		return err
+	}
+
+	err = s.right.Open()
+	if err != nil {
+		return err
+	}
+
+	if s.operator == "UNION" && s.all {
+		return nil
+	}
+
+	err = s.advanceLeft()
+	if err != nil {
+		return err
+	}
+	return s.advanceRight()
+}
+
+// Next ...
+func (s *SetOperation) Next() ([]string, error) {
+	if s.operator == "UNION" && s.all {
+		row, err := s.left.Next()
+		if err != io.EOF {
+			return row, err
+		}
+		return s.right.Next()
+	}
+
+	for {
+		// only a UNION has anything to return once the left query runs out
+		if s.leftRow == nil && (s.operator != "UNION" || s.rightRow == nil) {
+			return nil, io.EOF
+		}
+
+		comparison := 1
+		switch {
+		case s.leftRow == nil:
+		case s.rightRow == nil:
+			comparison = -1
+		default:
+			comparison = compareKeys(s.leftKey, s.rightKey, s.columns)
+		}
+
+		var row []string
+		var key []Value
+		emit := false
+		var err error
+
+		switch {
+		case comparison < 0:
+			row, key, emit = s.leftRow, s.leftKey, s.operator != "INTERSECT"
+			err = s.advanceLeft()
+
+		case comparison > 0:
+			row, key, emit = s.rightRow, s.rightKey, s.operator == "UNION"
+			err = s.advanceRight()
+
+		// a row that's in both: a UNION returns the left one and lets the right one be skipped
+		// as its duplicate, INTERSECT ALL and EXCEPT ALL match it with the right one
+		default:
+			row, key, emit = s.leftRow, s.leftKey, s.operator != "EXCEPT"
+			err = s.advanceLeft()
+			if err == nil && s.operator != "UNION" && (s.all || s.operator == "INTERSECT") {
+				err = s.advanceRight()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if emit == false || (s.all == false && s.last != nil && compareKeys(key, s.last, s.columns) == 0) {
+			continue
+		}
+
+		s.last = key
+		return row, nil
+	}
+}
+
+// Close ...
+func (s *SetOperation) Close() error {
+	s.left.Close()
+	s.right.Close()
+
+	s.leftRow, s.rightRow = nil, nil
+	return nil
+}
+
+// Columns ...
+func (s *SetOperation) Columns() []diskio.ColumnDef {
+	return s.columns
+}
+
+// Explain ...
+func (s *SetOperation) Explain() string {
+	explanation := strings.Title(strings.ToLower(s.operator))
+	if s.all {
+		explanation += " All"
+	}
+	return explanation
+}
+
+// Children ...
+func (s *SetOperation) Children() []Operator {
+	return []Operator{s.left, s.right}
+}
+
+// EstimatedRows ...
+func (s *SetOperation) EstimatedRows() float64 {
+	switch s.operator {
+	case "INTERSECT":
+		return math.Min(s.left.EstimatedRows(), s.right.EstimatedRows())
+	case "EXCEPT":
+		return s.left.EstimatedRows()
+	}
+	return s.left.EstimatedRows() + s.right.EstimatedRows()
+}
+
+func (s *SetOperation) advanceLeft() error {
+	var err error
+	s.leftRow, s.leftKey, err = readRow(s.left, s.columns)
+	return err
+}
+
+func (s *SetOperation) advanceRight() error {
+	var err error
+	s.rightRow, s.rightKey, err = readRow(s.right, s.right.Columns())
+	return err
+}
+
+//
+//			Helper functions
+//
+
+// readRow reads an operator's next row along with its values, a nil row once it's run out
+func readRow(operator Operator, columns []diskio.ColumnDef) ([]string, []Value, error) {
+	row, err := operator.Next()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := make([]Value, len(columns))
+	for i, column := range columns {
+		key[i] = ReadValue(row[i], column.TypeName)
+	}
+
+	return row, key, nil
+}
+
+// compareKeys orders two rows' values the way a sort on each of them in turn would
+func compareKeys(a []Value, b []Value, columns []diskio.ColumnDef) int {
+	for i := range a {
+		comparison := compareNullable(a[i], b[i], columns[i].Collation)
+		if comparison != 0 {
+			return comparison
+		}
+	}
+	return 0
+}
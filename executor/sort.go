@@ -0,0 +1,333 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strconv"
+	"strings"
+)
+
+// SortBufferRows is how many rows a sort holds in memory before it spills them to disk as a sorted run
+var SortBufferRows = 10000
+
+// Sort orders the rows of its child. Inputs that fit in SortBufferRows are sorted in memory,
+// bigger ones are cut into sorted runs on disk which are merged back together as rows are read
+type Sort struct {
+	child      Operator
+	keys       []evaluator
+	terms      []parser.OrderingTerm
+	descending []bool
+	collations []string
+
+	// how many runs the last sort spilled, for EXPLAIN ANALYZE
+	spilled int
+
+	// rows sorted in memory, or the runs being merged
+	rows   []sortRow
+	cursor int
+	runs   []*sortRun
+	merge  *runHeap
+}
+
+// a sortRow carries its evaluated keys, so they're only computed once
+type sortRow struct {
+	row []string
+	key []Value
+}
+
+// NewSort ...
+func NewSort(child Operator, terms []parser.OrderingTerm) (*Sort, error) {
+	s := &Sort{child: child, terms: terms}
+
+	for _, term := range terms {
+		compiled, _, err := compile(term.Expr, child.Columns())
+		if err != nil {
+			return nil, err
+		}
+		s.keys = append(s.keys, compiled)
+		s.descending = append(s.descending, term.Descending)
+		s.collations = append(s.collations, collationOf(term.Expr, child.Columns()))
+	}
+
+	return s, nil
+}
+
+// Open reads the whole of the child, which is closed again once it's been sorted
+func (s *Sort) Open() error {
+	s.Close()
+	s.cursor = 0
+	s.spilled = 0
+
+	err := s.child.Open()
+	if err != nil {
+		return err
+	}
+	defer s.child.Close()
+
+	err = drain(s.child, func(row []string) error {
+		sorted, err := s.keyRow(row)
+		if err != nil {
+			return err
+		}
+
+		s.rows = append(s.rows, sorted)
+
+		if len(s.rows) >= SortBufferRows {
+			return s.spill()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.sortRows()
+
+	// everything fit in memory
+	if len(s.runs) == 0 {
+		return nil
+	}
+
+	if len(s.rows) > 0 {
+		err = s.spill()
+		if err != nil {
+			return err
+		}
+	}
+
+	s.merge = &runHeap{sort: s}
+	for _, run := range s.runs {
+		err = run.rewind()
+		if err != nil {
+			return err
+		}
+
+		err = s.advance(run)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(s.merge, run)
+	}
+
+	return nil
+}
+
+// Next ...
+func (s *Sort) Next() ([]string, error) {
+	if s.merge == nil {
+		if s.cursor >= len(s.rows) {
+			return nil, io.EOF
+		}
+		row := s.rows[s.cursor].row
+		s.cursor++
+		return row, nil
+	}
+
+	if s.merge.Len() == 0 {
+		return nil, io.EOF
+	}
+
+	// take the least row from the run at the top of the heap, then refill that run
+	run := s.merge.runs[0]
+	row := run.current.row
+
+	err := s.advance(run)
+	if err == io.EOF {
+		heap.Pop(s.merge)
+	} else if err != nil {
+		return nil, err
+	} else {
+		heap.Fix(s.merge, 0)
+	}
+
+	return row, nil
+}
+
+// Close removes any runs that were spilled
+func (s *Sort) Close() error {
+	for _, run := range s.runs {
+		run.remove()
+	}
+
+	s.rows = nil
+	s.runs = nil
+	s.merge = nil
+	return nil
+}
+
+// Columns ...
+func (s *Sort) Columns() []diskio.ColumnDef {
+	return s.child.Columns()
+}
+
+// Explain ...
+func (s *Sort) Explain() string {
+	var keys []string
+	for _, term := range s.terms {
+		if term.Descending {
+			keys = append(keys, term.Expr.String()+" DESC")
+		} else {
+			keys = append(keys, term.Expr.String())
+		}
+	}
+
+	explanation := "Sort BY " + strings.Join(keys, ", ")
+	if s.spilled > 0 {
+		explanation += ", spilled " + strconv.Itoa(s.spilled) + " run(s) to disk"
+	}
+	return explanation
+}
+
+// Children ...
+func (s *Sort) Children() []Operator {
+	return []Operator{s.child}
+}
+
+// EstimatedRows ...
+func (s *Sort) EstimatedRows() float64 {
+	return s.child.EstimatedRows()
+}
+
+// spill writes the rows in memory to a new sorted run
+func (s *Sort) spill() error {
+	s.sortRows()
+
+	f, err := ioutil.TempFile("", "sqlit-sort-")
+	if err != nil {
+		return err
+	}
+
+	run := &sortRun{file: f, order: len(s.runs)}
+	s.runs = append(s.runs, run)
+	s.spilled++
+
+	encoder := gob.NewEncoder(f)
+	for _, sorted := range s.rows {
+		err = encoder.Encode(sorted.row)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.rows = nil
+	return nil
+}
+
+// advance reads a run's next row into its current row
+func (s *Sort) advance(run *sortRun) error {
+	var row []string
+
+	err := run.decoder.Decode(&row)
+	if err != nil {
+		return err
+	}
+
+	run.current, err = s.keyRow(row)
+	return err
+}
+
+func (s *Sort) sortRows() {
+	sort.SliceStable(s.rows, func(i, j int) bool {
+		return s.less(s.rows[i], s.rows[j])
+	})
+}
+
+func (s *Sort) keyRow(row []string) (sortRow, error) {
+	key := make([]Value, len(s.keys))
+
+	for i, evaluate := range s.keys {
+		value, err := evaluate(row)
+		if err != nil {
+			return sortRow{}, err
+		}
+		key[i] = value
+	}
+
+	return sortRow{row: row, key: key}, nil
+}
+
+// less orders rows by each key in turn, NULLs sort first and text compares under its key's collation
+func (s *Sort) less(a sortRow, b sortRow) bool {
+	for i := range s.keys {
+		comparison := compareNullable(a.key[i], b.key[i], s.collations[i])
+		if comparison == 0 {
+			continue
+		}
+
+		if s.descending[i] {
+			return comparison > 0
+		}
+		return comparison < 0
+	}
+
+	return false
+}
+
+//
+//			Sorted runs
+//
+
+// a sortRun is a temp file of gob encoded rows, in order
+type sortRun struct {
+	order   int
+	file    *os.File
+	decoder *gob.Decoder
+	current sortRow
+}
+
+func (run *sortRun) rewind() error {
+	_, err := run.file.Seek(0, io.SeekStart)
+	run.decoder = gob.NewDecoder(run.file)
+	return err
+}
+
+func (run *sortRun) remove() {
+	run.file.Close()
+	os.Remove(run.file.Name())
+}
+
+// runHeap keeps the run with the least current row on top,
+// ties go to the earlier run so the merge stays stable
+type runHeap struct {
+	sort *Sort
+	runs []*sortRun
+}
+
+func (h *runHeap) Len() int {
+	return len(h.runs)
+}
+
+func (h *runHeap) Less(i, j int) bool {
+	if h.sort.less(h.runs[i].current, h.runs[j].current) {
+		return true
+	}
+	if h.sort.less(h.runs[j].current, h.runs[i].current) {
+		return false
+	}
+	return h.runs[i].order < h.runs[j].order
+}
+
+func (h *runHeap) Swap(i, j int) {
+	h.runs[i], h.runs[j] = h.runs[j], h.runs[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	h.runs = append(h.runs, x.(*sortRun))
+}
+
+func (h *runHeap) Pop() interface{} {
+	run := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return run
+}
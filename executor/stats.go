@@ -0,0 +1,304 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"math"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strings"
+)
+
+// histogramBuckets is how many buckets ANALYZE divides each column's values into
+const histogramBuckets = 10
+
+// CollectStatistics reads through a table to count its rows and each column's NULLs,
+// then sorts it by each column in turn to count distinct values and build a histogram
+func CollectStatistics(session *diskio.Session, table string) (diskio.TableStats, error) {
+	stats := diskio.TableStats{Table: table}
+
+	scan, err := NewScan(session, table, "")
+	if err != nil {
+		return stats, err
+	}
+
+	for _, column := range scan.Columns() {
+		stats.Columns = append(stats.Columns, diskio.ColumnStats{Column: column.ColumnName})
+	}
+
+	err = scan.Open()
+	if err != nil {
+		return stats, err
+	}
+
+	err = drain(scan, func(row []string) error {
+		stats.Rows++
+		for i, field := range row {
+			if field == diskio.Null {
+				stats.Columns[i].Nulls++
+			}
+		}
+		return nil
+	})
+	scan.Close()
+	if err != nil {
+		return stats, err
+	}
+
+	for i, column := range scan.Columns() {
+		err = collectColumnStatistics(scan, i, column, stats.Rows, &stats.Columns[i])
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+// An Estimator guesses how selective predicates are from the stats of the tables they're
+// over. Anything about a table without stats falls back on Selectivity's guesses
+type Estimator struct {
+	columns []diskio.ColumnDef
+	stats   map[string]*diskio.TableStats
+}
+
+// NewEstimator ...
+func NewEstimator() *Estimator {
+	return &Estimator{stats: map[string]*diskio.TableStats{}}
+}
+
+// AddTable brings a table's qualified columns into scope, along with its stats if it has been analyzed
+func (e *Estimator) AddTable(columns []diskio.ColumnDef, stats *diskio.TableStats) {
+	e.columns = append(e.columns, columns...)
+	if len(columns) > 0 && stats != nil {
+		e.stats[strings.ToLower(columns[0].Table)] = stats
+	}
+}
+
+// Selectivity estimates the fraction of rows a predicate lets through
+func (e *Estimator) Selectivity(expr parser.Expr) float64 {
+	switch x := expr.(type) {
+	case *parser.BinaryExpr:
+		switch x.Operator {
+		case "AND":
+			return e.Selectivity(x.Left) * e.Selectivity(x.Right)
+		case "OR":
+			left, right := e.Selectivity(x.Left), e.Selectivity(x.Right)
+			return left + right - left*right
+		case "=", "!=", "<", ">", "<=", ">=":
+			if selectivity, ok := e.comparisonSelectivity(x); ok {
+				return selectivity
+			}
+		}
+
+	case *parser.UnaryExpr:
+		if x.Operator == "NOT" {
+			return 1 - e.Selectivity(x.Operand)
+		}
+
+	case *parser.IsNullExpr:
+		if table, column := e.columnStats(x.Operand); column != nil && table.Rows > 0 {
+			nulls := float64(column.Nulls) / float64(table.Rows)
+			if x.Not {
+				return 1 - nulls
+			}
+			return nulls
+		}
+	}
+
+	return Selectivity(expr)
+}
+
+// Distinct estimates how many distinct values an expression takes, when it's an analyzed column
+func (e *Estimator) Distinct(expr parser.Expr) (float64, bool) {
+	_, column := e.columnStats(expr)
+	if column == nil {
+		return 0, false
+	}
+
+	distinct := float64(column.Distinct)
+	if column.Nulls > 0 {
+		distinct++
+	}
+	return distinct, true
+}
+
+// comparisonSelectivity estimates a column compared to a constant, or two columns compared for equality
+func (e *Estimator) comparisonSelectivity(x *parser.BinaryExpr) (float64, bool) {
+	operator := x.Operator
+	left, right := x.Left, x.Right
+
+	if _, isConstant := constantValue(left); isConstant {
+		left, right = right, left
+		operator = FlipComparison(operator)
+	}
+
+	table, column := e.columnStats(left)
+	if column == nil || table.Rows == 0 {
+		return 0, false
+	}
+
+	nonNull := float64(table.Rows-column.Nulls) / float64(table.Rows)
+	distinct := math.Max(1, float64(column.Distinct))
+
+	value, isConstant := constantValue(right)
+	if isConstant == false {
+		_, other := e.columnStats(right)
+		if other == nil || operator != "=" {
+			return 0, false
+		}
+		return 1 / math.Max(distinct, math.Max(1, float64(other.Distinct))), true
+	}
+
+	if value == nil {
+		return 0, true
+	}
+
+	switch operator {
+	case "=":
+		return nonNull / distinct, true
+	case "!=":
+		return nonNull * (1 - 1/distinct), true
+	}
+
+	return nonNull * histogramFraction(column.Histogram, e.columnType(left), operator, value), true
+}
+
+// columnStats finds the stats of the column an expression is, if it is one and it's been analyzed
+func (e *Estimator) columnStats(expr parser.Expr) (*diskio.TableStats, *diskio.ColumnStats) {
+	ref, ok := expr.(*parser.ColumnRef)
+	if ok == false {
+		return nil, nil
+	}
+
+	index, err := ResolveColumn(ref, e.columns)
+	if err != nil {
+		return nil, nil
+	}
+
+	table := e.stats[strings.ToLower(e.columns[index].Table)]
+	if table == nil {
+		return nil, nil
+	}
+
+	return table, table.Column(e.columns[index].ColumnName)
+}
+
+func (e *Estimator) columnType(expr parser.Expr) string {
+	index, _ := ResolveColumn(expr.(*parser.ColumnRef), e.columns)
+	return e.columns[index].TypeName
+}
+
+// FlipComparison is the operator that compares the same way with its operands swapped
+func FlipComparison(operator string) string {
+	switch operator {
+	case "<":
+		return ">"
+	case ">":
+		return "<"
+	case "<=":
+		return ">="
+	case ">=":
+		return "<="
+	}
+	return operator
+}
+
+//
+//			Helper functions
+//
+
+// collectColumnStatistics sorts a table by one column, counting distinct values and
+// marking the last value of each histogram bucket as they go by
+func collectColumnStatistics(scan *Scan, field int, column diskio.ColumnDef, rows int, stats *diskio.ColumnStats) error {
+	sorted, err := NewSort(scan, []parser.OrderingTerm{{Expr: &parser.ColumnRef{Table: column.Table, Column: column.ColumnName}}})
+	if err != nil {
+		return err
+	}
+
+	err = sorted.Open()
+	if err != nil {
+		sorted.Close()
+		return err
+	}
+	defer sorted.Close()
+
+	nonNull := rows - stats.Nulls
+	seen := 0
+	var previous Value
+
+	return drain(sorted, func(row []string) error {
+		value := ReadValue(row[field], column.TypeName)
+		if value == nil {
+			return nil
+		}
+
+		if seen == 0 || compareValues(value, previous, column.Collation) != 0 {
+			stats.Distinct++
+		}
+
+		// the histogram starts at the smallest value, and a bucket ends wherever
+		// the running share of values crosses into the next tenth
+		if seen == 0 || (seen+1)*histogramBuckets/nonNull > seen*histogramBuckets/nonNull {
+			stats.Histogram = append(stats.Histogram, row[field])
+		}
+
+		seen++
+		previous = value
+		return nil
+	})
+}
+
+// histogramFraction estimates the fraction of a column's non-NULL values that compare to a
+// value, by counting the buckets that end below it and assuming half of the one it falls in.
+// Nothing is below the first bound, which is the smallest value, or above the last
+func histogramFraction(bounds []string, typeName string, operator string, value Value) float64 {
+	if len(bounds) == 0 {
+		return Selectivity(&parser.BinaryExpr{Operator: operator})
+	}
+
+	below, atOrBelow := 0, 0
+	for _, bound := range bounds {
+		comparison := compareValues(ReadValue(bound, typeName), value, "")
+		if comparison < 0 {
+			below++
+		}
+		if comparison <= 0 {
+			atOrBelow++
+		}
+	}
+
+	fraction := func(count int) float64 {
+		switch count {
+		case 0:
+			return 0
+		case len(bounds):
+			return 1
+		}
+		return (float64(count) - 0.5) / float64(len(bounds)-1)
+	}
+
+	switch operator {
+	case "<":
+		return fraction(below)
+	case "<=":
+		return fraction(atOrBelow)
+	case ">":
+		return 1 - fraction(atOrBelow)
+	}
+	return 1 - fraction(below)
+}
+
+// constantValue evaluates an expression that doesn't refer to any columns
+func constantValue(expr parser.Expr) (Value, bool) {
+	evaluate, _, err := compile(expr, nil)
+	if err != nil {
+		return nil, false
+	}
+
+	value, err := evaluate(nil)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
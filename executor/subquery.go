@@ -0,0 +1,319 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"strconv"
+)
+
+// Subquery is a planned subquery used as a value. Its rows are read into a diskio.Set
+// whenever it's evaluated, or just once if it isn't correlated with the query around it
+type Subquery struct {
+	expr    *parser.SubqueryExpr
+	plan    Operator
+	outer   *Outer
+	operand parser.Expr
+
+	// Correlated is whether it refers to a row of the query around it
+	Correlated bool
+
+	result *diskio.Set
+}
+
+// An Outer is the row of the query around a subquery, which the subquery's OuterRefs read
+type Outer struct {
+	columns []diskio.ColumnDef
+	row     []string
+}
+
+// OuterRef is a column of the query around a subquery, referred to from inside it
+type OuterRef struct {
+	Ref      *parser.ColumnRef
+	TypeName string
+	Outer    *Outer
+}
+
+// NewSubquery creates a subquery of a SubqueryExpr, whose OuterRefs read from outer.
+// The operand of an IN subquery is given already resolved, like the rest of the expression
+func NewSubquery(expr *parser.SubqueryExpr, operand parser.Expr, plan Operator, outer *Outer) (*Subquery, error) {
+	if expr.Kind != "EXISTS" && len(plan.Columns()) != 1 {
+		return nil, errors.New("!Failed to query because subquery " + expr.String() + " has to select one column.")
+	}
+
+	return &Subquery{expr: expr, plan: plan, outer: outer, operand: operand}, nil
+}
+
+// NewOuter ...
+func NewOuter() *Outer {
+	return &Outer{}
+}
+
+func (s *Subquery) String() string {
+	return s.expr.String()
+}
+
+func (r *OuterRef) String() string {
+	return r.Ref.String()
+}
+
+// rows evaluates the subquery for a row of the query around it, an EXISTS only needs its first row
+func (s *Subquery) rows(columns []diskio.ColumnDef, row []string) (*diskio.Set, error) {
+	if s.result != nil && s.Correlated == false {
+		return s.result, nil
+	}
+
+	s.outer.columns = columns
+	s.outer.row = row
+
+	err := s.plan.Open()
+	if err != nil {
+		s.plan.Close()
+		return nil, err
+	}
+	defer s.plan.Close()
+
+	set := &diskio.Set{ColumnDefs: s.plan.Columns()}
+	for s.expr.Kind != "EXISTS" || len(set.Records) == 0 {
+		record, err := s.plan.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		set.Records = append(set.Records, record)
+	}
+
+	s.result = set
+	return set, nil
+}
+
+func compileSubquery(s *Subquery, columns []diskio.ColumnDef) (evaluator, string, error) {
+	switch s.expr.Kind {
+	case "EXISTS":
+		return func(row []string) (Value, error) {
+			set, err := s.rows(columns, row)
+			if err != nil {
+				return nil, err
+			}
+			return boolValue(len(set.Records) > 0), nil
+		}, intType, nil
+
+	case "IN":
+		operand, _, err := compile(s.operand, columns)
+		if err != nil {
+			return nil, "", err
+		}
+
+		typeName := s.plan.Columns()[0].TypeName
+		collation := collationOf(s.operand, columns)
+		if collation == "" {
+			collation = s.plan.Columns()[0].Collation
+		}
+
+		return func(row []string) (Value, error) {
+			value, err := operand(row)
+			if err != nil {
+				return nil, err
+			}
+
+			set, err := s.rows(columns, row)
+			if err != nil {
+				return nil, err
+			}
+
+			var values []Value
+			for _, record := range set.Records {
+				values = append(values, ReadValue(record[0], typeName))
+			}
+			return in(value, values, collation), nil
+		}, intType, nil
+	}
+
+	column := s.plan.Columns()[0]
+	return func(row []string) (Value, error) {
+		set, err := s.rows(columns, row)
+		if err != nil {
+			return nil, err
+		}
+
+		switch len(set.Records) {
+		case 0:
+			return nil, nil
+		case 1:
+			return ReadValue(set.Records[0][0], column.TypeName), nil
+		}
+		return nil, errors.New("!Failed to query because subquery " + s.expr.String() + " returned more than one row.")
+	}, column.TypeName, nil
+}
+
+func compileOuterRef(r *OuterRef) (evaluator, string, error) {
+	return func(row []string) (Value, error) {
+		if r.Outer.row == nil {
+			return nil, errors.New("!Failed to query because column " + r.Ref.String() + " can't be used here.")
+		}
+
+		index, err := ResolveColumn(r.Ref, r.Outer.columns)
+		if err != nil {
+			return nil, err
+		}
+		return ReadValue(r.Outer.row[index], r.Outer.columns[index].TypeName), nil
+	}, r.TypeName, nil
+}
+
+func compileIn(e *parser.InExpr, columns []diskio.ColumnDef) (evaluator, string, error) {
+	operand, _, err := compile(e.Operand, columns)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var list []evaluator
+	for _, expr := range e.List {
+		item, _, err := compile(expr, columns)
+		if err != nil {
+			return nil, "", err
+		}
+		list = append(list, item)
+	}
+
+	// x IN (a, b) compares under the collation of x
+	collation := collationOf(e.Operand, columns)
+
+	return func(row []string) (Value, error) {
+		value, err := operand(row)
+		if err != nil {
+			return nil, err
+		}
+
+		var values []Value
+		for _, item := range list {
+			v, err := item(row)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return in(value, values, collation), nil
+	}, intType, nil
+}
+
+// Materialize reads its child into a diskio.Set the first time it's opened, and reads the
+// set back from then on. A derived table in FROM is materialized, under its alias
+type Materialize struct {
+	child   Operator
+	alias   string
+	columns []diskio.ColumnDef
+
+	// Correlated makes it read its child again each time it's opened
+	Correlated bool
+
+	set    *diskio.Set
+	cursor int
+}
+
+// NewMaterialize creates a materialization of its child under an alias, its columns can be renamed by names
+func NewMaterialize(child Operator, alias string, names []string) (*Materialize, error) {
+	if len(names) > 0 && len(names) != len(child.Columns()) {
+		return nil, errors.New("!Failed to query because " + alias + " names " + strconv.Itoa(len(names)) + " columns, but selects " +
+			strconv.Itoa(len(child.Columns())) + ".")
+	}
+
+	m := &Materialize{child: child, alias: alias}
+
+	for i, column := range child.Columns() {
+		column.Table = alias
+		if len(names) > 0 {
+			column.ColumnName = names[i]
+		}
+		m.columns = append(m.columns, column)
+	}
+
+	return m, nil
+}
+
+// Open ...
+func (m *Materialize) Open() error {
+	m.cursor = 0
+	if m.set != nil && m.Correlated == false {
+		return nil
+	}
+
+	err := m.child.Open()
+	if err != nil {
+		m.child.Close()
+		return err
+	}
+	defer m.child.Close()
+
+	set := &diskio.Set{Name: m.alias, ColumnDefs: m.columns}
+	err = drain(m.child, func(row []string) error {
+		set.Records = append(set.Records, row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.set = set
+	return nil
+}
+
+// Next ...
+func (m *Materialize) Next() ([]string, error) {
+	if m.cursor >= len(m.set.Records) {
+		return nil, io.EOF
+	}
+
+	m.cursor++
+	return m.set.Records[m.cursor-1], nil
+}
+
+// Close ...
+func (m *Materialize) Close() error {
+	return nil
+}
+
+// Columns ...
+func (m *Materialize) Columns() []diskio.ColumnDef {
+	return m.columns
+}
+
+// Explain ...
+func (m *Materialize) Explain() string {
+	return "Materialize AS " + m.alias
+}
+
+// Children ...
+func (m *Materialize) Children() []Operator {
+	return []Operator{m.child}
+}
+
+// EstimatedRows ...
+func (m *Materialize) EstimatedRows() float64 {
+	return m.child.EstimatedRows()
+}
+
+//
+//			Helper functions
+//
+
+// in checks if a value is among some values: NULL if it's NULL, or isn't found but a NULL
+// might have been it, the way x IN (a, b) is x = a OR x = b
+func in(value Value, values []Value, collation string) Value {
+	var result Value = int64(0)
+
+	for _, v := range values {
+		switch Compare("=", value, v, collation) {
+		case int64(1):
+			return int64(1)
+		case nil:
+			result = nil
+		}
+	}
+
+	return result
+}
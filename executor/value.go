@@ -0,0 +1,213 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"sqlit/diskio"
+	"strconv"
+	"strings"
+)
+
+// A Value is a field read as its column's type: nil for NULL, int64, float64, string or DateTime.
+// Comparisons and logic produce int64 1 or 0, the same as SQLite
+type Value interface{}
+
+// type families, the ways a column's declared type can be compared
+const (
+	intType   = "int"
+	floatType = "float"
+	textType  = "varchar"
+)
+
+// typeFamily maps a declared column type such as varchar(20) onto the way it's compared
+func typeFamily(typeName string) string {
+	t := strings.ToLower(typeName)
+
+	switch {
+	case strings.HasPrefix(t, "int"), strings.HasSuffix(t, "int"), t == "integer":
+		return intType
+	case strings.HasPrefix(t, "float"), strings.HasPrefix(t, "double"), strings.HasPrefix(t, "real"),
+		strings.HasPrefix(t, "decimal"), strings.HasPrefix(t, "numeric"):
+		return floatType
+	case t == "date":
+		return dateType
+	case t == "time":
+		return timeType
+	case t == "timestamp", t == "datetime":
+		return timestampType
+	}
+
+	return textType
+}
+
+// isNumericFamily checks if a type family is a number, an int or a float
+func isNumericFamily(family string) bool {
+	return family == intType || family == floatType
+}
+
+// ReadValue reads a persisted field as its column's type,
+// a number that doesn't parse stays a string
+func ReadValue(field string, typeName string) Value {
+	if field == diskio.Null {
+		return nil
+	}
+
+	switch typeFamily(typeName) {
+	case intType:
+		if i, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+			return f
+		}
+	case floatType:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+			return f
+		}
+	case dateType, timeType, timestampType:
+		if d, err := parseDateTime(field, typeFamily(typeName)); err == nil {
+			return d
+		}
+	}
+
+	return field
+}
+
+// FormatValue writes a value back out the way it's persisted
+func FormatValue(value Value) string {
+	switch v := value.(type) {
+	case nil:
+		return diskio.Null
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		// round off to 15 significant digits like SQLite, so 0.1 + 0.2 is written as 0.3
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 15, 64), 64)
+		return strconv.FormatFloat(rounded, 'f', -1, 64)
+	case string:
+		return v
+	case DateTime:
+		return v.String()
+	}
+
+	return ""
+}
+
+// compareValues orders two non-NULL values. Numbers compare numerically (a string that
+// reads as a number is one), dates and times by time (a string that reads as one is one),
+// everything else compares as text under a collation, ignoring case without one
+func compareValues(a Value, b Value, collation string) int {
+	if comparison, ok := compareDateTimes(a, b); ok {
+		return comparison
+	}
+
+	aNumber, aIsNumber := toNumber(a)
+	bNumber, bIsNumber := toNumber(b)
+
+	if aIsNumber && bIsNumber {
+		aInt, aIsInt := aNumber.(int64)
+		bInt, bIsInt := bNumber.(int64)
+		if aIsInt && bIsInt {
+			return compareInts(aInt, bInt)
+		}
+		return compareFloats(toFloat(aNumber), toFloat(bNumber))
+	}
+
+	return compareText(FormatValue(a), FormatValue(b), collation)
+}
+
+// compareNullable orders two values with NULLs first
+func compareNullable(a Value, b Value, collation string) int {
+	if a == nil && b == nil {
+		return 0
+	}
+	if a == nil {
+		return -1
+	}
+	if b == nil {
+		return 1
+	}
+	return compareValues(a, b, collation)
+}
+
+// Truth is a value's boolean meaning, known is false when it's NULL
+func Truth(value Value) (result bool, known bool) {
+	switch v := value.(type) {
+	case nil:
+		return false, false
+	case int64:
+		return v != 0, true
+	case float64:
+		return v != 0, true
+	case string:
+		if number, ok := toNumber(v); ok {
+			return toFloat(number) != 0, true
+		}
+	}
+
+	return false, true
+}
+
+func boolValue(b bool) Value {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+//
+//			Helper functions
+//
+
+// toNumber reads a value as an int64 or float64, if it is one
+func toNumber(value Value) (Value, bool) {
+	switch v := value.(type) {
+	case int64, float64:
+		return v, true
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+func toFloat(number Value) float64 {
+	switch v := number.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func compareInts(a int64, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a float64, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func notANumber(value Value) error {
+	return errors.New("!Failed to query because '" + FormatValue(value) + "' is not a number.")
+}
@@ -0,0 +1,459 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"strconv"
+	"strings"
+)
+
+// Window computes window functions over the partitions of its child, which must already be
+// sorted by the window's PARTITION BY and then its ORDER BY. Each partition is read into a
+// diskio.Set, and its rows are returned with a column for each function, named after its SQL.
+// Rows with the same ORDER BY values are peers, they rank the same
+type Window struct {
+	child       Operator
+	window      *parser.Window
+	partitionBy []evaluator
+	orderBy     []evaluator
+	functions   []windowFunction
+	columns     []diskio.ColumnDef
+
+	// how the text of each PARTITION BY and ORDER BY value is compared
+	partitionCollations []string
+	orderCollations     []string
+
+	// the partition being returned and its functions' values, and the row that started the next one
+	partition *diskio.Set
+	values    [][]Value
+	cursor    int
+	pending   []string
+	done      bool
+}
+
+// a windowFunction is a compiled window function call, an aggregate folds its frame with aggregate
+type windowFunction struct {
+	call      *parser.FuncCall
+	arg       evaluator
+	aggregate aggregateCall
+
+	// how far back LAG or forward LEAD looks, and what it is when that's outside the partition
+	offset   int
+	fallback evaluator
+}
+
+// NewWindow creates a window over its child for some calls, which are all over the same window
+func NewWindow(child Operator, window *parser.Window, calls []*parser.FuncCall) (*Window, error) {
+	w := &Window{child: child, window: window}
+	w.columns = append(w.columns, child.Columns()...)
+
+	for _, expr := range window.PartitionBy {
+		compiled, _, err := compile(expr, child.Columns())
+		if err != nil {
+			return nil, err
+		}
+		w.partitionBy = append(w.partitionBy, compiled)
+		w.partitionCollations = append(w.partitionCollations, collationOf(expr, child.Columns()))
+	}
+
+	for _, term := range window.OrderBy {
+		compiled, _, err := compile(term.Expr, child.Columns())
+		if err != nil {
+			return nil, err
+		}
+		w.orderBy = append(w.orderBy, compiled)
+		w.orderCollations = append(w.orderCollations, collationOf(term.Expr, child.Columns()))
+	}
+
+	for _, call := range calls {
+		function, typeName, err := compileWindowFunction(call, child.Columns())
+		if err != nil {
+			return nil, err
+		}
+
+		w.functions = append(w.functions, function)
+		w.columns = append(w.columns, diskio.ColumnDef{ColumnName: call.String(), TypeName: typeName})
+	}
+
+	return w, nil
+}
+
+// Open ...
+func (w *Window) Open() error {
+	w.partition = nil
+	w.values = nil
+	w.pending = nil
+	w.done = false
+	return w.child.Open()
+}
+
+// Next ...
+func (w *Window) Next() ([]string, error) {
+	for w.partition == nil || w.cursor >= len(w.partition.Records) {
+		if w.done && w.pending == nil {
+			return nil, io.EOF
+		}
+
+		err := w.readPartition()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	row := append([]string{}, w.partition.Records[w.cursor]...)
+	for _, value := range w.values[w.cursor] {
+		row = append(row, FormatValue(value))
+	}
+
+	w.cursor++
+	return row, nil
+}
+
+// Close ...
+func (w *Window) Close() error {
+	w.partition = nil
+	w.values = nil
+	return w.child.Close()
+}
+
+// Columns ...
+func (w *Window) Columns() []diskio.ColumnDef {
+	return w.columns
+}
+
+// Explain ...
+func (w *Window) Explain() string {
+	var calls []string
+	for _, function := range w.functions {
+		calls = append(calls, (&parser.FuncCall{Name: function.call.Name, Args: function.call.Args}).String())
+	}
+	return "Window " + w.window.String() + ": " + strings.Join(calls, ", ")
+}
+
+// Children ...
+func (w *Window) Children() []Operator {
+	return []Operator{w.child}
+}
+
+// EstimatedRows ...
+func (w *Window) EstimatedRows() float64 {
+	return w.child.EstimatedRows()
+}
+
+// readPartition reads the rows up to the next partition into a set, and computes their functions
+func (w *Window) readPartition() error {
+	row := w.pending
+	w.pending = nil
+
+	if row == nil {
+		var err error
+		row, err = w.child.Next()
+		if err == io.EOF {
+			w.done = true
+			w.partition = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	key, err := evaluateAll(w.partitionBy, row)
+	if err != nil {
+		return err
+	}
+
+	set := &diskio.Set{ColumnDefs: w.child.Columns()}
+	for {
+		set.Records = append(set.Records, row)
+
+		row, err = w.child.Next()
+		if err == io.EOF {
+			w.done = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rowKey, err := evaluateAll(w.partitionBy, row)
+		if err != nil {
+			return err
+		}
+		if sameKey(key, rowKey, w.partitionCollations) == false {
+			w.pending = row
+			break
+		}
+	}
+
+	w.partition = set
+	w.cursor = 0
+	w.values, err = w.compute(set.Records)
+	return err
+}
+
+// compute works out every function for every row of a partition
+func (w *Window) compute(records [][]string) ([][]Value, error) {
+	n := len(records)
+
+	// where each row's peers start and end
+	peerStart := make([]int, n)
+	peerEnd := make([]int, n)
+	var previous []Value
+
+	for i, row := range records {
+		key, err := evaluateAll(w.orderBy, row)
+		if err != nil {
+			return nil, err
+		}
+
+		peerStart[i] = i
+		if i > 0 && sameKey(previous, key, w.orderCollations) {
+			peerStart[i] = peerStart[i-1]
+		}
+		previous = key
+	}
+	for i := n - 1; i >= 0; i-- {
+		peerEnd[i] = i
+		if i < n-1 && peerStart[i+1] == peerStart[i] {
+			peerEnd[i] = peerEnd[i+1]
+		}
+	}
+
+	values := make([][]Value, n)
+	for i := range values {
+		values[i] = make([]Value, len(w.functions))
+	}
+
+	for f, function := range w.functions {
+		var err error
+
+		switch function.call.Name {
+		case "ROW_NUMBER":
+			for i := range records {
+				values[i][f] = int64(i + 1)
+			}
+
+		case "RANK":
+			for i := range records {
+				values[i][f] = int64(peerStart[i] + 1)
+			}
+
+		case "DENSE_RANK":
+			rank := int64(0)
+			for i := range records {
+				if peerStart[i] == i {
+					rank++
+				}
+				values[i][f] = rank
+			}
+
+		case "LAG", "LEAD":
+			err = w.computeOffset(f, function, records, values)
+
+		default:
+			err = w.computeAggregate(f, function, records, values, peerEnd)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// computeOffset reads the row offset back for LAG, or forward for LEAD
+func (w *Window) computeOffset(f int, function windowFunction, records [][]string, values [][]Value) error {
+	for i := range records {
+		j := i - function.offset
+		if function.call.Name == "LEAD" {
+			j = i + function.offset
+		}
+
+		var value Value
+		var err error
+
+		if j >= 0 && j < len(records) {
+			value, err = function.arg(records[j])
+		} else if function.fallback != nil {
+			value, err = function.fallback(records[i])
+		}
+		if err != nil {
+			return err
+		}
+
+		values[i][f] = value
+	}
+
+	return nil
+}
+
+// computeAggregate folds each row's frame. A frame that starts at the start of the partition
+// only ever grows, so it's folded as it goes, anything else is folded again for each row
+func (w *Window) computeAggregate(f int, function windowFunction, records [][]string, values [][]Value, peerEnd []int) error {
+	n := len(records)
+
+	running := accumulator{}
+	folded := 0
+
+	for i := range records {
+		start, end := 0, n-1
+		if w.window.Frame != nil {
+			start, end = frameIndex(w.window.Frame.Start, i, n), frameIndex(w.window.Frame.End, i, n)
+			if start < 0 {
+				start = 0
+			}
+			if end > n-1 {
+				end = n - 1
+			}
+		} else if len(w.orderBy) > 0 {
+			end = peerEnd[i]
+		}
+
+		if start == 0 {
+			for ; folded <= end; folded++ {
+				err := running.add(function.aggregate, records[folded])
+				if err != nil {
+					return err
+				}
+			}
+
+			values[i][f] = running.result(function.aggregate)
+			continue
+		}
+
+		frame := accumulator{}
+		for j := start; j <= end; j++ {
+			err := frame.add(function.aggregate, records[j])
+			if err != nil {
+				return err
+			}
+		}
+
+		values[i][f] = frame.result(function.aggregate)
+	}
+
+	return nil
+}
+
+//
+//			Helper functions
+//
+
+// compileWindowFunction compiles a call over a window, and returns the type it produces
+func compileWindowFunction(call *parser.FuncCall, columns []diskio.ColumnDef) (windowFunction, string, error) {
+	function := windowFunction{call: call}
+
+	if call.Distinct {
+		return function, "", errors.New("!Failed to query because DISTINCT can't be used in " + call.String() + ".")
+	}
+
+	switch call.Name {
+	case "ROW_NUMBER", "RANK", "DENSE_RANK":
+		if len(call.Args) != 0 {
+			return function, "", errors.New("!Failed to query because " + call.Name + " takes no arguments.")
+		}
+		return function, intType, nil
+
+	case "LAG", "LEAD":
+		if len(call.Args) < 1 || len(call.Args) > 3 {
+			return function, "", errors.New("!Failed to query because " + call.Name + " takes one to three arguments.")
+		}
+
+		arg, typeName, err := compile(call.Args[0], columns)
+		if err != nil {
+			return function, "", err
+		}
+		function.arg = arg
+		function.offset = 1
+
+		if len(call.Args) > 1 {
+			literal, ok := call.Args[1].(*parser.Literal)
+			offset, err := 0, errors.New("")
+			if ok && literal.Kind == tokenizer.Number {
+				offset, err = strconv.Atoi(literal.Value)
+			}
+			if err != nil || offset < 0 {
+				return function, "", errors.New("!Failed to query because the offset of " + call.Name + " has to be a number of rows.")
+			}
+			function.offset = offset
+		}
+
+		if len(call.Args) > 2 {
+			function.fallback, _, err = compile(call.Args[2], columns)
+			if err != nil {
+				return function, "", err
+			}
+		}
+
+		return function, typeName, nil
+	}
+
+	if parser.Aggregates[call.Name] == false {
+		return function, "", errors.New("!Failed to query because " + call.Name + " isn't a window function.")
+	}
+
+	if len(call.Args) != 1 {
+		return function, "", errors.New("!Failed to query because " + call.Name + " takes exactly one argument.")
+	}
+
+	function.aggregate = aggregateCall{name: call.Name}
+	typeName := intType
+
+	if _, star := call.Args[0].(*parser.Star); star == false {
+		arg, argType, err := compile(call.Args[0], columns)
+		if err != nil {
+			return function, "", err
+		}
+		function.aggregate.arg = arg
+		function.aggregate.typeName = argType
+		function.aggregate.collation = collationOf(call.Args[0], columns)
+		typeName = argType
+	} else if call.Name != "COUNT" {
+		return function, "", errors.New("!Failed to query because " + call.String() + " can't be used here.")
+	}
+
+	switch call.Name {
+	case "COUNT":
+		typeName = intType
+	case "AVG":
+		typeName = floatType
+	case "SUM":
+		typeName = numericType(typeName, typeName)
+	}
+
+	return function, typeName, nil
+}
+
+// frameIndex is the row of a partition of n rows a frame bound is at, from row i.
+// It can be outside the partition, which leaves the frame short, or empty
+func frameIndex(bound parser.FrameBound, i int, n int) int {
+	switch {
+	case bound.Unbounded && bound.Offset < 0:
+		return 0
+	case bound.Unbounded:
+		return n - 1
+	}
+	return i + bound.Offset
+}
+
+// evaluateAll evaluates some expressions over a row, like a key to compare rows by
+func evaluateAll(evaluators []evaluator, row []string) ([]Value, error) {
+	values := make([]Value, len(evaluators))
+
+	for i, evaluate := range evaluators {
+		value, err := evaluate(row)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
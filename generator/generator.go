@@ -7,50 +7,110 @@ package generator
 
 import (
 	"errors"
+	"io"
 	// "fmt"
 	"sqlit/diskio"
+	"sqlit/executor"
 	"sqlit/parser"
 	"sqlit/tokenizer"
+	"sqlit/vm"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Operation ...
 type Operation struct {
 	Assert func() (err error)
-	Invoke func() (success string, err error)
+	Invoke func() (result Result, err error)
+
+	// Program compiles the statement to bytecode, for statements the vm runs
+	Program func() (program *vm.Program, err error)
+
+	// Parameters is how many values a prepared statement takes, Bind makes an operation
+	// with them bound. The statement is only parsed once however many times it's bound
+	Parameters int
+	Bind       func(args []*parser.Literal) Operation
+}
+
+// Result is what an operation did. Selects fill in ColumnDefs and Rows,
+// everything else describes itself with a Message
+type Result struct {
+	ColumnDefs   []diskio.ColumnDef
+	Rows         RowIterator
+	RowsAffected int
+	LastInsertID int
+	Message      string
+}
+
+// RowIterator steps through the rows of a result, returning io.EOF once there are none left.
+// Every executor.Operator is one
+type RowIterator interface {
+	Next() ([]string, error)
+	Close() error
+}
+
+// setIterator steps through the records of an in-memory set
+type setIterator struct {
+	records [][]string
+	cursor  int
+}
+
+func newSetIterator(set diskio.Set) *setIterator {
+	return &setIterator{records: set.Records}
+}
+
+func (iterator *setIterator) Next() ([]string, error) {
+	if iterator.cursor >= len(iterator.records) {
+		return nil, io.EOF
+	}
+
+	record := iterator.records[iterator.cursor]
+	iterator.cursor++
+	return record, nil
+}
+
+func (iterator *setIterator) Close() error {
+	iterator.records = nil
+	return nil
 }
 
 // Generate ...
-func Generate(statement tokenizer.Statement, inTransactionMode bool) Operation {
+func Generate(session *diskio.Session, statement tokenizer.Statement) Operation {
 
 	operation := Operation{}
 
 	switch statement.Type {
 	case parser.Types["CREATE_DATABASE"]:
-		operation = generateCreateDatabase(statement)
+		operation = generateCreateDatabase(session, statement)
 	case parser.Types["DROP_DATABASE"]:
-		operation = generateDropDatabase(statement)
+		operation = generateDropDatabase(session, statement)
 	case parser.Types["USE_DATABASE"]:
-		operation = generateUseDatabase(statement)
+		operation = generateUseDatabase(session, statement)
 	case parser.Types["CREATE_TABLE"]:
-		operation = generateCreateTable(statement)
+		operation = generateCreateTable(session, statement)
 	case parser.Types["ALTER_TABLE"]:
-		operation = generateAlterTable(statement)
+		operation = generateAlterTable(session, statement)
 	case parser.Types["DROP_TABLE"]:
-		operation = generateDropTable(statement)
+		operation = generateDropTable(session, statement)
+	case parser.Types["CREATE_INDEX"]:
+		operation = generateCreateIndex(session, statement)
+	case parser.Types["DROP_INDEX"]:
+		operation = generateDropIndex(session, statement)
+	case parser.Types["ANALYZE"]:
+		operation = generateAnalyze(session, statement)
 	case parser.Types["SELECT"]:
-		operation = generateSelect(statement)
-	case parser.Types["SELECT_INNER"]:
-		operation = generateSelectInnerJoin(statement)
-	case parser.Types["SELECT_LEFT"]:
-		operation = generateSelectLeftJoin(statement)
+		operation = generateQuery(session, statement)
+	case parser.Types["EXPLAIN"]:
+		operation = generateExplain(session, statement)
 	case parser.Types["INSERT"]:
-		operation = generateInsert(statement)
+		operation = generateInsert(session, statement)
 	case parser.Types["UPDATE"]:
-		operation = generateUpdate(statement, inTransactionMode)
+		operation = generateUpdate(session, statement)
 	case parser.Types["DELETE"]:
-		operation = generateDelete(statement)
+		operation = generateDelete(session, statement)
+	case parser.Types["TRUNCATE"]:
+		operation = generateTruncate(session, statement)
 		//	case parser.Types["BEGIN"]:
 		// operation = generateBegin(statement)
 		// 	case parser.Types["COMMIT"]:
@@ -86,446 +146,986 @@ func Generate(statement tokenizer.Statement, inTransactionMode bool) Operation {
 // 	return Operation{Assert: assert, Invoke: invoke}
 // }
 
-func generateCreateDatabase(statement tokenizer.Statement) Operation {
+func generateCreateDatabase(session *diskio.Session, statement tokenizer.Statement) Operation {
 	name := getFirstTokenOfName(statement, "DATABASE_NAME")
 
 	assert := func() error {
-		if diskio.CheckIfDatabaseExists(name) == true {
+		if diskio.CheckIfDatabaseExists(session, name) == true {
 			return errors.New("!Failed to create database " + name + " because it already exists.")
 		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		err := diskio.CreateDatabase(name)
-		diskio.CreateDatabaseMeta(name)
-		return "Database " + name + " created.", err
+	invoke := func() (Result, error) {
+		err := diskio.CreateDatabase(session, name)
+		if err != nil {
+			return Result{}, err
+		}
+		diskio.CreateDatabaseMeta(session, name)
+		return Result{Message: "Database " + name + " created."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateDropDatabase(statement tokenizer.Statement) Operation {
+func generateDropDatabase(session *diskio.Session, statement tokenizer.Statement) Operation {
 	name := getFirstTokenOfName(statement, "DATABASE_NAME")
 
 	assert := func() error {
-		if diskio.CheckIfDatabaseExists(name) == false {
+		if diskio.CheckIfDatabaseExists(session, name) == false {
 			return errors.New("!Failed to delete " + name + " because it does not exist.")
 		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		err := diskio.DeleteDatabase(name)
-		return "Database " + name + " deleted.", err
+	invoke := func() (Result, error) {
+		err := diskio.DeleteDatabase(session, name)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Message: "Database " + name + " deleted."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateUseDatabase(statement tokenizer.Statement) Operation {
+func generateUseDatabase(session *diskio.Session, statement tokenizer.Statement) Operation {
 	name := getFirstTokenOfName(statement, "DATABASE_NAME")
 
 	assert := func() error {
-		if diskio.CheckIfDatabaseExists(name) == false {
+		if diskio.CheckIfDatabaseExists(session, name) == false {
 			return errors.New("!Failed to use database " + name + " because it does not exist.")
 		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		diskio.UseDatabase(name)
-		return "Using database " + name, nil
+	invoke := func() (Result, error) {
+		diskio.UseDatabase(session, name)
+		return Result{Message: "Using database " + name}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateCreateTable(statement tokenizer.Statement) Operation {
-	name := getFirstTokenOfName(statement, "TABLE_NAME")
-	columns := getAllTokensOfName(statement, "COL_NAME")
-	constraints := getAllTokensOfName(statement, "COL_TYPE")
-
-	// TODO: move these to tokenizer?
-	columns, constraints = removeOuterParenthesis(columns, constraints)
-
-	columns = removeCommas(columns)
-	constraints = removeCommas(constraints)
+func generateCreateTable(session *diskio.Session, statement tokenizer.Statement) Operation {
+	create, parseErr := parser.ParseCreateTable(statement.Raw)
 
 	assert := func() error {
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
+		if parseErr != nil {
+			return parseErr
+		}
+		name := create.Table
+
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
 			return errors.New("!Failed to create table " + name + " because no database is in use.")
 		}
 
-		if diskio.CheckIfTableExists(name) == true {
+		if diskio.CheckIfTableExists(session, name) == true {
 			return errors.New("!Failed to create table " + name + " because it already exists.")
 		}
+
+		for _, column := range create.Columns {
+			if column.Collation != "" && executor.IsCollation(column.Collation) == false {
+				return errors.New("!Failed to create table " + name + " because " + column.Collation + " isn't a collation.")
+			}
+		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		diskio.CreateTable(name, columns, constraints)
-		return "Table " + name + " created.", nil
+	invoke := func() (Result, error) {
+		var columns, constraints []string
+		for _, column := range create.Columns {
+			constraint := column.TypeName
+			if column.Collation != "" {
+				constraint += " COLLATE " + column.Collation
+			}
+
+			columns = append(columns, column.Name)
+			constraints = append(constraints, constraint)
+		}
+
+		diskio.CreateTable(session, create.Table, columns, constraints)
+		return Result{Message: "Table " + create.Table + " created."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateDropTable(statement tokenizer.Statement) Operation {
+func generateDropTable(session *diskio.Session, statement tokenizer.Statement) Operation {
 	name := getFirstTokenOfName(statement, "TABLE_NAME")
 
 	assert := func() error {
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
 			return errors.New("!Failed to delete table " + name + " because no database is in use.")
 		}
 
-		if diskio.CheckIfTableExists(name) == false {
+		if diskio.CheckIfTableExists(session, name) == false {
 			return errors.New("!Failed to delete table " + name + " because it does not exist.")
 		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		diskio.DropTable(name)
-		return "Table " + name + " deleted.", nil
+	invoke := func() (Result, error) {
+		diskio.DropTable(session, name)
+		diskio.DropIndexesOfTable(session, name)
+		diskio.DropTableStats(session, name)
+		return Result{Message: "Table " + name + " deleted."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateAlterTable(statement tokenizer.Statement) Operation {
+func generateAlterTable(session *diskio.Session, statement tokenizer.Statement) Operation {
 	name := getFirstTokenOfName(statement, "TABLE_NAME")
 	method := getFirstTokenOfName(statement, "ADD_COL")
 	column := getFirstTokenOfName(statement, "COL_NAME")
 	constraint := getFirstTokenOfName(statement, "COL_TYPE")
 
 	assert := func() error {
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
 			return errors.New("!Failed to alter table " + name + " because no database is in use.")
 		}
 
-		if diskio.CheckIfTableExists(name) == false {
+		if diskio.CheckIfTableExists(session, name) == false {
 			return errors.New("!Failed to alter table " + name + " because it does not exist.")
 		}
 
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		diskio.AlterTable(name, method, column, constraint)
-		return "Table " + name + " modified.", nil
+	invoke := func() (Result, error) {
+		diskio.AlterTable(session, name, method, column, constraint)
+		return Result{Message: "Table " + name + " modified."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateSelect(statement tokenizer.Statement) Operation {
+func generateCreateIndex(session *diskio.Session, statement tokenizer.Statement) Operation {
+	parseErr := checkCreateIndex(statement)
+	if parseErr != nil {
+		return Operation{Assert: func() error { return parseErr }}
+	}
 
-	name := getFirstTokenOfName(statement, "TABLE_NAME")
-	clause := statement.Tokens[1].Name
+	index := diskio.IndexDef{
+		Name:   getFirstTokenOfName(statement, "INDEX_NAME"),
+		Table:  getFirstTokenOfName(statement, "TABLE_NAME"),
+		Column: indexColumn(statement),
+	}
 
 	assert := func() error {
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
-			return errors.New("!Failed to query table " + name + " because no database is in use.")
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to create index " + index.Name + " because no database is in use.")
 		}
 
-		if diskio.CheckIfTableExists(name) == false {
-			return errors.New("!Failed to query table " + name + " because it does not exist.")
+		if diskio.CheckIfTableExists(session, index.Table) == false {
+			return errors.New("!Failed to create index " + index.Name + " because table " + index.Table + " does not exist.")
 		}
-		return nil
-	}
 
-	invoke := func() (string, error) {
-		var result string
-
-		if clause == "ALL" {
-			result = diskio.SelectAll(name)
-		} else {
-			name := getFirstTokenOfName(statement, "TABLE_NAME")
-
-			var colNames []string
+		if diskio.CheckIfIndexExists(session, index.Name) == true {
+			return errors.New("!Failed to create index " + index.Name + " because it already exists.")
+		}
 
-			colNames = append(colNames, getFirstTokenOfName(statement, "COL_NAME"))
-			colNames = append(colNames, getSecondTokenOfName(statement, "COL_NAME"))
+		columns, err := diskio.ReadColumnDefs(session, index.Table)
+		if err != nil {
+			return err
+		}
+		for _, column := range columns {
+			if strings.EqualFold(column.ColumnName, index.Column) {
+				index.Column = column.ColumnName
+				return nil
+			}
+		}
 
-			whereColName := getThirdTokenOfName(statement, "COL_NAME")
-			whereColVal := getFirstTokenOfName(statement, "COL_VALUE")
+		return errors.New("!Failed to create index " + index.Name + " because column " + index.Column + " does not exist.")
+	}
 
-			colNames = removeCommas(colNames)
+	invoke := func() (Result, error) {
+		diskio.CreateIndexMeta(session, index)
 
-			result = diskio.SelectWhere(name, colNames, whereColName, whereColVal)
+		err := executor.BuildIndex(session, index)
+		if err != nil {
+			diskio.DropIndex(session, index.Name)
+			return Result{}, err
 		}
-		return result, nil
+
+		return Result{Message: "Index " + index.Name + " created."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateSelectInnerJoin(statement tokenizer.Statement) Operation {
-
-	leftTableName := getFirstTokenOfName(statement, "TABLE_NAME")
-	rightTableName := getSecondTokenOfName(statement, "TABLE_NAME")
+func generateDropIndex(session *diskio.Session, statement tokenizer.Statement) Operation {
+	if len(statement.Tokens) < 3 {
+		return Operation{Assert: func() error {
+			return errors.New("!Failed to parse statement because DROP INDEX needs the name of an index.")
+		}}
+	}
+	name := getFirstTokenOfName(statement, "INDEX_NAME")
 
 	assert := func() error {
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to delete index " + name + " because no database is in use.")
+		}
+
+		if diskio.CheckIfIndexExists(session, name) == false {
+			return errors.New("!Failed to delete index " + name + " because it does not exist.")
+		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		setOne := diskio.SelectSet(leftTableName)
-		setTwo := diskio.SelectSet(rightTableName)
-		innerJoinOfSets := InnerJoin(setOne, setTwo, "id", "employeeID")
-
-		result := diskio.SerializeSet(innerJoinOfSets)
-
-		return result, nil
+	invoke := func() (Result, error) {
+		diskio.DropIndex(session, name)
+		return Result{Message: "Index " + name + " deleted."}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-func generateSelectLeftJoin(statement tokenizer.Statement) Operation {
-	leftTableName := getFirstTokenOfName(statement, "TABLE_NAME")
-	rightTableName := getSecondTokenOfName(statement, "TABLE_NAME")
+// generateAnalyze collects the stats the planner estimates with, for one table or every table in the database
+func generateAnalyze(session *diskio.Session, statement tokenizer.Statement) Operation {
+	name := strings.Join(getAllTokensOfName(statement, "TABLE_NAME"), "")
 
 	assert := func() error {
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to analyze because no database is in use.")
+		}
+
+		if name != "" && diskio.CheckIfTableExists(session, name) == false {
+			return errors.New("!Failed to analyze table " + name + " because it does not exist.")
+		}
 		return nil
 	}
 
-	invoke := func() (string, error) {
-		setOne := diskio.SelectSet(leftTableName)
-		setTwo := diskio.SelectSet(rightTableName)
-		leftJoinOfSets := LeftJoin(setOne, setTwo, "id", "employeeID")
-		result := diskio.SerializeSet(leftJoinOfSets)
-		return result, nil
+	invoke := func() (Result, error) {
+		tables := []string{name}
+		if name == "" {
+			tables = diskio.ListTables(session)
+		}
+
+		var messages []string
+		for _, table := range tables {
+			stats, err := executor.CollectStatistics(session, table)
+			if err != nil {
+				return Result{}, err
+			}
+
+			diskio.WriteTableStats(session, stats)
+			messages = append(messages, "Table "+table+" analyzed.")
+		}
+
+		if len(messages) == 0 {
+			messages = append(messages, "No tables to analyze.")
+		}
+
+		return Result{Message: strings.Join(messages, "\n")}, nil
 	}
 
 	return Operation{Assert: assert, Invoke: invoke}
 }
 
-// InnerJoin ...
-func InnerJoin(setOne diskio.Set, setTwo diskio.Set, setOneColName string, setTwoColName string) diskio.Set {
-	var columnDefs []diskio.ColumnDef
+func generateInsert(session *diskio.Session, statement tokenizer.Statement) Operation {
+	insert, err := parser.ParseInsert(statement.Raw)
+	return insertOperation(session, insert, err)
+}
 
-	for _, columnDef := range setOne.ColumnDefs {
-		columnDefs = append(columnDefs, columnDef)
-	}
+func insertOperation(session *diskio.Session, insert *parser.Insert, parseErr error) Operation {
+	assert := func() error {
+		if parseErr != nil {
+			return parseErr
+		}
+		tableName := insert.Table
 
-	for _, columnDef := range setTwo.ColumnDefs {
-		columnDefs = append(columnDefs, columnDef)
-	}
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to query table " + tableName + " because no database is in use.")
+		}
+
+		if diskio.CheckIfTableExists(session, tableName) == false {
+			return errors.New("!Failed to query table " + tableName + " because it does not exist.")
+		}
 
-	var setOneColIndex int
-	var setTwoColIndex int
+		if insert.Query != nil {
+			err := assertQuery(session, insert.Query, nil)()
+			if err != nil {
+				return err
+			}
 
-	for index, columnDef := range setOne.ColumnDefs {
-		if columnDef.ColumnName == setOneColName {
-			setOneColIndex = index
+			// the query is planned before the table is locked, so one that can't run never leaves it locked
+			columns, err := diskio.ReadColumnDefs(session, tableName)
+			if err != nil {
+				return err
+			}
+			targets, err := insertTargets(tableName, columns, insert.Columns)
+			if err != nil {
+				return err
+			}
+			_, err = planInsertQuery(session, insert, targets)
+			if err != nil {
+				return err
+			}
 		}
+
+		return lockTableForWrite(session, tableName)
 	}
 
-	for index, columnDef := range setTwo.ColumnDefs {
-		if columnDef.ColumnName == setTwoColName {
-			setTwoColIndex = index
+	invoke := func() (Result, error) {
+		// NOW() is taken once, so every record the statement writes sees the same one
+		insert := insert.Rewrite(nowAt(time.Now()))
+
+		tableName := insert.Table
+
+		if session.InTransactionMode == false {
+			diskio.UnlockTable(session, tableName)
+		}
+
+		records, err := insertRecords(session, insert)
+		if err != nil {
+			return Result{}, err
 		}
+
+		recordsUpdated := 0
+		if insert.OnConflict != nil {
+			records, recordsUpdated, err = upsertRecords(session, insert, records)
+			if err != nil {
+				return Result{}, err
+			}
+		}
+
+		machine := vm.New(session, compileInsert(tableName, records))
+		err = machine.Run()
+		if err != nil {
+			return Result{}, err
+		}
+
+		message := "1 new record inserted."
+		if machine.Changes != 1 {
+			message = strconv.Itoa(machine.Changes) + " new records inserted."
+		}
+		if insert.OnConflict != nil && (insert.OnConflict.Replace || len(insert.OnConflict.Update) > 0) {
+			message += " " + strconv.Itoa(recordsUpdated) + " record(s) modified."
+		}
+		return Result{RowsAffected: machine.Changes + recordsUpdated, LastInsertID: machine.LastInsertID, Message: message}, nil
 	}
 
-	records := make([][]string, 5)
-	recordsRowIndex := 0
+	operation := Operation{Assert: assert, Invoke: invoke}
+
+	if insert == nil {
+		return operation
+	}
 
-	for _, setOneRecords := range setOne.Records {
-		for _, setTwoRecords := range setTwo.Records {
-			if setOneRecords[setOneColIndex] == setTwoRecords[setTwoColIndex] {
-				recordsRowIndex++
-				records[recordsRowIndex] = make([]string, 4)
-				records[recordsRowIndex][0] = setOneRecords[0]
-				records[recordsRowIndex][1] = setOneRecords[1]
-				records[recordsRowIndex][2] = setTwoRecords[0]
-				records[recordsRowIndex][3] = setTwoRecords[1]
+	// the records of an INSERT ... SELECT aren't known until its query has run,
+	// and which records of an upsert are inserted isn't known until the table is read
+	if insert.Query == nil && insert.OnConflict == nil {
+		operation.Program = func() (*vm.Program, error) {
+			records, err := insertRecords(session, insert)
+			if err != nil {
+				return nil, err
 			}
+			return compileInsert(insert.Table, records), nil
 		}
 	}
 
-	set := diskio.Set{Name: "inner-join", ColumnDefs: columnDefs, Records: records}
+	operation.Parameters = insert.Parameters
+	operation.Bind = func(args []*parser.Literal) Operation {
+		return insertOperation(session, insert.Bind(args), nil)
+	}
 
-	return set
+	return operation
 }
 
-// LeftJoin ...
-func LeftJoin(leftSet diskio.Set, rightSet diskio.Set, leftSetColName string, rightSetColName string) diskio.Set {
-	innerJoinOfSets := InnerJoin(leftSet, rightSet, leftSetColName, rightSetColName)
+func generateUpdate(session *diskio.Session, statement tokenizer.Statement) Operation {
+	update, err := parser.ParseUpdate(statement.Raw)
+	return updateOperation(session, update, err)
+}
+
+func updateOperation(session *diskio.Session, update *parser.Update, parseErr error) Operation {
+	assert := func() error {
+		if parseErr != nil {
+			return parseErr
+		}
+		tableName := update.Table
+
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to query table " + tableName + " because no database is in use.")
+		}
+
+		if diskio.CheckIfTableExists(session, tableName) == false {
+			return errors.New("!Failed to query table " + tableName + " because it does not exist.")
+		}
 
-	var columnDefsOfSets []diskio.ColumnDef
+		err := assertTables(session, update.Tables())
+		if err != nil {
+			return err
+		}
 
-	for _, columnDef := range leftSet.ColumnDefs {
-		columnDefsOfSets = append(columnDefsOfSets, columnDef)
+		return lockTableForWrite(session, tableName)
 	}
 
-	for _, columnDef := range rightSet.ColumnDefs {
-		columnDefsOfSets = append(columnDefsOfSets, columnDef)
+	invoke := func() (Result, error) {
+		update := update.Rewrite(nowAt(time.Now()))
+
+		tableName := update.Table
+
+		if session.InTransactionMode == false {
+			diskio.UnlockTable(session, tableName)
+		}
+
+		program, err := compileUpdate(session, update)
+		if err != nil {
+			return Result{}, err
+		}
+
+		machine := vm.New(session, program)
+		err = machine.Run()
+		if err != nil {
+			return Result{}, err
+		}
+		recordsModified := machine.Changes
+
+		result := strconv.Itoa(recordsModified)
+		result = result + " record(s) modified."
+		return Result{RowsAffected: recordsModified, Message: result}, nil
 	}
 
-	var leftSetColIndex int
-	var rightSetColIndex int
+	operation := Operation{Assert: assert, Invoke: invoke}
 
-	for index, columnDef := range leftSet.ColumnDefs {
-		if columnDef.ColumnName == leftSetColName {
-			leftSetColIndex = index
+	if update != nil {
+		operation.Program = func() (*vm.Program, error) {
+			return compileUpdate(session, update)
+		}
+		operation.Parameters = update.Parameters
+		operation.Bind = func(args []*parser.Literal) Operation {
+			return updateOperation(session, update.Bind(args), nil)
 		}
 	}
 
-	for index, columnDef := range rightSet.ColumnDefs {
-		if columnDef.ColumnName == rightSetColName {
-			rightSetColIndex = index
+	return operation
+}
+
+func generateDelete(session *diskio.Session, statement tokenizer.Statement) Operation {
+	del, err := parser.ParseDelete(statement.Raw)
+	return deleteOperation(session, del, err)
+}
+
+func deleteOperation(session *diskio.Session, del *parser.Delete, parseErr error) Operation {
+	assert := func() error {
+		if parseErr != nil {
+			return parseErr
 		}
+		table := del.Table
+
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to query table " + table + " because no database is in use.")
+		}
+
+		if diskio.CheckIfTableExists(session, table) == false {
+			return errors.New("!Failed to query table " + table + " because it does not exist.")
+		}
+
+		err := assertTables(session, del.Tables())
+		if err != nil {
+			return err
+		}
+
+		return lockTableForWrite(session, table)
 	}
 
-	leftExclusiveRecords := make([][]string, 5)
-	leftExclusiveRecordsTailRowIndex := -1
+	invoke := func() (Result, error) {
+		del := del.Rewrite(nowAt(time.Now()))
 
-	for _, leftSetRowRecord := range leftSet.Records {
-		leftExclusive := true
+		table := del.Table
 
-		for _, rightSetRowRecord := range rightSet.Records {
-			if leftSetRowRecord[leftSetColIndex] == rightSetRowRecord[rightSetColIndex] {
-				leftExclusive = false
-			}
+		if session.InTransactionMode == false {
+			diskio.UnlockTable(session, table)
 		}
 
-		if leftExclusive == true {
-			leftExclusiveRecordsTailRowIndex++
-			leftExclusiveRecords[leftExclusiveRecordsTailRowIndex] = make([]string, 4)
-			leftExclusiveRecords[leftExclusiveRecordsTailRowIndex][0] = leftSetRowRecord[0]
-			leftExclusiveRecords[leftExclusiveRecordsTailRowIndex][1] = leftSetRowRecord[1]
-			leftExclusiveRecords[leftExclusiveRecordsTailRowIndex][2] = ""
-			leftExclusiveRecords[leftExclusiveRecordsTailRowIndex][3] = ""
+		program, err := compileDelete(session, del)
+		if err != nil {
+			return Result{}, err
 		}
+
+		machine := vm.New(session, program)
+		err = machine.Run()
+		if err != nil {
+			return Result{}, err
+		}
+		recordsDeleted := machine.Changes
+
+		result := strconv.Itoa(recordsDeleted)
+		result = result + " record(s) deleted."
+		return Result{RowsAffected: recordsDeleted, Message: result}, nil
 	}
 
-	leftJoinRecords := append(innerJoinOfSets.Records, leftExclusiveRecords...)
+	operation := Operation{Assert: assert, Invoke: invoke}
 
-	leftJoinOfSets := diskio.Set{Name: "left-join", ColumnDefs: columnDefsOfSets, Records: leftJoinRecords}
+	if del != nil {
+		operation.Program = func() (*vm.Program, error) {
+			return compileDelete(session, del)
+		}
+		operation.Parameters = del.Parameters
+		operation.Bind = func(args []*parser.Literal) Operation {
+			return deleteOperation(session, del.Bind(args), nil)
+		}
+	}
 
-	return leftJoinOfSets
+	return operation
 }
 
-func generateInsert(statement tokenizer.Statement) Operation {
-	tableName := getFirstTokenOfName(statement, "TABLE_NAME")
-	values := getAllTokensOfName(statement, "VALUE")
-	values = removeCommas(values)
-	values = removeOuterParenthesisFromValues(values)
-	values = removeQuotes(values)
+func generateTruncate(session *diskio.Session, statement tokenizer.Statement) Operation {
+	name := strings.Join(getAllTokensOfName(statement, "TABLE_NAME"), "")
 
 	assert := func() error {
-		if diskio.CheckIfTableIsLockedByOtherProcess(tableName) == true {
-			return errors.New("Error: Table " + tableName + " is locked!")
-		} else {
-			diskio.LockTable(tableName)
+		if name == "" {
+			return errors.New("!Failed to truncate table because no table is named.")
 		}
 
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
-			return errors.New("!Failed to query table " + tableName + " because no database is in use.")
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to truncate table " + name + " because no database is in use.")
 		}
 
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
-			return errors.New("!Failed to query table " + tableName + " because no database is in use.")
+		if diskio.CheckIfTableExists(session, name) == false {
+			return errors.New("!Failed to truncate table " + name + " because it does not exist.")
 		}
 
-		if diskio.CheckIfTableExists(tableName) == false {
-			return errors.New("!Failed to query table " + tableName + " because it does not exist.")
+		return lockTableForWrite(session, name)
+	}
+
+	invoke := func() (Result, error) {
+		if session.InTransactionMode == false {
+			diskio.UnlockTable(session, name)
 		}
 
-		return nil
+		err := diskio.TruncateTable(session, name)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Message: "Table " + name + " truncated."}, nil
 	}
 
-	invoke := func() (string, error) {
-		diskio.UnlockTable(tableName)
+	return Operation{Assert: assert, Invoke: invoke}
+}
 
-		diskio.InsertRecord(tableName, values)
-		result := "1 new record inserted."
-		return result, nil
+//
+//			Helper functions
+//
+
+// compileCondition compiles a WHERE condition to test each record of a table against, every record passes without one.
+// Its subqueries are planned like those of a query, and can read the record's columns. They read the table as it was
+// before the statement, since it's only replaced once every record has been tested
+func compileCondition(session *diskio.Session, table string, columns []diskio.ColumnDef, where parser.Expr) (func(record []string) (bool, error), error) {
+	if where == nil {
+		return func(record []string) (bool, error) { return true, nil }, nil
 	}
 
-	return Operation{Assert: assert, Invoke: invoke}
+	where, err := newPlanner(session, false).resolve(where, qualifyColumns(table, columns))
+	if err != nil {
+		return nil, err
+	}
+
+	return executor.CompileFilter(where, qualifyColumns(table, columns))
 }
 
-func generateUpdate(statement tokenizer.Statement, inTransactionMode bool) Operation {
-	tableName := getFirstTokenOfName(statement, "TABLE_NAME")
-	whereCol := getSecondTokenOfName(statement, "COL_NAME")
-	whereValue := getSecondTokenOfName(statement, "COL_VALUE")
-	toCol := getFirstTokenOfName(statement, "COL_NAME")
-	toValue := getFirstTokenOfName(statement, "COL_VALUE")
+// compileAssignments compiles the SET of an UPDATE into a function from a record to its updated copy.
+// Every value is worked out from the record as it was, before any column of it is set, and its subqueries are planned like compileCondition's
+func compileAssignments(session *diskio.Session, table string, columns []diskio.ColumnDef, assignments []parser.Assignment) (func(record []string) ([]string, error), error) {
+	offsets, fields, err := compileFields(session, table, columns, assignments)
+	if err != nil {
+		return nil, err
+	}
 
-	whereValue = strings.Replace(whereValue, "'", "", -1)
-	toValue = strings.Replace(toValue, "'", "", -1)
+	return func(record []string) ([]string, error) {
+		updated := append([]string{}, record...)
+		for i, field := range fields {
+			value, err := field(record)
+			if err != nil {
+				return nil, err
+			}
+			updated[offsets[i]] = value
+		}
+		return updated, nil
+	}, nil
+}
 
-	assert := func() error {
-		if diskio.CheckIfTableIsLockedByOtherProcess(tableName) == true {
-			return errors.New("!Error: Table " + tableName + " is locked!")
-		} else {
-			diskio.LockTable(tableName)
+// compileFields compiles each value of a SET into a function from a record to the field it writes,
+// along with the offset of the column it's written to
+func compileFields(session *diskio.Session, table string, columns []diskio.ColumnDef, assignments []parser.Assignment) ([]int, []func(record []string) (string, error), error) {
+	planner := newPlanner(session, false)
+
+	offsets := make([]int, len(assignments))
+	fields := make([]func(record []string) (string, error), len(assignments))
+
+	for i, assignment := range assignments {
+		offsets[i] = -1
+		for j, column := range columns {
+			if strings.EqualFold(column.ColumnName, assignment.Column) {
+				offsets[i] = j
+			}
+		}
+		if offsets[i] < 0 {
+			return nil, nil, errors.New("!Failed to update table " + table + " because it has no column " + assignment.Column + ".")
 		}
 
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
-			return errors.New("!Failed to query table " + tableName + " because no database is in use.")
+		value, err := planner.resolve(assignment.Value, qualifyColumns(table, columns))
+		if err != nil {
+			return nil, nil, err
 		}
 
-		if diskio.CheckIfTableExists(tableName) == false {
-			return errors.New("!Failed to query table " + tableName + " because it does not exist.")
+		field, err := executor.CompileField(value, qualifyColumns(table, columns), columns[offsets[i]])
+		if err != nil {
+			return nil, nil, err
 		}
+		fields[i] = field
+	}
+
+	return offsets, fields, nil
+}
+
+// qualifyColumns names the table of each of its columns, so a condition can refer to them as table.column
+func qualifyColumns(table string, columns []diskio.ColumnDef) []diskio.ColumnDef {
+	qualified := make([]diskio.ColumnDef, len(columns))
+	for i, column := range columns {
+		qualified[i] = column
+		qualified[i].Table = table
+	}
+	return qualified
+}
+
+// insertRecords works out the records an INSERT writes, each written the way it's persisted.
+// The rows of a query are all read before any is written, so a table can be inserted into from itself
+func insertRecords(session *diskio.Session, insert *parser.Insert) ([][]string, error) {
+	columns, err := diskio.ReadColumnDefs(session, insert.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := insertTargets(insert.Table, columns, insert.Columns)
+	if err != nil {
+		return nil, err
+	}
 
-		if inTransactionMode {
+	var records [][]string
 
+	if insert.Query != nil {
+		plan, err := planInsertQuery(session, insert, targets)
+		if err != nil {
+			return nil, err
 		}
 
-		return nil
+		program, err := compileQuery(plan)
+		if err != nil {
+			return nil, err
+		}
+
+		rows := vm.New(session, program)
+		for {
+			row, err := rows.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			record := newRecord(len(columns))
+			for i, value := range row {
+				record[targets[i]], err = executor.ParseField(value, columns[targets[i]].TypeName)
+				if err != nil {
+					return nil, err
+				}
+			}
+			records = append(records, record)
+		}
+
+		return records, nil
 	}
 
-	invoke := func() (string, error) {
-		diskio.UnlockTable(tableName)
+	for _, row := range insert.Rows {
+		if len(row) != len(targets) {
+			return nil, insertWidthError(insert.Table, len(row), len(targets))
+		}
 
-		recordsModified := diskio.UpdateRecord(tableName, whereCol, whereValue, toCol, toValue)
-		result := strconv.Itoa(recordsModified)
-		result = result + " record(s) modified."
-		return result, nil
+		record := newRecord(len(columns))
+		for i, value := range row {
+			field, err := executor.CompileField(value, nil, columns[targets[i]])
+			if err != nil {
+				return nil, err
+			}
+
+			record[targets[i]], err = field(nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		records = append(records, record)
 	}
 
-	return Operation{Assert: assert, Invoke: invoke}
+	return records, nil
 }
 
-func generateDelete(statement tokenizer.Statement) Operation {
+// upsertRecords resolves the records of an INSERT that conflict on the columns its ON CONFLICT names, with a
+// record already in the table or one before them. DO NOTHING leaves them out, and DO UPDATE updates what they
+// conflict with instead, or replaces it for OR REPLACE. It returns the records left to insert, and how many
+// records of the table were updated
+func upsertRecords(session *diskio.Session, insert *parser.Insert, records [][]string) ([][]string, int, error) {
+	columns, err := diskio.ReadColumnDefs(session, insert.Table)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	table := getFirstTokenOfName(statement, "TABLE_NAME")
-	whereCol := getFirstTokenOfName(statement, "COL_NAME")
-	whereValue := getFirstTokenOfName(statement, "COL_VALUE")
-	comparator := statement.Tokens[5].Name
+	keyFields, err := insertTargets(insert.Table, columns, insert.OnConflict.Columns)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	whereValue = strings.Replace(whereValue, "'", "", -1)
+	var update func(record []string, excluded []string) ([]string, bool, error)
+	if insert.OnConflict.Replace {
+		update = func(record []string, excluded []string) ([]string, bool, error) {
+			return excluded, true, nil
+		}
+	} else if len(insert.OnConflict.Update) > 0 {
+		update, err = compileUpsert(session, insert.Table, columns, insert.OnConflict)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
 
-	assert := func() error {
-		if diskio.CheckIfAnyDatabaseIsInUse() == false {
-			return errors.New("!Failed to query table " + table + " because no database is in use.")
+	// the keys of the records already in the table
+	inTable := map[string]bool{}
+
+	reader, err := diskio.OpenTable(session, insert.Table)
+	if err != nil {
+		return nil, 0, err
+	}
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			reader.Close()
+			return nil, 0, err
 		}
 
-		if diskio.CheckIfTableExists(table) == false {
-			return errors.New("!Failed to query table " + table + " because it does not exist.")
+		if key, ok := executor.ConflictKey(record, keyFields, columns); ok {
+			inTable[key] = true
+		}
+	}
+	reader.Close()
+
+	// a row that conflicts with one before it in the statement updates it before it's inserted, and counts as an update too
+	var pending [][]string
+	updatedPending := 0
+	pendingAt := map[string]int{}
+	conflicts := map[string][][]string{}
+
+	for _, record := range records {
+		key, ok := executor.ConflictKey(record, keyFields, columns)
+		index, isPending := pendingAt[key]
+
+		switch {
+		case ok == false:
+			pending = append(pending, record)
+		case inTable[key]:
+			conflicts[key] = append(conflicts[key], record)
+		case isPending:
+			if update != nil {
+				next, applied, err := update(pending[index], record)
+				if err != nil {
+					return nil, 0, err
+				}
+				if applied {
+					pending[index] = next
+					updatedPending++
+				}
+			}
+		default:
+			pendingAt[key] = len(pending)
+			pending = append(pending, record)
 		}
+	}
 
-		return nil
+	if update == nil || len(conflicts) == 0 {
+		return pending, updatedPending, nil
 	}
 
-	invoke := func() (string, error) {
-		recordsDeleted := diskio.DeleteRecord(table, whereCol, whereValue, comparator)
-		result := strconv.Itoa(recordsDeleted)
-		result = result + " record(s) deleted."
-		return result, nil
+	// resolve applies each row that conflicts with a record to it in turn, and tells whether the condition let any of them.
+	// A record is only matched when one did, and it's resolved again when it's rewritten
+	resolve := func(record []string) ([]string, bool, error) {
+		key, ok := executor.ConflictKey(record, keyFields, columns)
+		if ok == false {
+			return record, false, nil
+		}
+
+		changed := false
+		for _, excluded := range conflicts[key] {
+			next, applied, err := update(record, excluded)
+			if err != nil {
+				return nil, false, err
+			}
+			if applied {
+				record, changed = next, true
+			}
+		}
+		return record, changed, nil
 	}
 
-	return Operation{Assert: assert, Invoke: invoke}
+	rows, err := diskio.MatchRecords(session, insert.Table, func(record []string) (bool, error) {
+		_, changed, err := resolve(record)
+		return changed, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	recordsUpdated, err := diskio.UpdateRecords(session, rows, func(record []string) ([]string, error) {
+		updated, _, err := resolve(record)
+		return updated, err
+	})
+	return pending, recordsUpdated + updatedPending, err
 }
 
-//
-//			Helper functions
-//
+// compileUpsert compiles the DO UPDATE of an ON CONFLICT into a function from a record and the row that conflicted
+// with it to the updated record, and whether its condition let it be updated. The row's columns follow the record's,
+// where excluded.column refers to them
+func compileUpsert(session *diskio.Session, table string, columns []diskio.ColumnDef, onConflict *parser.OnConflict) (func(record []string, excluded []string) ([]string, bool, error), error) {
+	both := append([]diskio.ColumnDef{}, columns...)
+	for _, column := range columns {
+		both = append(both, diskio.ColumnDef{ColumnName: "excluded." + column.ColumnName, TypeName: column.TypeName, Collation: column.Collation})
+	}
+
+	excluded := func(e parser.Expr) parser.Expr {
+		ref, ok := e.(*parser.ColumnRef)
+		if ok == false || strings.EqualFold(ref.Table, "excluded") == false {
+			return e
+		}
+
+		for i, column := range columns {
+			if strings.EqualFold(column.ColumnName, ref.Column) {
+				return &executor.ColumnAt{Index: len(columns) + i, Column: both[len(columns)+i]}
+			}
+		}
+		return e
+	}
+
+	var assignments []parser.Assignment
+	for _, assignment := range onConflict.Update {
+		assignments = append(assignments, parser.Assignment{Column: assignment.Column, Value: parser.RewriteExpr(assignment.Value, excluded)})
+	}
+
+	update, err := compileAssignments(session, table, both, assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := compileCondition(session, table, both, parser.RewriteExpr(onConflict.Where, excluded))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record []string, excludedRecord []string) ([]string, bool, error) {
+		row := append(append([]string{}, record...), excludedRecord...)
+
+		matched, err := match(row)
+		if err != nil || matched == false {
+			return record, false, err
+		}
+
+		updated, err := update(row)
+		if err != nil {
+			return nil, false, err
+		}
+		return updated[:len(record)], true, nil
+	}, nil
+}
+
+// insertTargets finds the column of the table each value of an INSERT's rows is written to,
+// which is every column in order when it doesn't name any
+func insertTargets(table string, columns []diskio.ColumnDef, names []string) ([]int, error) {
+	if len(names) == 0 {
+		targets := make([]int, len(columns))
+		for i := range targets {
+			targets[i] = i
+		}
+		return targets, nil
+	}
+
+	targets := make([]int, len(names))
+	named := map[int]bool{}
+
+	for i, name := range names {
+		targets[i] = -1
+		for j, column := range columns {
+			if strings.EqualFold(column.ColumnName, name) {
+				targets[i] = j
+			}
+		}
+
+		if targets[i] < 0 {
+			return nil, errors.New("!Failed to insert into table " + table + " because it has no column " + name + ".")
+		}
+		if named[targets[i]] {
+			return nil, errors.New("!Failed to insert into table " + table + " because column " + name + " is named twice.")
+		}
+		named[targets[i]] = true
+	}
+
+	return targets, nil
+}
+
+// planInsertQuery plans the query of an INSERT ... SELECT, checking it selects a value for each column the INSERT targets
+func planInsertQuery(session *diskio.Session, insert *parser.Insert, targets []int) (executor.Operator, error) {
+	plan, err := planSelect(session, insert.Query, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Columns()) != len(targets) {
+		return nil, insertWidthError(insert.Table, len(plan.Columns()), len(targets))
+	}
+
+	return plan, nil
+}
+
+// newRecord makes a record of NULLs, for the columns an INSERT doesn't name. sqlit has no column defaults yet
+func newRecord(width int) []string {
+	record := make([]string, width)
+	for i := range record {
+		record[i] = diskio.Null
+	}
+	return record
+}
+
+func insertWidthError(table string, values int, columns int) error {
+	return errors.New("!Failed to insert into table " + table + " because a row has " + strconv.Itoa(values) + " value(s) for " + strconv.Itoa(columns) + " column(s).")
+}
+
+// lockTableForWrite takes the exclusive lock a write needs, unless another session holds a lock on the table.
+// Assertions take it last, once everything else is checked, so one that fails never leaves its table locked
+func lockTableForWrite(session *diskio.Session, tableName string) error {
+	if diskio.CheckIfTableIsLockedByOtherSession(session, tableName) == true {
+		return errors.New("Error: Table " + tableName + " is locked!")
+	}
+	diskio.LockTable(session, tableName)
+
+	return nil
+}
+
+// lockTableForRead takes whatever lock a transaction's isolation level requires before reading a table
+func lockTableForRead(session *diskio.Session, tableName string) error {
+	switch session.TransactionIsolationLevel {
+	case diskio.RepeatableRead:
+		if diskio.CheckIfTableIsExclusivelyLockedByOtherSession(session, tableName) == true {
+			return errors.New("Error: Table " + tableName + " is locked!")
+		}
+		diskio.LockTableShared(session, tableName)
+	case diskio.Serializable:
+		if diskio.CheckIfTableIsLockedByOtherSession(session, tableName) == true {
+			return errors.New("Error: Table " + tableName + " is locked!")
+		}
+		diskio.LockTable(session, tableName)
+	}
+
+	return nil
+}
 
 func getAllTokensOfName(statement tokenizer.Statement, name string) []string {
 	var specials []string
@@ -537,6 +1137,33 @@ func getAllTokensOfName(statement tokenizer.Statement, name string) []string {
 	return specials
 }
 
+// checkCreateIndex makes sure a CREATE INDEX has all of its parts, in the order the parser labels them
+func checkCreateIndex(statement tokenizer.Statement) error {
+	usage := " Indexes are created with CREATE INDEX name ON table (column)."
+	tokens := statement.Tokens
+
+	if len(tokens) < 3 || strings.EqualFold(tokens[2].Special, "ON") {
+		return errors.New("!Failed to parse statement because CREATE INDEX needs a name." + usage)
+	}
+	if len(tokens) < 4 || strings.EqualFold(tokens[3].Special, "ON") == false {
+		return errors.New("!Failed to parse statement because ON was expected after CREATE INDEX " + tokens[2].Special + "." + usage)
+	}
+	if len(tokens) < 6 || indexColumn(statement) == "" {
+		return errors.New("!Failed to parse statement because CREATE INDEX " + tokens[2].Special + " needs a table and a column to index." + usage)
+	}
+
+	return nil
+}
+
+// indexColumn is the column a CREATE INDEX names after its table, however it's spaced within its parentheses
+func indexColumn(statement tokenizer.Statement) string {
+	column := ""
+	for _, token := range statement.Tokens[5:] {
+		column += token.Special
+	}
+	return strings.Trim(column, "() ")
+}
+
 func getFirstTokenOfName(statement tokenizer.Statement, name string) string {
 	for _, token := range statement.Tokens {
 		if token.Name == name {
@@ -561,53 +1188,3 @@ func getSecondTokenOfName(statement tokenizer.Statement, name string) string {
 
 	panic("Token " + name + " doesn't exist")
 }
-
-func getThirdTokenOfName(statement tokenizer.Statement, name string) string {
-	foundFirst := false
-	foundSecond := false
-	for _, token := range statement.Tokens {
-		if token.Name == name {
-			if foundFirst == true {
-				if foundSecond == true {
-
-					return token.Special
-				}
-
-				foundSecond = true
-			}
-			foundFirst = true
-		}
-	}
-
-	panic("Token " + name + " doesn't exist")
-}
-
-//	@in	    (pid int, | name varchar(20), | price float)
-//  @out		pid int, | name varchar(20), | price float
-func removeOuterParenthesis(columns []string, constraints []string) ([]string, []string) {
-	columns[0] = strings.Replace(columns[0], "(", "", 1)
-	constraints[len(constraints)-1] = strings.Replace(constraints[len(constraints)-1], ")", "", 1)
-
-	return columns, constraints
-}
-
-func removeOuterParenthesisFromValues(values []string) []string {
-	values[0] = strings.Replace(values[0], "(", "", 1)
-	values[len(values)-1] = strings.Replace(values[len(values)-1], ")", "", 1)
-
-	return values
-}
-
-func removeCommas(values []string) []string {
-	for i := 0; i < len(values); i++ {
-		values[i] = strings.Replace(values[i], ",", "", 1)
-	}
-	return values
-}
-
-func removeQuotes(values []string) []string {
-	for i := 0; i < len(values); i++ {
-		values[i] = strings.Replace(values[i], "'", "", -1)
-	}
-	return values
-}
@@ -0,0 +1,62 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package generator
+
+import (
+	"sqlit/executor"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"strings"
+	"time"
+)
+
+// nowAt makes a rewrite for RewriteExpr that replaces each NOW() with the TIMESTAMP it was at t,
+// written the way a stored TIMESTAMP is, so every row a statement reads or writes sees the same NOW()
+func nowAt(t time.Time) func(parser.Expr) parser.Expr {
+	value := executor.FormatValue(executor.Now(t))
+
+	return parser.RewriteSubqueries(func(e parser.Expr) parser.Expr {
+		call, ok := e.(*parser.FuncCall)
+		if ok == false || strings.EqualFold(call.Name, "NOW") == false || len(call.Args) > 0 || call.Over != nil {
+			return e
+		}
+		return &parser.CastExpr{Operand: &parser.Literal{Kind: tokenizer.String, Value: value}, TypeName: "timestamp"}
+	})
+}
+
+// queryAt makes a copy of a query with each NOW() taken at t. A result column without an alias
+// keeps the name it had, NOW() and not the timestamp it became
+func queryAt(query *parser.Select, t time.Time) *parser.Select {
+	at := query.Rewrite(nowAt(t))
+	keepColumnNames(query, at)
+	return at
+}
+
+//
+//			Helper functions
+//
+
+// keepColumnNames names each result column of a rewritten query that was named after its expression,
+// and whose expression the rewrite changed, after the expression it had. Derived tables,
+// common tables and compound SELECTs are named the same way
+func keepColumnNames(original *parser.Select, rewritten *parser.Select) {
+	for i, column := range original.Columns {
+		if column.Alias == "" && column.Expr.String() != rewritten.Columns[i].Expr.String() {
+			rewritten.Columns[i].Alias = column.Expr.String()
+		}
+	}
+
+	for i, table := range original.From {
+		if table.Subquery != nil {
+			keepColumnNames(table.Subquery, rewritten.From[i].Subquery)
+		}
+	}
+
+	for i, table := range original.With {
+		keepColumnNames(table.Query, rewritten.With[i].Query)
+	}
+
+	for i, compound := range original.Compound {
+		keepColumnNames(compound.Query, rewritten.Compound[i].Query)
+	}
+}
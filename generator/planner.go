@@ -0,0 +1,570 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package generator
+
+import (
+	"errors"
+	"math"
+	"sqlit/diskio"
+	"sqlit/executor"
+	"sqlit/parser"
+	"strings"
+)
+
+// The planner picks how to read each table of a query and what order to join them in,
+// by estimating the cost of each choice in records read. A full scan reads every record
+// of its table in order, an index scan reads a few index entries and then jumps to each
+// matching record, which costs randomReadCost sequential reads. Tables that have been
+// analyzed are estimated from their stats, anything else is guessed at
+const randomReadCost = 4
+
+// maxReorderedTables is the most tables whose join order is searched exhaustively,
+// past it tables are joined in the order they're written
+const maxReorderedTables = 10
+
+// guessedFieldBytes is how long a field is taken to be, to guess a table's rows from its size without stats
+const guessedFieldBytes = 8
+
+// a planner plans one query
+type planner struct {
+	session   *diskio.Session
+	profile   bool
+	stats     map[string]*diskio.TableStats
+	estimator *executor.Estimator
+
+	// parent is the query around a subquery, correlated is whether the subquery refers to it
+	parent     *scope
+	correlated bool
+
+	// with are the common tables in scope, by lowercase name
+	with map[string]*commonTable
+}
+
+// a relation is a table of the FROM clause, along with what the planner has worked out about it
+type relation struct {
+	ref     parser.TableRef
+	columns []diskio.ColumnDef
+	stats   *diskio.TableStats
+
+	// rows is how many records the table has, and filters are the parts of WHERE only about it
+	rows    float64
+	filters []parser.Expr
+
+	// derived reads a subquery or common table in FROM, rather than a table
+	derived executor.Operator
+
+	// access reads the table with its filters applied, cost is what that takes each time it's
+	// opened, and output is how many rows it's expected to come up with
+	access executor.Operator
+	cost   float64
+	output float64
+}
+
+// a joinPredicate is a part of WHERE or ON that relates tables, tables has a bit for each one
+type joinPredicate struct {
+	expr   parser.Expr
+	tables uint64
+	used   bool
+}
+
+// a joinOrder is the cheapest way found to join some set of tables
+type joinOrder struct {
+	order []int
+	cost  float64
+	rows  float64
+}
+
+func newPlanner(session *diskio.Session, profile bool) *planner {
+	return &planner{session: session, profile: profile, stats: diskio.ReadStats(session), estimator: executor.NewEstimator()}
+}
+
+// add finishes off each operator as it's planned, when profiling it's wrapped in an executor.Profile
+func (p *planner) add(operator executor.Operator, err error) (executor.Operator, error) {
+	if err != nil {
+		return nil, err
+	}
+	if p.profile {
+		return executor.NewProfile(operator), nil
+	}
+	return operator, nil
+}
+
+// planFrom plans the FROM and WHERE clauses of a query. The WHERE clause is split on AND,
+// and each part that's only about one table is pushed down to filter it as it's read,
+// possibly through an index. Without outer joins, the parts of ON are treated the same,
+// and the tables are joined in whichever order is cheapest. It also returns every
+// column of the FROM clause in the order written, which is what * stands for
+func (p *planner) planFrom(query *parser.Select) (executor.Operator, []diskio.ColumnDef, error) {
+	if query.Where != nil && parser.HasAggregate(query.Where) {
+		return nil, nil, errors.New("!Failed to query because aggregates can't be used in WHERE.")
+	}
+
+	if query.Where != nil && parser.HasWindow(query.Where) {
+		return nil, nil, errors.New("!Failed to query because window functions can't be used in WHERE.")
+	}
+
+	var relations []*relation
+	var columns []diskio.ColumnDef
+
+	outer := false
+	for _, ref := range query.From {
+		rel, err := p.newRelation(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		relations = append(relations, rel)
+		columns = append(columns, rel.columns...)
+		outer = outer || ref.Join == "LEFT"
+	}
+
+	where, err := p.resolve(query.Where, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, rel := range relations {
+		rel.ref.On, err = p.resolve(rel.ref.On, columns)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	conjuncts := splitConjuncts(where)
+	if outer == false {
+		for _, rel := range relations {
+			conjuncts = append(conjuncts, splitConjuncts(rel.ref.On)...)
+		}
+	}
+
+	// sort each part of the predicate into the table it filters, the tables it joins, or what's left over
+	var joins []*joinPredicate
+	var residual []parser.Expr
+
+	for _, conjunct := range conjuncts {
+		tables, ok := referencedRelations(conjunct, relations)
+
+		switch {
+		case ok == false || tables == 0:
+			residual = append(residual, conjunct)
+
+		case tables&(tables-1) == 0:
+			rel := relations[bitIndex(tables)]
+
+			// a left join's right side is padded with NULLs after it's filtered, so WHERE has to wait for it
+			if rel.ref.Join == "LEFT" {
+				residual = append(residual, conjunct)
+				continue
+			}
+			rel.filters = append(rel.filters, conjunct)
+
+		case outer:
+			residual = append(residual, conjunct)
+
+		default:
+			joins = append(joins, &joinPredicate{expr: conjunct, tables: tables})
+		}
+	}
+
+	for _, rel := range relations {
+		err := p.chooseAccess(rel)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	order := make([]int, len(relations))
+	for i := range order {
+		order[i] = i
+	}
+	if outer == false && len(relations) > 1 && len(relations) <= maxReorderedTables {
+		order = p.orderJoins(relations, joins)
+	}
+
+	plan := relations[order[0]].access
+	placed := uint64(1) << uint(order[0])
+
+	for _, i := range order[1:] {
+		rel := relations[i]
+		placed |= 1 << uint(i)
+
+		on := rel.ref.On
+		if outer == false {
+			on = nil
+			for _, join := range joins {
+				if join.used == false && join.tables&placed == join.tables {
+					on = conjoin(on, join.expr)
+					join.used = true
+				}
+			}
+		}
+
+		join, err := executor.NewNestedLoopJoin(plan, rel.access, on, rel.ref.Join == "LEFT")
+		if err != nil {
+			return nil, nil, err
+		}
+		if on != nil {
+			join.Selectivity = p.estimator.Selectivity(on)
+		}
+
+		plan, err = p.add(join, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(residual) > 0 {
+		var where parser.Expr
+		for _, conjunct := range residual {
+			where = conjoin(where, conjunct)
+		}
+
+		filter, err := executor.NewFilter(plan, where)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter.Selectivity = p.estimator.Selectivity(where)
+
+		plan, err = p.add(filter, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return plan, columns, nil
+}
+
+// estimateGroups tells an aggregate how many groups to expect, when every grouped expression is an analyzed column
+func (p *planner) estimateGroups(aggregate *executor.Aggregate, groupBy []parser.Expr, input float64) {
+	groups := 1.0
+
+	for _, expr := range groupBy {
+		distinct, ok := p.estimator.Distinct(expr)
+		if ok == false {
+			return
+		}
+		groups *= distinct
+	}
+
+	aggregate.Groups = math.Max(1, math.Min(groups, input))
+}
+
+// newRelation reads a table's columns and stats, and how many records it has.
+// A derived table, or a common table of WITH, is planned as a query of its own and materialized
+func (p *planner) newRelation(ref parser.TableRef) (*relation, error) {
+	if ref.Subquery != nil {
+		child := p.subplanner(p.parent)
+
+		plan, err := child.plan(ref.Subquery)
+		if err != nil {
+			return nil, err
+		}
+
+		return p.derivedRelation(ref, plan, child.correlated, nil)
+	}
+
+	if table := p.with[strings.ToLower(ref.Name)]; table != nil {
+		return p.commonRelation(ref, table)
+	}
+
+	columns, err := diskio.ReadColumnDefs(p.session, ref.Name)
+	if err != nil {
+		return nil, errors.New("!Failed to query table " + ref.Name + " because it does not exist.")
+	}
+
+	qualifier := ref.Name
+	if ref.Alias != "" {
+		qualifier = ref.Alias
+	}
+	for i := range columns {
+		columns[i].Table = qualifier
+	}
+
+	rel := &relation{ref: ref, columns: columns, stats: p.stats[ref.Name]}
+
+	if rel.stats != nil {
+		rel.rows = float64(rel.stats.Rows)
+	} else {
+		size, _ := diskio.TableSize(p.session, ref.Name)
+		rel.rows = math.Max(1, float64(size)/float64(guessedFieldBytes*len(columns)))
+	}
+
+	p.estimator.AddTable(columns, rel.stats)
+	return rel, nil
+}
+
+// derivedRelation materializes the plan of a query read in FROM
+func (p *planner) derivedRelation(ref parser.TableRef, plan executor.Operator, correlated bool, names []string) (*relation, error) {
+	alias := ref.Alias
+	if alias == "" {
+		alias = ref.Name
+	}
+
+	derived, err := executor.NewMaterialize(plan, alias, names)
+	if err != nil {
+		return nil, err
+	}
+
+	derived.Correlated = correlated
+	p.correlated = p.correlated || correlated
+
+	rel := &relation{ref: ref, columns: derived.Columns(), derived: derived, rows: math.Max(1, plan.EstimatedRows())}
+	p.estimator.AddTable(rel.columns, nil)
+	return rel, nil
+}
+
+// chooseAccess decides between a full scan of a table and an index scan on one of its filters,
+// whichever reads less, and filters the table by whatever the access path doesn't already cover
+func (p *planner) chooseAccess(rel *relation) error {
+	cost := rel.rows
+	var best *executor.IndexScan
+	chosen := -1
+
+	for i, filter := range rel.filters {
+		index, operator, key, ok := p.indexableFilter(rel, filter)
+		if ok == false {
+			continue
+		}
+
+		selectivity := p.estimator.Selectivity(filter)
+		indexCost := math.Log2(rel.rows+1) + selectivity*rel.rows*randomReadCost
+		if indexCost >= cost {
+			continue
+		}
+
+		scan, err := executor.NewIndexScan(p.session, index, rel.ref.Alias, operator, key)
+		if err != nil {
+			return err
+		}
+		scan.Rows = selectivity * rel.rows
+
+		best, chosen, cost = scan, i, indexCost
+	}
+
+	var access executor.Operator
+	var err error
+
+	if rel.derived != nil {
+		access, err = p.add(rel.derived, nil)
+	} else if best != nil {
+		access, err = p.add(best, nil)
+	} else {
+		scan, scanErr := executor.NewScan(p.session, rel.ref.Name, rel.ref.Alias)
+		if scanErr != nil {
+			return scanErr
+		}
+		if rel.stats != nil {
+			scan.Rows = rel.rows
+		}
+		access, err = p.add(scan, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	var remaining parser.Expr
+	selectivity := 1.0
+	for i, filter := range rel.filters {
+		selectivity *= p.estimator.Selectivity(filter)
+		if i != chosen {
+			remaining = conjoin(remaining, filter)
+		}
+	}
+
+	if remaining != nil {
+		filter, err := executor.NewFilter(access, remaining)
+		if err != nil {
+			return err
+		}
+		filter.Selectivity = p.estimator.Selectivity(remaining)
+
+		access, err = p.add(filter, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	rel.access = access
+	rel.cost = cost
+	rel.output = rel.rows * selectivity
+	return nil
+}
+
+// indexableFilter checks if a filter compares an indexed column to a constant, and if it
+// does returns the index and the comparison written with the column on the left
+func (p *planner) indexableFilter(rel *relation, filter parser.Expr) (diskio.IndexDef, string, parser.Expr, bool) {
+	comparison, ok := filter.(*parser.BinaryExpr)
+	if ok == false {
+		return diskio.IndexDef{}, "", nil, false
+	}
+
+	operator := comparison.Operator
+	column, key := comparison.Left, comparison.Right
+
+	if isConstant(column) {
+		column, key = key, column
+		operator = executor.FlipComparison(operator)
+	}
+
+	switch operator {
+	case "=", "<", "<=", ">", ">=":
+	default:
+		return diskio.IndexDef{}, "", nil, false
+	}
+
+	ref, ok := column.(*parser.ColumnRef)
+	if ok == false || isConstant(key) == false || rel.derived != nil {
+		return diskio.IndexDef{}, "", nil, false
+	}
+
+	// an index is sorted under its column's collation, so it can't answer a comparison under another
+	if _, collated := key.(*parser.CollateExpr); collated {
+		return diskio.IndexDef{}, "", nil, false
+	}
+
+	for _, index := range diskio.ReadIndexDefsOfTable(p.session, rel.ref.Name) {
+		if strings.EqualFold(index.Column, ref.Column) {
+			return index, operator, key, true
+		}
+	}
+
+	return diskio.IndexDef{}, "", nil, false
+}
+
+// orderJoins finds the cheapest order to join tables in, left-deep, searching every order
+// by dynamic programming over sets of tables. Joining a table onto the rows so far costs
+// an access of the table per row, since the right side of a nested loop is read again for each
+func (p *planner) orderJoins(relations []*relation, joins []*joinPredicate) []int {
+	best := map[uint64]*joinOrder{}
+
+	for i, rel := range relations {
+		best[1<<uint(i)] = &joinOrder{order: []int{i}, cost: rel.cost, rows: rel.output}
+	}
+
+	all := uint64(1)<<uint(len(relations)) - 1
+
+	for tables := uint64(1); tables < all; tables++ {
+		current, ok := best[tables]
+		if ok == false {
+			continue
+		}
+
+		for i, rel := range relations {
+			bit := uint64(1) << uint(i)
+			if tables&bit != 0 {
+				continue
+			}
+
+			rows := current.rows * rel.output
+			for _, join := range joins {
+				if join.tables&bit != 0 && join.tables&(tables|bit) == join.tables {
+					rows *= p.estimator.Selectivity(join.expr)
+				}
+			}
+
+			cost := current.cost + current.rows*rel.cost
+
+			if existing, ok := best[tables|bit]; ok == false || cost < existing.cost {
+				order := append(append([]int{}, current.order...), i)
+				best[tables|bit] = &joinOrder{order: order, cost: cost, rows: rows}
+			}
+		}
+	}
+
+	return best[all].order
+}
+
+//
+//			Helper functions
+//
+
+// splitConjuncts breaks a predicate into the parts ANDed together
+func splitConjuncts(expr parser.Expr) []parser.Expr {
+	if expr == nil {
+		return nil
+	}
+
+	if and, ok := expr.(*parser.BinaryExpr); ok && and.Operator == "AND" {
+		return append(splitConjuncts(and.Left), splitConjuncts(and.Right)...)
+	}
+
+	return []parser.Expr{expr}
+}
+
+// conjoin ANDs a predicate onto another, which may be nil
+func conjoin(expr parser.Expr, conjunct parser.Expr) parser.Expr {
+	if expr == nil {
+		return conjunct
+	}
+	return &parser.BinaryExpr{Operator: "AND", Left: expr, Right: conjunct}
+}
+
+// referencedRelations finds which tables an expression refers to, as a bit for each.
+// It's not ok if a column can't be pinned to exactly one table, in which case
+// the expression is left to where the error can be reported
+func referencedRelations(expr parser.Expr, relations []*relation) (uint64, bool) {
+	var tables uint64
+	ok := true
+
+	parser.WalkExpr(expr, func(e parser.Expr) {
+		switch x := e.(type) {
+		case *parser.ColumnRef:
+			found := -1
+			for i, rel := range relations {
+				for _, column := range rel.columns {
+					if strings.EqualFold(column.ColumnName, x.Column) && (x.Table == "" || strings.EqualFold(column.Table, x.Table)) {
+						if found != -1 && found != i {
+							ok = false
+						}
+						found = i
+					}
+				}
+			}
+			if found == -1 {
+				ok = false
+				return
+			}
+			tables |= 1 << uint(found)
+
+		// a subquery is left for last, when every column it might refer to is there
+		case *parser.FuncCall:
+			if isScalarCall(x) == false {
+				ok = false
+			}
+		case *parser.Star, *executor.Subquery:
+			ok = false
+		}
+	})
+
+	return tables, ok
+}
+
+// isConstant checks if an expression doesn't depend on any row, of this query or one around it
+func isConstant(expr parser.Expr) bool {
+	constant := true
+	parser.WalkExpr(expr, func(e parser.Expr) {
+		switch x := e.(type) {
+		case *parser.FuncCall:
+			if isScalarCall(x) == false {
+				constant = false
+			}
+		case *parser.ColumnRef, *parser.Star, *executor.Subquery, *executor.OuterRef:
+			constant = false
+		}
+	})
+	return constant
+}
+
+// isScalarCall checks a call is of a function of one row, not an aggregate or a window function
+func isScalarCall(call *parser.FuncCall) bool {
+	return call.Over == nil && parser.Aggregates[call.Name] == false && parser.WindowFunctions[call.Name] == false
+}
+
+func bitIndex(bit uint64) int {
+	index := 0
+	for bit > 1 {
+		bit >>= 1
+		index++
+	}
+	return index
+}
@@ -0,0 +1,528 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package generator
+
+import (
+	"sqlit/diskio"
+	"sqlit/executor"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"sqlit/vm"
+	"strconv"
+)
+
+// compiler turns a query's plan into a program. Scans, filters and nested loop joins become
+// loops over cursors with their predicates and the projection compiled to register operations.
+// Anything else, like a sort or an aggregate, is left to its operator and read through a
+// pipeline cursor, and so is anything with an expression the compiler doesn't know
+type compiler struct {
+	program *vm.Program
+
+	// the columns in scope, and the cursor and field each one is read from
+	columns []diskio.ColumnDef
+	slots   []slot
+}
+
+type slot struct {
+	cursor int
+	field  int
+}
+
+// compileQuery compiles a planned SELECT, which usually ends in a projection
+func compileQuery(plan executor.Operator) (*vm.Program, error) {
+	c := &compiler{program: &vm.Program{Columns: plan.Columns()}}
+	p := c.program
+
+	// anything else, like a compound query, is read through column by column
+	var exprs []parser.Expr
+	source := plan
+
+	project, ok := plan.(*executor.Project)
+	if ok && supports(project.Children()[0].Columns(), project.Exprs()...) {
+		exprs = project.Exprs()
+		source = project.Children()[0]
+	}
+
+	err := c.loop(source, func(skip int) error {
+		result := p.NewRegisters(len(plan.Columns()))
+		for i, column := range plan.Columns() {
+			if exprs == nil {
+				p.Add(vm.Column, c.slots[i].cursor, c.slots[i].field, result+i, nil, column.ColumnName)
+				continue
+			}
+
+			err := c.expr(exprs[i], result+i)
+			if err != nil {
+				return err
+			}
+		}
+
+		p.Add(vm.ResultRow, result, len(plan.Columns()), 0, nil, "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.Add(vm.Halt, 0, 0, 0, nil, "")
+	p.Finish()
+	return p, nil
+}
+
+// compileInsert compiles an INSERT of some records, all loaded into the same registers in turn
+func compileInsert(table string, records [][]string) *vm.Program {
+	p := &vm.Program{}
+
+	cursor := p.NewCursor()
+	p.Add(vm.OpenWrite, cursor, 0, 0, table, "")
+
+	width := 0
+	if len(records) > 0 {
+		width = len(records[0])
+	}
+
+	first := p.NewRegisters(width)
+	record := p.NewRegisters(1)
+
+	for _, values := range records {
+		for i, value := range values {
+			p.Add(vm.String, 0, first+i, 0, value, "")
+		}
+
+		p.Add(vm.MakeRecord, first, width, record, nil, "")
+		p.Add(vm.Insert, cursor, record, 0, nil, "")
+	}
+	p.Add(vm.Halt, 0, 0, 0, nil, "")
+
+	p.Finish()
+	return p
+}
+
+// compileUpdate compiles an UPDATE into a loop over its table through a write cursor. Each value
+// of its SET is left to the executor, which checks it the way a column is written
+func compileUpdate(session *diskio.Session, update *parser.Update) (*vm.Program, error) {
+	columns, err := diskio.ReadColumnDefs(session, update.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, fields, err := compileFields(session, update.Table, columns, update.Assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{program: &vm.Program{}}
+	p := c.program
+
+	err = c.rewrite(session, update.Table, columns, update.Where, func(cursor int) {
+		first := p.NewRegisters(len(fields))
+		for i, field := range fields {
+			field := field
+			evaluate := func(row []string) (executor.Value, error) {
+				return field(row)
+			}
+			p.Add(vm.Function, cursor, first+i, 0, &vm.Expression{Expr: update.Assignments[i].Value.String(), Evaluate: evaluate}, update.Assignments[i].Column)
+		}
+
+		p.Add(vm.Update, cursor, first, 0, offsets, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// compileDelete compiles a DELETE into a loop over its table through a write cursor
+func compileDelete(session *diskio.Session, del *parser.Delete) (*vm.Program, error) {
+	columns, err := diskio.ReadColumnDefs(session, del.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{program: &vm.Program{}}
+	p := c.program
+
+	err = c.rewrite(session, del.Table, columns, del.Where, func(cursor int) {
+		p.Add(vm.Delete, cursor, 0, 0, nil, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// rewrite compiles a loop over every record of a table through a write cursor, which runs body for each
+// record the condition matches, and then halts. The condition is compiled like a query's filter if the
+// compiler knows it, and is left to the executor otherwise. Its subqueries are planned like compileCondition's
+func (c *compiler) rewrite(session *diskio.Session, table string, columns []diskio.ColumnDef, where parser.Expr, body func(cursor int)) error {
+	p := c.program
+
+	cursor := p.NewCursor()
+	p.Add(vm.OpenWrite, cursor, 0, 0, table, "")
+
+	for i, column := range qualifyColumns(table, columns) {
+		c.columns = append(c.columns, column)
+		c.slots = append(c.slots, slot{cursor: cursor, field: i})
+	}
+
+	done, next := p.NewLabel(), p.NewLabel()
+	p.Add(vm.Rewind, cursor, done, 0, nil, "")
+	top := p.Address()
+
+	if where != nil {
+		where, err := newPlanner(session, false).resolve(where, c.columns)
+		if err != nil {
+			return err
+		}
+
+		if supports(c.columns, where) {
+			err = c.test(where, next)
+		} else {
+			err = c.filter(where, cursor, next)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	body(cursor)
+
+	p.Place(next)
+	p.Add(vm.Next, cursor, top, 0, nil, "")
+	p.Place(done)
+	p.Add(vm.Halt, 0, 0, 0, nil, "")
+
+	p.Finish()
+	return nil
+}
+
+// filter compiles a predicate the executor evaluates against the row of a cursor, which jumps to skip unless it's true
+func (c *compiler) filter(predicate parser.Expr, cursor int, skip int) error {
+	match, err := executor.CompileFilter(predicate, c.columns)
+	if err != nil {
+		return err
+	}
+
+	evaluate := func(row []string) (executor.Value, error) {
+		matched, err := match(row)
+		if matched {
+			return int64(1), err
+		}
+		return int64(0), err
+	}
+
+	register := c.program.NewRegisters(1)
+	c.program.Add(vm.Function, cursor, register, 0, &vm.Expression{Expr: predicate.String(), Evaluate: evaluate}, "")
+	c.program.Add(vm.IfNot, register, skip, 0, nil, predicate.String())
+	return nil
+}
+
+// loop compiles an operator into code that runs body once per row, with the row's columns in
+// scope. body jumps to skip to move on to the next row
+func (c *compiler) loop(op executor.Operator, body func(skip int) error) error {
+	switch o := op.(type) {
+	case *executor.Filter:
+		if supports(o.Columns(), o.Predicate()) == false {
+			break
+		}
+
+		return c.loop(o.Children()[0], func(skip int) error {
+			err := c.test(o.Predicate(), skip)
+			if err != nil {
+				return err
+			}
+			return body(skip)
+		})
+
+	case *executor.NestedLoopJoin:
+		if supports(o.Columns(), o.On()) == false || (o.Outer() && singleTable(o.Children()[1]) == false) {
+			break
+		}
+
+		if o.Outer() {
+			return c.leftJoin(o, body)
+		}
+
+		return c.loop(o.Children()[0], func(int) error {
+			return c.loop(o.Children()[1], func(skip int) error {
+				err := c.test(o.On(), skip)
+				if err != nil {
+					return err
+				}
+				return body(skip)
+			})
+		})
+	}
+
+	return c.scan(op, body)
+}
+
+// scan compiles a loop over a cursor, reading a table directly or the rows of any other operator
+func (c *compiler) scan(op executor.Operator, body func(skip int) error) error {
+	p := c.program
+
+	opcode := vm.OpenPipeline
+	switch op.(type) {
+	case *executor.Scan, *executor.IndexScan:
+		opcode = vm.OpenRead
+	}
+
+	cursor := p.NewCursor()
+	p.Add(opcode, cursor, 0, 0, op, "")
+
+	for i, column := range op.Columns() {
+		c.columns = append(c.columns, column)
+		c.slots = append(c.slots, slot{cursor: cursor, field: i})
+	}
+
+	done, next := p.NewLabel(), p.NewLabel()
+	p.Add(vm.Rewind, cursor, done, 0, nil, "")
+	top := p.Address()
+
+	err := body(next)
+	if err != nil {
+		return err
+	}
+
+	p.Place(next)
+	p.Add(vm.Next, cursor, top, 0, nil, "")
+	p.Place(done)
+	return nil
+}
+
+// leftJoin compiles a left join. Once a left row's loop over the right table is done, if nothing
+// matched, the right cursor is nulled out and the body is run once more for the padded row
+func (c *compiler) leftJoin(join *executor.NestedLoopJoin, body func(skip int) error) error {
+	p := c.program
+
+	return c.loop(join.Children()[0], func(skip int) error {
+		matched := p.NewRegisters(1)
+		p.Add(vm.Integer, 0, matched, 0, nil, "no match yet")
+
+		first := p.Cursors
+		joined := p.NewLabel()
+
+		err := c.loop(join.Children()[1], func(next int) error {
+			if join.On() != nil {
+				err := c.test(join.On(), next)
+				if err != nil {
+					return err
+				}
+			}
+
+			p.Add(vm.Integer, 1, matched, 0, nil, "")
+			p.Place(joined)
+			return body(next)
+		})
+		if err != nil {
+			return err
+		}
+
+		// the right cursor has run out, and stays out, so the body's jump to
+		// the next row comes back around to here with a match
+		p.Add(vm.If, matched, skip, 0, nil, "")
+		p.Add(vm.Integer, 1, matched, 0, nil, "")
+		for cursor := first; cursor < p.Cursors; cursor++ {
+			p.Add(vm.NullRow, cursor, 0, 0, nil, "")
+		}
+		p.Add(vm.Goto, 0, joined, 0, nil, "pad with NULLs")
+		return nil
+	})
+}
+
+// test compiles a predicate that jumps to skip unless it's true
+func (c *compiler) test(predicate parser.Expr, skip int) error {
+	if predicate == nil {
+		return nil
+	}
+
+	register := c.program.NewRegisters(1)
+	err := c.expr(predicate, register)
+	if err != nil {
+		return err
+	}
+
+	c.program.Add(vm.IfNot, register, skip, 0, nil, predicate.String())
+	return nil
+}
+
+// expr compiles an expression that puts its value in a register. It mirrors how the
+// executor compiles expressions, so the two agree on what every expression means
+func (c *compiler) expr(expr parser.Expr, target int) error {
+	p := c.program
+
+	// an expression an operator below already computed is read from the column named after it
+	switch expr.(type) {
+	case *parser.ColumnRef, *parser.Literal:
+	default:
+		for i, column := range c.columns {
+			if column.Table == "" && column.ColumnName == expr.String() {
+				p.Add(vm.Column, c.slots[i].cursor, c.slots[i].field, target, nil, column.ColumnName)
+				return nil
+			}
+		}
+	}
+
+	switch e := expr.(type) {
+	case *parser.ColumnRef:
+		index, err := executor.ResolveColumn(e, c.columns)
+		if err != nil {
+			return err
+		}
+		p.Add(vm.Column, c.slots[index].cursor, c.slots[index].field, target, nil, e.String())
+
+	case *parser.Literal:
+		c.literal(e, target)
+
+	case *parser.UnaryExpr:
+		operand := p.NewRegisters(1)
+		err := c.expr(e.Operand, operand)
+		if err != nil {
+			return err
+		}
+
+		if e.Operator == "NOT" {
+			p.Add(vm.Not, operand, target, 0, nil, "")
+		} else {
+			p.Add(vm.Negative, operand, target, 0, nil, "")
+		}
+
+	case *parser.IsNullExpr:
+		operand := p.NewRegisters(1)
+		err := c.expr(e.Operand, operand)
+		if err != nil {
+			return err
+		}
+
+		if e.Not {
+			p.Add(vm.NotNull, operand, target, 0, nil, "")
+		} else {
+			p.Add(vm.IsNull, operand, target, 0, nil, "")
+		}
+
+	case *parser.BinaryExpr:
+		operands := p.NewRegisters(2)
+		err := c.expr(e.Left, operands)
+		if err != nil {
+			return err
+		}
+		err = c.expr(e.Right, operands+1)
+		if err != nil {
+			return err
+		}
+
+		opcode, p4 := binaryOpcode(e.Operator)
+		p.Add(opcode, operands, operands+1, target, p4, "")
+	}
+
+	return nil
+}
+
+// literal loads a constant, typed the way the executor types it
+func (c *compiler) literal(literal *parser.Literal, target int) {
+	p := c.program
+
+	switch literal.Kind {
+	case "NULL":
+		p.Add(vm.Null, 0, target, 0, nil, "")
+		return
+	case tokenizer.Number:
+		if i, err := strconv.ParseInt(literal.Value, 10, 64); err == nil && int64(int(i)) == i {
+			p.Add(vm.Integer, int(i), target, 0, nil, "")
+			return
+		}
+		if f, err := strconv.ParseFloat(literal.Value, 64); err == nil {
+			p.Add(vm.Real, 0, target, 0, f, "")
+			return
+		}
+	}
+
+	p.Add(vm.String, 0, target, 0, literal.Value, "")
+}
+
+//
+//			Helper functions
+//
+
+// supports checks if the compiler knows every kind of expression in some expressions. Any other
+// kind is fine if an operator below has already computed it, like an aggregate
+func supports(columns []diskio.ColumnDef, exprs ...parser.Expr) bool {
+	for _, expr := range exprs {
+		switch e := expr.(type) {
+		case nil, *parser.Literal:
+			continue
+		case *parser.ColumnRef:
+			// the virtual machine compares text ignoring case, a column with another collation is left to the operators
+			if index, err := executor.ResolveColumn(e, columns); err != nil || columns[index].Collation == "" {
+				continue
+			}
+			return false
+		}
+
+		computed := false
+		for _, column := range columns {
+			if column.Table == "" && column.ColumnName == expr.String() {
+				computed = true
+			}
+		}
+		if computed {
+			continue
+		}
+
+		switch e := expr.(type) {
+		case *parser.UnaryExpr:
+			if supports(columns, e.Operand) {
+				continue
+			}
+		case *parser.IsNullExpr:
+			if supports(columns, e.Operand) {
+				continue
+			}
+		case *parser.BinaryExpr:
+			if opcode, _ := binaryOpcode(e.Operator); opcode != vm.Halt && supports(columns, e.Left, e.Right) {
+				continue
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// binaryOpcode finds the opcode of a binary operator, Halt if there isn't one
+func binaryOpcode(operator string) (vm.Opcode, interface{}) {
+	switch operator {
+	case "+":
+		return vm.Add, nil
+	case "-":
+		return vm.Subtract, nil
+	case "*":
+		return vm.Multiply, nil
+	case "/":
+		return vm.Divide, nil
+	case "%":
+		return vm.Remainder, nil
+	case "||":
+		return vm.Concat, nil
+	case "AND":
+		return vm.And, nil
+	case "OR":
+		return vm.Or, nil
+	case "=", "!=", "<", ">", "<=", ">=":
+		return vm.Compare, operator
+	}
+	return vm.Halt, nil
+}
+
+// singleTable checks if an operator reads one table, which is all the right side of a left join can be
+func singleTable(op executor.Operator) bool {
+	switch o := op.(type) {
+	case *executor.Scan, *executor.IndexScan:
+		return true
+	case *executor.Filter:
+		return singleTable(o.Children()[0])
+	}
+	return false
+}
@@ -0,0 +1,533 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package generator
+
+import (
+	"errors"
+	"sqlit/diskio"
+	"sqlit/executor"
+	"sqlit/parser"
+	"sqlit/tokenizer"
+	"sqlit/vm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generateQuery plans a SELECT into a pipeline of executor operators, and compiles that into
+// a program. Invoke hands a vm running the program back as the result's rows, so nothing
+// is read until the caller steps through them. They're planned on a snapshot of the session,
+// so they're read from the database in use when the query ran whatever the session does next
+func generateQuery(session *diskio.Session, statement tokenizer.Statement) Operation {
+	query, err := parser.ParseSelect(statement.Raw)
+	return queryOperation(session, query, err)
+}
+
+func queryOperation(session *diskio.Session, query *parser.Select, parseErr error) Operation {
+	program := func() (*vm.Program, error) {
+		plan, err := planSelect(session, query, false)
+		if err != nil {
+			return nil, err
+		}
+		return compileQuery(plan)
+	}
+
+	invoke := func() (Result, error) {
+		snapshot := session.Snapshot()
+
+		plan, err := planSelect(snapshot, queryAt(query, time.Now()), false)
+		if err != nil {
+			return Result{}, err
+		}
+
+		program, err := compileQuery(plan)
+		if err != nil {
+			return Result{}, err
+		}
+
+		return Result{ColumnDefs: program.Columns, Rows: vm.New(snapshot, program)}, nil
+	}
+
+	operation := Operation{Assert: assertQuery(session, query, parseErr), Invoke: invoke, Program: program}
+
+	if query != nil {
+		operation.Parameters = query.Parameters
+		operation.Bind = func(args []*parser.Literal) Operation {
+			return queryOperation(session, query.Bind(args), nil)
+		}
+	}
+
+	return operation
+}
+
+// generateExplain plans a query without running it, and lays the plan out as rows.
+// EXPLAIN ANALYZE runs the query too, profiling every operator along the way
+func generateExplain(session *diskio.Session, statement tokenizer.Statement) Operation {
+	explain, err := parser.ParseExplain(statement.Raw)
+	return explainOperation(session, explain, err)
+}
+
+func explainOperation(session *diskio.Session, explain *parser.Explain, parseErr error) Operation {
+	var query *parser.Select
+	if parseErr == nil {
+		query = explain.Query
+	}
+
+	invoke := func() (Result, error) {
+		analyzed := query
+		if explain.Analyze {
+			analyzed = queryAt(query, time.Now())
+		}
+
+		plan, err := planSelect(session, analyzed, explain.Analyze)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if explain.Analyze {
+			err = executor.Analyze(plan)
+			if err != nil {
+				return Result{}, err
+			}
+		}
+
+		var records [][]string
+		for _, line := range executor.Explain(plan) {
+			records = append(records, []string{line})
+		}
+
+		set := diskio.Set{Name: "plan", ColumnDefs: []diskio.ColumnDef{{ColumnName: "plan", TypeName: "varchar"}}, Records: records}
+
+		return Result{ColumnDefs: set.ColumnDefs, Rows: newSetIterator(set)}, nil
+	}
+
+	operation := Operation{Assert: assertQuery(session, query, parseErr), Invoke: invoke}
+
+	if query != nil {
+		operation.Parameters = query.Parameters
+		operation.Bind = func(args []*parser.Literal) Operation {
+			return explainOperation(session, &parser.Explain{Analyze: explain.Analyze, Query: query.Bind(args)}, nil)
+		}
+	}
+
+	return operation
+}
+
+// assertQuery checks a query's tables exist, those of its subqueries included, and locks them for reading inside a transaction
+func assertQuery(session *diskio.Session, query *parser.Select, parseErr error) func() error {
+	return func() error {
+		if parseErr != nil {
+			return parseErr
+		}
+
+		return assertTables(session, query.Tables())
+	}
+}
+
+// assertTables checks the tables a statement reads exist, and locks them for reading inside a transaction
+func assertTables(session *diskio.Session, tables []string) error {
+	for _, table := range tables {
+		if diskio.CheckIfAnyDatabaseIsInUse(session) == false {
+			return errors.New("!Failed to query table " + table + " because no database is in use.")
+		}
+
+		if diskio.CheckIfTableExists(session, table) == false {
+			return errors.New("!Failed to query table " + table + " because it does not exist.")
+		}
+	}
+
+	if session.InTransactionMode {
+		for _, table := range tables {
+			if err := lockTableForRead(session, table); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// planSelect builds the operators of a query from the bottom up: the planner's choice of
+// scans and joins with WHERE pushed into them, then grouping, HAVING, ORDER BY, and the projection.
+// When profiling, every operator is wrapped in an executor.Profile
+func planSelect(session *diskio.Session, query *parser.Select, profile bool) (executor.Operator, error) {
+	return newPlanner(session, profile).plan(query)
+}
+
+func (p *planner) plan(query *parser.Select) (executor.Operator, error) {
+	if len(query.With) > 0 {
+		p.bindWith(query)
+
+		body := *query
+		body.With = nil
+		query = &body
+	}
+
+	if len(query.Compound) > 0 {
+		return p.planCompound(query)
+	}
+
+	add := p.add
+
+	plan, fromColumns, err := p.planFrom(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// the columns that will be projected, with stars expanded
+	exprs, aliases, err := expandResultColumns(query.Columns, fromColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range exprs {
+		exprs[i], err = p.resolve(exprs[i], fromColumns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// ORDER BY can name a result column by its alias or position
+	var orderBy []parser.OrderingTerm
+	for _, term := range query.OrderBy {
+		expr, err := resolveOrderingExpr(term.Expr, exprs, aliases)
+		if err != nil {
+			return nil, err
+		}
+
+		expr, err = p.resolve(expr, fromColumns)
+		if err != nil {
+			return nil, err
+		}
+		orderBy = append(orderBy, parser.OrderingTerm{Expr: expr, Descending: term.Descending})
+	}
+
+	var groupBy []parser.Expr
+	for _, expr := range query.GroupBy {
+		expr, err = p.resolve(expr, fromColumns)
+		if err != nil {
+			return nil, err
+		}
+		groupBy = append(groupBy, expr)
+	}
+
+	having, err := p.resolve(query.Having, fromColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, expr := range append(groupBy, having) {
+		if expr != nil && parser.HasWindow(expr) {
+			return nil, errors.New("!Failed to query because window functions can't be used in GROUP BY or HAVING.")
+		}
+	}
+
+	// group, which first needs rows of the same group next to each other
+	var everything []parser.Expr
+	everything = append(everything, exprs...)
+	everything = append(everything, having)
+	for _, term := range orderBy {
+		everything = append(everything, term.Expr)
+	}
+	aggregates := collectAggregates(everything)
+
+	// the order rows are already in, which saves sorting them again
+	var ordered []parser.OrderingTerm
+
+	if len(groupBy) > 0 || len(aggregates) > 0 {
+		if len(groupBy) > 0 {
+			var groupOrder []parser.OrderingTerm
+			for _, expr := range groupBy {
+				groupOrder = append(groupOrder, parser.OrderingTerm{Expr: expr})
+			}
+			ordered = groupOrder
+
+			plan, err = add(executor.NewSort(plan, groupOrder))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		aggregate, err := executor.NewAggregate(plan, groupBy, aggregates)
+		if err != nil {
+			return nil, err
+		}
+		if len(groupBy) > 0 {
+			p.estimateGroups(aggregate, groupBy, plan.EstimatedRows())
+		}
+
+		plan, err = add(aggregate, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if having != nil {
+		plan, err = add(executor.NewFilter(plan, having))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// each window sorts rows into its partitions, in order, and adds its functions' columns
+	var windowed []parser.Expr
+	windowed = append(windowed, exprs...)
+	for _, term := range orderBy {
+		windowed = append(windowed, term.Expr)
+	}
+
+	for _, calls := range collectWindows(windowed) {
+		window := calls[0].Over
+
+		var windowOrder []parser.OrderingTerm
+		for _, expr := range window.PartitionBy {
+			windowOrder = append(windowOrder, parser.OrderingTerm{Expr: expr})
+		}
+		windowOrder = append(windowOrder, window.OrderBy...)
+
+		if len(windowOrder) > 0 && satisfiesOrder(ordered, windowOrder) == false {
+			plan, err = add(executor.NewSort(plan, windowOrder))
+			if err != nil {
+				return nil, err
+			}
+			ordered = windowOrder
+		}
+
+		plan, err = add(executor.NewWindow(plan, window, calls))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(orderBy) > 0 && query.Distinct == false && satisfiesOrder(ordered, orderBy) == false {
+		plan, err = add(executor.NewSort(plan, orderBy))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err = add(executor.NewProject(plan, exprs, aliases))
+	if err != nil || query.Distinct == false {
+		return plan, err
+	}
+
+	plan, err = add(executor.NewDistinct(plan), nil)
+	if err != nil || len(orderBy) == 0 {
+		return plan, err
+	}
+
+	// a distinct row might stand for rows that sort differently, so SELECT DISTINCT sorts
+	// last, and only by its result columns
+	var distinctOrder []parser.OrderingTerm
+	for _, term := range orderBy {
+		found := false
+		for i, expr := range exprs {
+			if strings.EqualFold(expr.String(), term.Expr.String()) {
+				column := &executor.ColumnAt{Index: i, Column: plan.Columns()[i]}
+				distinctOrder = append(distinctOrder, parser.OrderingTerm{Expr: column, Descending: term.Descending})
+				found = true
+				break
+			}
+		}
+
+		if found == false {
+			return nil, errors.New("!Failed to query because ORDER BY " + term.Expr.String() + " has to be a result column of SELECT DISTINCT.")
+		}
+	}
+
+	return add(executor.NewSort(plan, distinctOrder))
+}
+
+// planCompound plans each SELECT of a compound query on its own, and combines them left to right.
+// ORDER BY then sorts the combined rows, naming their columns by name or position
+func (p *planner) planCompound(query *parser.Select) (executor.Operator, error) {
+	first := *query
+	first.Compound = nil
+	first.OrderBy = nil
+
+	plan, err := p.planNested(&first)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, compound := range query.Compound {
+		right, err := p.planNested(compound.Query)
+		if err != nil {
+			return nil, err
+		}
+
+		// anything but UNION ALL merges its queries sorted
+		if compound.Operator != "UNION" || compound.All == false {
+			collations := executor.SetCollations(plan.Columns(), right.Columns())
+
+			plan, err = p.add(executor.NewRowSort(plan, collations))
+			if err != nil {
+				return nil, err
+			}
+
+			right, err = p.add(executor.NewRowSort(right, collations))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		plan, err = p.add(executor.NewSetOperation(plan, right, compound.Operator, compound.All))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(query.OrderBy) == 0 {
+		return plan, nil
+	}
+
+	var exprs []parser.Expr
+	var names []string
+	for _, column := range plan.Columns() {
+		exprs = append(exprs, &parser.ColumnRef{Column: column.ColumnName})
+		names = append(names, column.ColumnName)
+	}
+
+	var orderBy []parser.OrderingTerm
+	for _, term := range query.OrderBy {
+		expr, err := resolveOrderingExpr(term.Expr, exprs, names)
+		if err != nil {
+			return nil, err
+		}
+		orderBy = append(orderBy, parser.OrderingTerm{Expr: expr, Descending: term.Descending})
+	}
+
+	return p.add(executor.NewSort(plan, orderBy))
+}
+
+// planNested plans a query on its own but in the same scope as this one, like a derived table
+func (p *planner) planNested(query *parser.Select) (executor.Operator, error) {
+	child := p.subplanner(p.parent)
+
+	plan, err := child.plan(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p.correlated = p.correlated || child.correlated
+	return plan, nil
+}
+
+//
+//			Helper functions
+//
+
+// expandResultColumns replaces * and table.* with a reference to each column they cover
+func expandResultColumns(columns []parser.ResultColumn, available []diskio.ColumnDef) ([]parser.Expr, []string, error) {
+	var exprs []parser.Expr
+	var aliases []string
+
+	for _, column := range columns {
+		star, ok := column.Expr.(*parser.Star)
+		if ok == false {
+			exprs = append(exprs, column.Expr)
+			aliases = append(aliases, column.Alias)
+			continue
+		}
+
+		found := false
+		for _, columnDef := range available {
+			if star.Table != "" && strings.EqualFold(star.Table, columnDef.Table) == false {
+				continue
+			}
+
+			exprs = append(exprs, &parser.ColumnRef{Table: columnDef.Table, Column: columnDef.ColumnName})
+			aliases = append(aliases, "")
+			found = true
+		}
+
+		if found == false {
+			return nil, nil, errors.New("!Failed to query because table " + star.Table + " does not exist.")
+		}
+	}
+
+	return exprs, aliases, nil
+}
+
+// resolveOrderingExpr swaps a result column's alias or position for the expression it names
+func resolveOrderingExpr(expr parser.Expr, exprs []parser.Expr, aliases []string) (parser.Expr, error) {
+	if literal, ok := expr.(*parser.Literal); ok && literal.Kind == tokenizer.Number {
+		position, err := strconv.Atoi(literal.Value)
+		if err != nil || position < 1 || position > len(exprs) {
+			return nil, errors.New("!Failed to query because ORDER BY " + literal.Value + " is not a result column.")
+		}
+		return exprs[position-1], nil
+	}
+
+	if ref, ok := expr.(*parser.ColumnRef); ok && ref.Table == "" {
+		for i, alias := range aliases {
+			if alias != "" && strings.EqualFold(alias, ref.Column) {
+				return exprs[i], nil
+			}
+		}
+	}
+
+	return expr, nil
+}
+
+// satisfiesOrder checks if rows in one order are already in another, that is if it's a prefix
+func satisfiesOrder(ordered []parser.OrderingTerm, wanted []parser.OrderingTerm) bool {
+	if len(wanted) > len(ordered) {
+		return false
+	}
+
+	for i := range wanted {
+		if wanted[i].Descending != ordered[i].Descending || strings.EqualFold(wanted[i].Expr.String(), ordered[i].Expr.String()) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collectAggregates finds each distinct aggregate call in a list of expressions
+func collectAggregates(exprs []parser.Expr) []*parser.FuncCall {
+	var aggregates []*parser.FuncCall
+	seen := map[string]bool{}
+
+	for _, expr := range exprs {
+		parser.WalkExpr(expr, func(e parser.Expr) {
+			call, ok := e.(*parser.FuncCall)
+			if ok == false || call.Over != nil || parser.Aggregates[call.Name] == false || seen[call.String()] {
+				return
+			}
+
+			seen[call.String()] = true
+			aggregates = append(aggregates, call)
+		})
+	}
+
+	return aggregates
+}
+
+// collectWindows finds each distinct window function call in a list of expressions,
+// grouped by the window they're over, in the order the windows first show up
+func collectWindows(exprs []parser.Expr) [][]*parser.FuncCall {
+	var windows [][]*parser.FuncCall
+	index := map[string]int{}
+	seen := map[string]bool{}
+
+	for _, expr := range exprs {
+		parser.WalkExpr(expr, func(e parser.Expr) {
+			call, ok := e.(*parser.FuncCall)
+			if ok == false || call.Over == nil || seen[call.String()] {
+				return
+			}
+			seen[call.String()] = true
+
+			over := call.Over.String()
+			if _, ok := index[over]; ok == false {
+				index[over] = len(windows)
+				windows = append(windows, nil)
+			}
+			windows[index[over]] = append(windows[index[over]], call)
+		})
+	}
+
+	return windows
+}
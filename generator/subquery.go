@@ -0,0 +1,96 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package generator
+
+import (
+	"sqlit/diskio"
+	"sqlit/executor"
+	"sqlit/parser"
+	"strings"
+)
+
+// A scope is a query a subquery is nested in, columns are what the subquery can refer to
+// and outer is where the row they're read from is kept while the subquery runs
+type scope struct {
+	columns []diskio.ColumnDef
+	outer   *executor.Outer
+	parent  *scope
+	planner *planner
+}
+
+// subplanner makes a planner for a subquery, sharing this one's session and stats
+func (p *planner) subplanner(parent *scope) *planner {
+	return &planner{session: p.session, profile: p.profile, stats: p.stats, estimator: executor.NewEstimator(), parent: parent, with: p.with}
+}
+
+// resolve plans the subqueries of an expression, and swaps any column that isn't one of the
+// query's own for a reference to the query around it, which makes the query correlated
+func (p *planner) resolve(expr parser.Expr, columns []diskio.ColumnDef) (parser.Expr, error) {
+	var err error
+
+	resolved := parser.RewriteExpr(expr, func(e parser.Expr) parser.Expr {
+		if err != nil {
+			return e
+		}
+
+		switch x := e.(type) {
+		case *parser.ColumnRef:
+			if _, ok := findColumn(columns, x); ok {
+				return e
+			}
+
+			for s := p.parent; s != nil; s = s.parent {
+				column, ok := findColumn(s.columns, x)
+				if ok == false {
+					continue
+				}
+
+				// every query between here and the one the column belongs to has to run again for each of its rows
+				p.correlated = true
+				for t := p.parent; t != s; t = t.parent {
+					t.planner.correlated = true
+				}
+
+				return &executor.OuterRef{Ref: x, TypeName: column.TypeName, Outer: s.outer}
+			}
+
+		case *parser.SubqueryExpr:
+			outer := executor.NewOuter()
+			child := p.subplanner(&scope{columns: columns, outer: outer, parent: p.parent, planner: p})
+
+			var plan executor.Operator
+			plan, err = child.plan(x.Query)
+			if err != nil {
+				return e
+			}
+
+			var subquery *executor.Subquery
+			subquery, err = executor.NewSubquery(x, x.Operand, plan, outer)
+			if err != nil {
+				return e
+			}
+
+			subquery.Correlated = child.correlated
+			return subquery
+		}
+
+		return e
+	})
+
+	return resolved, err
+}
+
+//
+//			Helper functions
+//
+
+// findColumn finds the column a reference names, if there is one
+func findColumn(columns []diskio.ColumnDef, ref *parser.ColumnRef) (diskio.ColumnDef, bool) {
+	for _, column := range columns {
+		if strings.EqualFold(column.ColumnName, ref.Column) && (ref.Table == "" || strings.EqualFold(column.Table, ref.Table)) {
+			return column, true
+		}
+	}
+
+	return diskio.ColumnDef{}, false
+}
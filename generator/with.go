@@ -0,0 +1,151 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package generator
+
+import (
+	"errors"
+	"sqlit/executor"
+	"sqlit/parser"
+	"strings"
+)
+
+// A commonTable is a query named by WITH, along with the common tables it can read in turn.
+// Each reference to it plans it again, so it can be read in more than one place at once.
+// While the step of a recursive one is being planned, reading it reads step's working set
+type commonTable struct {
+	table     parser.CommonTable
+	with      map[string]*commonTable
+	recursive bool
+	step      *executor.Recursive
+}
+
+// bindWith adds a query's common tables to those in scope, each can read the ones
+// before it, and a recursive one can read itself too
+func (p *planner) bindWith(query *parser.Select) {
+	with := map[string]*commonTable{}
+	for name, table := range p.with {
+		with[name] = table
+	}
+
+	for _, table := range query.With {
+		common := &commonTable{table: table, recursive: query.Recursive, with: copyWith(with)}
+		with[strings.ToLower(table.Name)] = common
+
+		if query.Recursive {
+			common.with[strings.ToLower(table.Name)] = common
+		}
+	}
+
+	p.with = with
+}
+
+// commonRelation plans a common table read in FROM
+func (p *planner) commonRelation(ref parser.TableRef, table *commonTable) (*relation, error) {
+	if table.step != nil {
+		alias := ref.Alias
+		if alias == "" {
+			alias = ref.Name
+		}
+
+		working := table.step.WorkingTable(alias)
+		rel := &relation{ref: ref, columns: working.Columns(), derived: working, rows: working.EstimatedRows()}
+		p.estimator.AddTable(rel.columns, nil)
+		return rel, nil
+	}
+
+	child := p.subplanner(p.parent)
+	child.with = table.with
+
+	if table.recursive && readsTable(table.table.Query, table.table.Name) {
+		plan, err := child.planRecursive(table)
+		if err != nil {
+			return nil, err
+		}
+		return p.derivedRelation(ref, plan, child.correlated, nil)
+	}
+
+	plan, err := child.plan(table.table.Query)
+	if err != nil {
+		return nil, err
+	}
+	return p.derivedRelation(ref, plan, child.correlated, table.table.Columns)
+}
+
+// planRecursive plans a recursive common table, which has to be an anchor that doesn't read
+// the table combined by UNION [ALL] with a step that does
+func (p *planner) planRecursive(table *commonTable) (executor.Operator, error) {
+	name := table.table.Name
+	query := table.table.Query
+	last := len(query.Compound) - 1
+
+	shapeErr := errors.New("!Failed to query because recursive " + name + " has to be a query that doesn't read " + name +
+		", UNION [ALL] a query that does.")
+
+	if last < 0 || query.Compound[last].Operator != "UNION" || readsTable(query.Compound[last].Query, name) == false {
+		return nil, shapeErr
+	}
+
+	anchorQuery := *query
+	anchorQuery.Compound = query.Compound[:last]
+	anchorQuery.OrderBy = nil
+
+	if readsTable(&anchorQuery, name) {
+		return nil, shapeErr
+	}
+	if len(query.OrderBy) > 0 {
+		return nil, errors.New("!Failed to query because recursive " + name + " can't have ORDER BY.")
+	}
+
+	anchor, err := p.plan(&anchorQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	recursive, err := executor.NewRecursive(name, anchor, query.Compound[last].All, table.table.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	// the step reads the rows from the last round wherever it reads the table
+	self := *table
+	self.step = recursive
+
+	stepPlanner := p.subplanner(p.parent)
+	stepPlanner.with = copyWith(table.with)
+	stepPlanner.with[strings.ToLower(name)] = &self
+
+	step, err := stepPlanner.plan(query.Compound[last].Query)
+	if err != nil {
+		return nil, err
+	}
+	p.correlated = p.correlated || stepPlanner.correlated
+
+	err = recursive.SetStep(step)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.add(recursive, nil)
+}
+
+//
+//			Helper functions
+//
+
+func copyWith(with map[string]*commonTable) map[string]*commonTable {
+	copied := map[string]*commonTable{}
+	for name, table := range with {
+		copied[name] = table
+	}
+	return copied
+}
+
+// readsTable checks if a query reads a table, in any of its parts
+func readsTable(query *parser.Select, name string) bool {
+	for _, table := range query.Tables() {
+		if strings.EqualFold(table, name) {
+			return true
+		}
+	}
+	return false
+}
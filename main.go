@@ -1,20 +1,18 @@
 /* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
 
-// Package main is the program's core, providing a REPL
-// for query input (launchConsole()) as well the a main
-// loop which transforms queries down to disk operations (processLine()).
+// Package main is the program's shell, providing a REPL
+// for query input (launchConsole()) which hands each statement
+// to an engine session and prints what it did (processLine()).
 package main
 
 import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"sqlit/diskio"
-	"sqlit/generator"
-	"sqlit/parser"
-	"sqlit/tokenizer"
+	"sqlit/engine"
 	"strings"
 )
 
@@ -23,10 +21,6 @@ var cleanPtr *bool
 // DebugPtr toggles global printing of debugging statements
 var DebugPtr *bool
 
-var inTransactionMode bool
-
-var transactionStack []generator.Operation
-
 const (
 	InfoColor    = "\033[1;34m%s\033[0m"
 	NoticeColor  = "\033[1;36m%s\033[0m"
@@ -36,8 +30,6 @@ const (
 )
 
 func main() {
-	inTransactionMode = false
-
 	createTmpDirectory()
 
 	cleanPtr = flag.Bool("clean", false, "deletes all previously created databases in sqlit/tmp")
@@ -56,6 +48,10 @@ func main() {
 // launchConsole loops for standard input, does some basic
 // filtering to allow for multi line statements
 func launchConsole() {
+	db, err := engine.Open("tmp")
+	check(err)
+	db.Debug = *DebugPtr
+
 	reader := bufio.NewReader(os.Stdin)
 	partialStatementBuffer := make([]string, 0, 1000)
 
@@ -63,20 +59,18 @@ func launchConsole() {
 
 	fmt.Println("🔥")
 
-	lineNumber := 0
-
 	for {
-		lineNumber++
-		if lineNumber > 31 {
-			fmt.Println("All done.")
-			os.Exit(0)
-		}
-
 		if lastLineWasEmpty == false {
 			fmt.Printf(WarningColor, "sqlit> ")
 		}
 
-		line, _ := reader.ReadString('\n')
+		line, err := reader.ReadString('\n')
+
+		// a piped script may end without an .exit, however long it is
+		if err == io.EOF && len(line) == 0 {
+			fmt.Println("All done.")
+			os.Exit(0)
+		}
 
 		line = removeComment(line)
 		line = removeTrailingSpaces(line)
@@ -105,123 +99,57 @@ func launchConsole() {
 			statement := strings.Join(partialStatementBuffer, " ") + line
 
 			if len(statement) > 1 {
-				processLine(statement)
+				processLine(db, statement)
 				partialStatementBuffer = partialStatementBuffer[:0]
 			}
 		}
 	}
 }
 
-// processLine goes through all the main functionality by transforming input into operations
-func processLine(line string) {
+// processLine runs a statement on the session, printing what it did
+func processLine(db *engine.DB, line string) {
+	fmt.Println(line)
 
-	// Break our line of input up into tokens
-	statement := tokenizer.TokenizeStatement(line)
+	result, err := db.Exec(line)
 
-	if *DebugPtr {
-		tokenizer.PrintStatement(statement)
-	}
-
-	// Give them some syntactical meaning
-	statement = parser.ParseStatement(statement)
-
-	// interpert transaction mode entry, break if entering
-	if statement.Type == "BEGIN" {
-		inTransactionMode = true
-		fmt.Printf(DebugColor, "Transaction starts.")
+	if result.Rows != nil {
+		fmt.Printf(DebugColor, formatRows(result.Rows))
+		fmt.Println()
+	} else if len(result.Message) > 0 {
+		fmt.Printf(DebugColor, result.Message)
 		fmt.Println()
-		return
-	}
-
-	// interpert transaction commit
-	if statement.Type == "COMMIT" {
-
-		// 1. assert that all operations in the transaction stack are valid
-		//	(including table lock checks)
-		//	return on error
-		for _, operation := range transactionStack {
-			err := operation.Assert()
-
-			if err != nil {
-				fmt.Printf(ErrorColor, err)
-				fmt.Println()
-				fmt.Printf(ErrorColor, "Transaction abort.")
-				fmt.Println()
-
-				// empty out our transaction stack
-				transactionStack = []generator.Operation{}
-
-				// unlock all associated resources
-				diskio.UnlockTable("flights")
-
-				return
-			}
-		}
-
-		// 2. invoke all operations in transaction
-		for _, operation := range transactionStack {
-			success, err := operation.Invoke()
-			if err != nil {
-				fmt.Printf(ErrorColor, err)
-				fmt.Println()
-			} else {
-				fmt.Printf(DebugColor, success)
-				fmt.Println()
-			}
-		}
-
-		// empty out our transaction stack
-		transactionStack = []generator.Operation{}
-
-		// unlock all associated resources
-		diskio.UnlockTable("flights")
-
-		// exit transaction mode
-		inTransactionMode = false
-		return
-	}
-
-	if *DebugPtr {
-		tokenizer.PrintStatement(statement)
 	}
 
-	// Generate a function of assertions and a function of operations for our query
-	operation := generator.Generate(statement, inTransactionMode)
-
-	// Make sure our query is valid before we request resources
-	err := operation.Assert()
 	if err != nil {
 		fmt.Printf(ErrorColor, err)
 		fmt.Println()
+	}
+}
 
-		if inTransactionMode {
-			fmt.Printf(ErrorColor, "Transaction abort.")
-			fmt.Println()
-		}
+//
+//			Helper functions
+//
+
+// formatRows lays out rows the same way a table is persisted, a header of column defs followed by pipe delimited records
+func formatRows(rows *engine.Rows) string {
+	defer rows.Close()
 
-		return
+	var header []string
+	for _, column := range rows.Columns() {
+		header = append(header, column.Name+" "+column.Type)
 	}
 
-	// if we're in transaction mode, and assertions pass, we store the operation on the transaction stack rather then executing it immediately
-	if inTransactionMode {
-		transactionStack = append(transactionStack, operation)
-		return
+	lines := []string{strings.Join(header, "|")}
+	for rows.Next() {
+		lines = append(lines, strings.Join(rows.Strings(), "|"))
 	}
 
-	// Finally, execute our query
-	success, err := operation.Invoke()
-	if err != nil {
-		fmt.Printf(ErrorColor, err)
-		fmt.Println()
-	} else {
-		fmt.Printf(DebugColor, success)
-		fmt.Println()
+	if rows.Err() != nil {
+		lines = append(lines, rows.Err().Error())
 	}
-}
 
-//
-//			Helper functions
-//
+	return strings.Join(lines, "\n")
+}
 
 func createTmpDirectory() {
 	_, err := os.Stat("tmp/")
@@ -0,0 +1,125 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"sqlit/tokenizer"
+	"strings"
+)
+
+// CreateTable is the parse tree of CREATE TABLE table (column type [COLLATE collation], ...)
+type CreateTable struct {
+	Table   string
+	Columns []ColumnSpec
+}
+
+// ColumnSpec is a column of a CREATE TABLE. TypeName is written the way it was declared,
+// like varchar(20), and Collation is empty when none is given
+type ColumnSpec struct {
+	Name      string
+	TypeName  string
+	Collation string
+}
+
+// ParseCreateTable parses the raw SQL of a CREATE TABLE statement
+func ParseCreateTable(raw string) (*CreateTable, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	create := &CreateTable{}
+
+	err = p.expectKeyword("CREATE")
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("TABLE")
+	if err != nil {
+		return nil, err
+	}
+
+	create.Table, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		column, err := p.parseColumnSpec()
+		if err != nil {
+			return nil, err
+		}
+		create.Columns = append(create.Columns, column)
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	err = p.expectSymbol(")")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	return create, nil
+}
+
+//
+//			Helper functions
+//
+
+// @in		name type [( size {, size} )] [COLLATE collation]
+func (p *queryParser) parseColumnSpec() (ColumnSpec, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return ColumnSpec{}, err
+	}
+
+	typeName, err := p.parseName()
+	if err != nil {
+		return ColumnSpec{}, err
+	}
+
+	if p.acceptSymbol("(") {
+		var sizes []string
+		for {
+			if p.peek().Name != tokenizer.Number {
+				return ColumnSpec{}, p.unexpected()
+			}
+			sizes = append(sizes, p.next().Special)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return ColumnSpec{}, err
+		}
+		typeName += "(" + strings.Join(sizes, ",") + ")"
+	}
+
+	column := ColumnSpec{Name: name, TypeName: typeName}
+
+	if p.acceptKeyword("COLLATE") {
+		collation, err := p.parseName()
+		if err != nil {
+			return ColumnSpec{}, err
+		}
+		column.Collation = strings.ToUpper(collation)
+	}
+
+	return column, nil
+}
@@ -0,0 +1,77 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"sqlit/tokenizer"
+)
+
+// Delete is the parse tree of DELETE FROM table [WHERE condition]. Where is nil without one,
+// which deletes every record
+type Delete struct {
+	Table string
+	Where Expr
+
+	// Parameters is how many parameters the statement takes when it's prepared
+	Parameters int
+}
+
+// ParseDelete parses the raw SQL of a DELETE statement
+func ParseDelete(raw string) (*Delete, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	del := &Delete{}
+
+	err = p.expectKeyword("DELETE")
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("FROM")
+	if err != nil {
+		return nil, err
+	}
+
+	del.Table, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("WHERE") {
+		del.Where, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	del.Parameters = p.parameters
+	return del, nil
+}
+
+// Bind makes a copy of a delete with each parameter replaced by the value bound to it,
+// including the parameters of its subqueries
+func (del *Delete) Bind(args []*Literal) *Delete {
+	bound := del.Rewrite(bindArgs(args))
+	bound.Parameters = 0
+	return bound
+}
+
+// Rewrite makes a copy of a delete with its condition rewritten by RewriteExpr
+func (del *Delete) Rewrite(rewrite func(Expr) Expr) *Delete {
+	rewritten := *del
+	rewritten.Where = RewriteExpr(del.Where, rewrite)
+	return &rewritten
+}
+
+// Tables lists every table the subqueries of a delete's condition read
+func (del *Delete) Tables() []string {
+	return SubqueryTables(del.Where)
+}
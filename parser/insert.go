@@ -0,0 +1,269 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"errors"
+	"sqlit/tokenizer"
+)
+
+// Insert is the parse tree of INSERT [OR REPLACE | OR IGNORE] INTO table [(column {, column})] VALUES (value {, value}) {, (...)},
+// or of INSERT ... INTO table [(column {, column})] query, either followed by an optional ON CONFLICT. Columns is empty
+// when none are named, then each row gives every column of the table in order. A row is either in Rows, or is one of Query's
+type Insert struct {
+	Table   string
+	Columns []string
+	Rows    [][]Expr
+	Query   *Select
+
+	// OnConflict is what's done with a row that conflicts with a record, nil without ON CONFLICT
+	OnConflict *OnConflict
+
+	// Parameters is how many parameters the statement takes when it's prepared
+	Parameters int
+}
+
+// OnConflict is the parse tree of ON CONFLICT (column {, column}) DO NOTHING, or of
+// ON CONFLICT (column {, column}) DO UPDATE SET column = value {, column = value} [WHERE condition].
+// Update is empty for DO NOTHING. Its values and condition read the record in the table, and the row
+// that conflicted with it as excluded.column. INSERT OR IGNORE and INSERT OR REPLACE have no Columns,
+// tables don't have a key so a row conflicts with a record that has all the same values
+type OnConflict struct {
+	Columns []string
+	Update  []Assignment
+	Where   Expr
+
+	// Replace replaces the whole record a row conflicts with by the row, for INSERT OR REPLACE
+	Replace bool
+}
+
+// ParseInsert parses the raw SQL of an INSERT statement
+func ParseInsert(raw string) (*Insert, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	insert := &Insert{}
+
+	err = p.expectKeyword("INSERT")
+	if err != nil {
+		return nil, err
+	}
+
+	// OR REPLACE and OR IGNORE are ON CONFLICT over every column
+	if p.acceptKeyword("OR") {
+		switch {
+		case p.acceptKeyword("REPLACE"):
+			insert.OnConflict = &OnConflict{Replace: true}
+		case p.acceptKeyword("IGNORE"):
+			insert.OnConflict = &OnConflict{}
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	err = p.expectKeyword("INTO")
+	if err != nil {
+		return nil, err
+	}
+
+	insert.Table, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptSymbol("(") {
+		insert.Columns, err = p.parseColumnNames()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isQueryAt(0) {
+		insert.Query, err = p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = p.expectKeyword("VALUES")
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			row, err := p.parseRow()
+			if err != nil {
+				return nil, err
+			}
+			insert.Rows = append(insert.Rows, row)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("ON") {
+		if insert.OnConflict != nil {
+			return nil, errors.New("!Failed to parse query because INSERT OR REPLACE and INSERT OR IGNORE can't have an ON CONFLICT too.")
+		}
+		insert.OnConflict, err = p.parseOnConflict()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	insert.Parameters = p.parameters
+	return insert, nil
+}
+
+// Bind makes a copy of an insert with each parameter replaced by the value bound to it,
+// including the parameters of subqueries
+func (insert *Insert) Bind(args []*Literal) *Insert {
+	bound := insert.Rewrite(bindArgs(args))
+	if bound.Query != nil {
+		bound.Query.Parameters = 0
+	}
+	bound.Parameters = 0
+	return bound
+}
+
+// Rewrite makes a copy of an insert with its values, query and conflict clause rewritten by RewriteExpr
+func (insert *Insert) Rewrite(rewrite func(Expr) Expr) *Insert {
+	rewritten := *insert
+	rewritten.Rows = nil
+	for _, row := range insert.Rows {
+		var values []Expr
+		for _, value := range row {
+			values = append(values, RewriteExpr(value, rewrite))
+		}
+		rewritten.Rows = append(rewritten.Rows, values)
+	}
+	if insert.Query != nil {
+		rewritten.Query = insert.Query.Rewrite(rewrite)
+	}
+	if insert.OnConflict != nil {
+		onConflict := &OnConflict{Columns: insert.OnConflict.Columns, Where: RewriteExpr(insert.OnConflict.Where, rewrite), Replace: insert.OnConflict.Replace}
+		for _, assignment := range insert.OnConflict.Update {
+			onConflict.Update = append(onConflict.Update, Assignment{Column: assignment.Column, Value: RewriteExpr(assignment.Value, rewrite)})
+		}
+		rewritten.OnConflict = onConflict
+	}
+	return &rewritten
+}
+
+//
+//			Helper functions
+//
+
+// @in		(value {, value})
+func (p *queryParser) parseRow() ([]Expr, error) {
+	err := p.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+
+	var row []Expr
+	for {
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, value)
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	err = p.expectSymbol(")")
+	if err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// @in		column {, column} )
+func (p *queryParser) parseColumnNames() ([]string, error) {
+	var columns []string
+	for {
+		column, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	return columns, p.expectSymbol(")")
+}
+
+// @in		CONFLICT (column {, column}) DO NOTHING
+// @in		CONFLICT (column {, column}) DO UPDATE SET column = value {, column = value} [WHERE condition]
+func (p *queryParser) parseOnConflict() (*OnConflict, error) {
+	err := p.expectKeyword("CONFLICT")
+	if err != nil {
+		return nil, err
+	}
+
+	// without a key, the columns a row conflicts on have to be named
+	if p.acceptSymbol("(") == false {
+		return nil, errors.New("!Failed to parse query because ON CONFLICT has to name the columns that conflict.")
+	}
+
+	onConflict := &OnConflict{}
+	onConflict.Columns, err = p.parseColumnNames()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("DO")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("NOTHING") {
+		return onConflict, nil
+	}
+
+	err = p.expectKeyword("UPDATE")
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("SET")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		column, value, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		onConflict.Update = append(onConflict.Update, Assignment{Column: column, Value: value})
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	if p.acceptKeyword("WHERE") {
+		onConflict.Where, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return onConflict, nil
+}
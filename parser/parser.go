@@ -11,48 +11,53 @@ import (
 )
 
 var names = map[string]string{
-	"CREATE":   "CREATE",
-	"DROP":     "DROP",
-	"USE":      "USE",
-	"DATABASE": "DATABASE",
-	"TABLE":    "TABLE",
-	"INSERT":   "INSERT",
-	"ALTER":    "ALTER",
-	"SELECT":   "SELECT",
-	"DELETE":   "DELETE",
-	"UPDATE":   "UPDATE",
-	"LITERAL":  "LITERAL",
-	"BEGIN":    "BEGIN",
-	"COMMIT":   "COMMIT",
-	"special":  "special",
+	"CREATE":    "CREATE",
+	"DROP":      "DROP",
+	"USE":       "USE",
+	"DATABASE":  "DATABASE",
+	"TABLE":     "TABLE",
+	"INSERT":    "INSERT",
+	"ALTER":     "ALTER",
+	"SELECT":    "SELECT",
+	"DELETE":    "DELETE",
+	"UPDATE":    "UPDATE",
+	"LITERAL":   "LITERAL",
+	"BEGIN":     "BEGIN",
+	"COMMIT":    "COMMIT",
+	"ROLLBACK":  "ROLLBACK",
+	"SAVEPOINT": "SAVEPOINT",
+	"RELEASE":   "RELEASE",
+	"special":   "special",
 }
 
 var specialNames = map[string]string{
-	"DATABASE_NAME": "DATABASE_NAME",
-	"TABLE_NAME":    "TABLE_NAME",
-	"COL_NAME":      "COL_NAME",
-	"COL_TYPE":      "COL_TYPE",
-	"ADD_COL":       "ADD_COL",
-	"ALL":           "ALL",
-	"FROM":          "FROM",
-	"VALUE":         "VALUE",
-	"SET":           "SET",
-	"EQUALS":        "EQUALS",
-	"NOT_EQUALS":    "NOT_EQUALS",
-	"GREATER_THAN":  "GREATER_THAN",
-	"COL_VALUE":     "COL_VALUE",
-	"WHERE":         "WHERE",
-	"INTO":          "INTO",
-	"VALUES":        "VALUES",
-	"SET_NAME":      "SET_NAME",
-	"INNER":         "INNER",
-	"JOIN":          "JOIN",
-	"ON":            "ON",
-	"LEFT":          "LEFT",
-	"OUTER":         "OUTER",
-	"SET_COL_NAME":  "SET_COL_NAME",
-	"BEGIN":         "BEGIN",
-	"COMMIT":        "COMMIT",
+	"DATABASE_NAME":   "DATABASE_NAME",
+	"TABLE_NAME":      "TABLE_NAME",
+	"COL_NAME":        "COL_NAME",
+	"COL_TYPE":        "COL_TYPE",
+	"ADD_COL":         "ADD_COL",
+	"FROM":            "FROM",
+	"VALUE":           "VALUE",
+	"SET":             "SET",
+	"EQUALS":          "EQUALS",
+	"GREATER_THAN":    "GREATER_THAN",
+	"COL_VALUE":       "COL_VALUE",
+	"WHERE":           "WHERE",
+	"INTO":            "INTO",
+	"VALUES":          "VALUES",
+	"BEGIN":           "BEGIN",
+	"COMMIT":          "COMMIT",
+	"TRANSACTION":     "TRANSACTION",
+	"ISOLATION":       "ISOLATION",
+	"LEVEL":           "LEVEL",
+	"ISOLATION_LEVEL": "ISOLATION_LEVEL",
+	"TO":              "TO",
+	"SAVEPOINT":       "SAVEPOINT",
+	"SAVEPOINT_NAME":  "SAVEPOINT_NAME",
+	"INDEX":           "INDEX",
+	"INDEX_NAME":      "INDEX_NAME",
+	"ON":              "ON",
+	"STATEMENT_NAME":  "STATEMENT_NAME",
 }
 
 // Types are general classes for statements
@@ -65,13 +70,24 @@ var Types = map[string]string{
 	"ALTER_TABLE":     "ALTER_TABLE",
 	"INSERT":          "INSERT",
 	"SELECT":          "SELECT",
-	"SELECT_INNER":    "SELECT_INNER",
-	"SELECT_LEFT":     "SELECT_LEFT",
 	"SELECT_MULTIPLE": "SELECT_MULTIPLE",
 	"UPDATE":          "UPDATE",
 	"DELETE":          "DELETE",
+	"TRUNCATE":        "TRUNCATE",
 	"BEGIN":           "BEGIN",
 	"COMMIT":          "COMMIT",
+	"SET_TRANSACTION": "SET_TRANSACTION",
+	"SAVEPOINT":       "SAVEPOINT",
+	"RELEASE":         "RELEASE",
+	"ROLLBACK":        "ROLLBACK",
+	"ROLLBACK_TO":     "ROLLBACK_TO",
+	"EXPLAIN":         "EXPLAIN",
+	"CREATE_INDEX":    "CREATE_INDEX",
+	"DROP_INDEX":      "DROP_INDEX",
+	"ANALYZE":         "ANALYZE",
+	"PREPARE":         "PREPARE",
+	"EXECUTE":         "EXECUTE",
+	"DEALLOCATE":      "DEALLOCATE",
 }
 
 // ParseStatement ....
@@ -102,10 +118,53 @@ func inferStatementType(statement tokenizer.Statement) tokenizer.Statement {
 		return statement
 	}
 
+	if statement.Tokens[0].Name == names["ROLLBACK"] {
+		statement.Type = Types["ROLLBACK"]
+		if len(statement.Tokens) > 1 && strings.EqualFold(statement.Tokens[1].Special, "TO") {
+			statement.Type = Types["ROLLBACK_TO"]
+		}
+		return statement
+	}
+
+	if strings.EqualFold(statement.Tokens[0].Special, "ANALYZE") {
+		statement.Type = Types["ANALYZE"]
+		return statement
+	}
+
 	if len(statement.Tokens) < 2 {
 		return statement
 	}
 
+	if strings.EqualFold(statement.Tokens[0].Special, "PREPARE") {
+		statement.Type = Types["PREPARE"]
+		return statement
+	}
+
+	if strings.EqualFold(statement.Tokens[0].Special, "EXECUTE") {
+		statement.Type = Types["EXECUTE"]
+		return statement
+	}
+
+	if strings.EqualFold(statement.Tokens[0].Special, "DEALLOCATE") {
+		statement.Type = Types["DEALLOCATE"]
+		return statement
+	}
+
+	if statement.Tokens[0].Name == names["SAVEPOINT"] {
+		statement.Type = Types["SAVEPOINT"]
+		return statement
+	}
+
+	if statement.Tokens[0].Name == names["RELEASE"] {
+		statement.Type = Types["RELEASE"]
+		return statement
+	}
+
+	if strings.EqualFold(statement.Tokens[0].Special, "SET") && strings.EqualFold(statement.Tokens[1].Special, "TRANSACTION") {
+		statement.Type = Types["SET_TRANSACTION"]
+		return statement
+	}
+
 	if statement.Tokens[0].Name == names["CREATE"] && statement.Tokens[1].Name == names["DATABASE"] {
 		statement.Type = Types["CREATE_DATABASE"]
 		return statement
@@ -131,6 +190,16 @@ func inferStatementType(statement tokenizer.Statement) tokenizer.Statement {
 		return statement
 	}
 
+	if statement.Tokens[0].Name == names["CREATE"] && strings.EqualFold(statement.Tokens[1].Special, "INDEX") {
+		statement.Type = Types["CREATE_INDEX"]
+		return statement
+	}
+
+	if statement.Tokens[0].Name == names["DROP"] && strings.EqualFold(statement.Tokens[1].Special, "INDEX") {
+		statement.Type = Types["DROP_INDEX"]
+		return statement
+	}
+
 	if statement.Tokens[0].Name == names["ALTER"] && statement.Tokens[1].Name == names["TABLE"] {
 		statement.Type = Types["ALTER_TABLE"]
 		return statement
@@ -141,7 +210,13 @@ func inferStatementType(statement tokenizer.Statement) tokenizer.Statement {
 		return statement
 	}
 
-	if statement.Tokens[0].Name == names["SELECT"] {
+	if strings.EqualFold(statement.Tokens[0].Special, "EXPLAIN") {
+		statement.Type = Types["EXPLAIN"]
+		return statement
+	}
+
+	// a query can start by naming common tables
+	if statement.Tokens[0].Name == names["SELECT"] || strings.EqualFold(statement.Tokens[0].Special, "WITH") {
 		statement.Type = Types["SELECT"]
 		return statement
 	}
@@ -156,6 +231,11 @@ func inferStatementType(statement tokenizer.Statement) tokenizer.Statement {
 		return statement
 	}
 
+	if strings.EqualFold(statement.Tokens[0].Special, "TRUNCATE") {
+		statement.Type = Types["TRUNCATE"]
+		return statement
+	}
+
 	return statement
 }
 
@@ -174,18 +254,34 @@ func inferTokenspecialNames(statement tokenizer.Statement) tokenizer.Statement {
 		statement = parseAlterTable(statement)
 	case Types["DROP_TABLE"]:
 		statement = parseDropTable(statement)
+	case Types["CREATE_INDEX"]:
+		statement = parseCreateIndex(statement)
+	case Types["DROP_INDEX"]:
+		statement = parseDropIndex(statement)
+	case Types["ANALYZE"]:
+		statement = parseAnalyze(statement)
 	case Types["INSERT"]:
 		statement = parseInsert(statement)
-	case Types["SELECT"]:
-		statement = parseSelect(statement)
 	case Types["UPDATE"]:
 		statement = parseUpdate(statement)
 	case Types["DELETE"]:
 		statement = parseDelete(statement)
+	case Types["TRUNCATE"]:
+		statement = parseTruncate(statement)
 	case Types["BEGIN"]:
 		statement = parseBegin(statement)
 	case Types["COMMIT"]:
 		statement = parseCommit(statement)
+	case Types["SET_TRANSACTION"]:
+		statement = parseSetTransaction(statement)
+	case Types["SAVEPOINT"]:
+		statement = parseSavepoint(statement)
+	case Types["RELEASE"]:
+		statement = parseRelease(statement)
+	case Types["ROLLBACK_TO"]:
+		statement = parseRollbackTo(statement)
+	case Types["DEALLOCATE"]:
+		statement = parseDeallocate(statement)
 	}
 
 	return statement
@@ -199,6 +295,58 @@ func parseCommit(statement tokenizer.Statement) tokenizer.Statement {
 	return statement
 }
 
+// @in		SET TRANSACTION ISOLATION LEVEL REPEATABLE READ
+// @out	SET TRANSACTION ISOLATION LEVEL ISOLATION_LEVEL ISOLATION_LEVEL
+func parseSetTransaction(statement tokenizer.Statement) tokenizer.Statement {
+	setSpecialNameIfTokenExists(statement, 1, specialNames["TRANSACTION"])
+	setSpecialNameIfTokenExists(statement, 2, specialNames["ISOLATION"])
+	setSpecialNameIfTokenExists(statement, 3, specialNames["LEVEL"])
+
+	for i := 4; i < len(statement.Tokens); i++ {
+		setSpecialNameIfTokenExists(statement, i, specialNames["ISOLATION_LEVEL"])
+	}
+
+	return statement
+}
+
+func parseSavepoint(statement tokenizer.Statement) tokenizer.Statement {
+	setSpecialNameIfTokenExists(statement, 1, specialNames["SAVEPOINT_NAME"])
+	return statement
+}
+
+// @in		RELEASE [SAVEPOINT] s1
+func parseRelease(statement tokenizer.Statement) tokenizer.Statement {
+	if statement.Tokens[1].Name == names["SAVEPOINT"] {
+		setSpecialNameIfTokenExists(statement, 1, specialNames["SAVEPOINT"])
+		setSpecialNameIfTokenExists(statement, 2, specialNames["SAVEPOINT_NAME"])
+	} else {
+		setSpecialNameIfTokenExists(statement, 1, specialNames["SAVEPOINT_NAME"])
+	}
+	return statement
+}
+
+// @in		ROLLBACK TO [SAVEPOINT] s1
+func parseRollbackTo(statement tokenizer.Statement) tokenizer.Statement {
+	setSpecialNameIfTokenExists(statement, 1, specialNames["TO"])
+	if len(statement.Tokens) > 2 && statement.Tokens[2].Name == names["SAVEPOINT"] {
+		setSpecialNameIfTokenExists(statement, 2, specialNames["SAVEPOINT"])
+		setSpecialNameIfTokenExists(statement, 3, specialNames["SAVEPOINT_NAME"])
+	} else {
+		setSpecialNameIfTokenExists(statement, 2, specialNames["SAVEPOINT_NAME"])
+	}
+	return statement
+}
+
+// @in		DEALLOCATE [PREPARE] p1
+func parseDeallocate(statement tokenizer.Statement) tokenizer.Statement {
+	if strings.EqualFold(statement.Tokens[1].Special, "PREPARE") {
+		setSpecialNameIfTokenExists(statement, 2, specialNames["STATEMENT_NAME"])
+	} else {
+		setSpecialNameIfTokenExists(statement, 1, specialNames["STATEMENT_NAME"])
+	}
+	return statement
+}
+
 func parseCreateDatabase(statement tokenizer.Statement) tokenizer.Statement {
 	setSpecialNameIfTokenExists(statement, 2, specialNames["DATABASE_NAME"])
 	return statement
@@ -245,102 +393,36 @@ func parseAlterTable(statement tokenizer.Statement) tokenizer.Statement {
 	return statement
 }
 
-// https://www.sqlite.org/draft/syntaxdiagrams.html#select-stmt
-func parseSelect(statement tokenizer.Statement) tokenizer.Statement {
-	if strings.EqualFold(statement.Tokens[1].Special, "*") {
-		setSpecialNameIfTokenExists(statement, 1, specialNames["ALL"])
-
-		if strings.EqualFold(statement.Tokens[2].Special, "FROM") {
-			setSpecialNameIfTokenExists(statement, 2, specialNames["FROM"])
-			setSpecialNameIfTokenExists(statement, 3, specialNames["TABLE_NAME"])
-
-			if len(statement.Tokens) >= 5 {
-				setSpecialNameIfTokenExists(statement, 4, specialNames["SET_NAME"])
-			}
-
-			if len(statement.Tokens) >= 5 {
-				if strings.EqualFold(statement.Tokens[5].Special, "inner") {
-					statement.Type = Types["SELECT_INNER"]
-					setSpecialNameIfTokenExists(statement, 5, specialNames["INNER"])
-					setSpecialNameIfTokenExists(statement, 6, specialNames["JOIN"])
-					setSpecialNameIfTokenExists(statement, 7, specialNames["TABLE_NAME"])
-					setSpecialNameIfTokenExists(statement, 8, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 9, specialNames["ON"])
-					setSpecialNameIfTokenExists(statement, 10, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 11, specialNames["SET_COL_NAME"])
-					setSpecialNameIfTokenExists(statement, 12, specialNames["EQUALS"])
-					setSpecialNameIfTokenExists(statement, 13, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 14, specialNames["SET_COL_NAME"])
-				} else if strings.EqualFold(statement.Tokens[5].Special, "left") {
-					statement.Type = Types["SELECT_LEFT"]
-					setSpecialNameIfTokenExists(statement, 5, specialNames["LEFT"])
-					setSpecialNameIfTokenExists(statement, 6, specialNames["OUTER"])
-					setSpecialNameIfTokenExists(statement, 7, specialNames["JOIN"])
-					setSpecialNameIfTokenExists(statement, 8, specialNames["TABLE_NAME"])
-					setSpecialNameIfTokenExists(statement, 9, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 10, specialNames["ON"])
-					setSpecialNameIfTokenExists(statement, 11, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 12, specialNames["SET_COL_NAME"])
-					setSpecialNameIfTokenExists(statement, 13, specialNames["EQUALS"])
-					setSpecialNameIfTokenExists(statement, 14, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 15, specialNames["SET_COL_NAME"])
-				} else {
-					statement.Type = Types["SELECT_INNER"]
-					setSpecialNameIfTokenExists(statement, 5, specialNames["TABLE_NAME"])
-					setSpecialNameIfTokenExists(statement, 6, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 7, specialNames["WHERE"])
-					setSpecialNameIfTokenExists(statement, 8, specialNames["SET_COL_NAME"])
-					setSpecialNameIfTokenExists(statement, 9, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 10, specialNames["EQUALS"])
-					setSpecialNameIfTokenExists(statement, 11, specialNames["SET_NAME"])
-					setSpecialNameIfTokenExists(statement, 12, specialNames["SET_COL_NAME"])
-				}
-			}
-
-		}
-	}
-
-	if strings.EqualFold(statement.Tokens[1].Special, "*") == false {
-		setSpecialNameIfTokenExists(statement, 1, specialNames["COL_NAME"])
-		setSpecialNameIfTokenExists(statement, 2, specialNames["COL_NAME"])
-
-		if strings.EqualFold(statement.Tokens[3].Special, "FROM") {
-			setSpecialNameIfTokenExists(statement, 3, specialNames["FROM"])
-			setSpecialNameIfTokenExists(statement, 4, specialNames["TABLE_NAME"])
-
-			if strings.EqualFold(statement.Tokens[5].Special, "WHERE") {
-				setSpecialNameIfTokenExists(statement, 5, specialNames["WHERE"])
-				setSpecialNameIfTokenExists(statement, 6, specialNames["COL_NAME"])
+// @in		CREATE INDEX idx ON Product (price)
+// @out	CREATE INDEX INDEX_NAME ON TABLE_NAME COL_NAME
+func parseCreateIndex(statement tokenizer.Statement) tokenizer.Statement {
+	setSpecialNameIfTokenExists(statement, 1, specialNames["INDEX"])
+	setSpecialNameIfTokenExists(statement, 2, specialNames["INDEX_NAME"])
+	setSpecialNameIfTokenExists(statement, 3, specialNames["ON"])
+	setSpecialNameIfTokenExists(statement, 4, specialNames["TABLE_NAME"])
+	setSpecialNameIfTokenExists(statement, 5, specialNames["COL_NAME"])
+	return statement
+}
 
-				if strings.EqualFold(statement.Tokens[7].Special, "!=") {
-					setSpecialNameIfTokenExists(statement, 7, specialNames["NOT_EQUALS"])
-					setSpecialNameIfTokenExists(statement, 8, specialNames["COL_VALUE"])
-				}
-			}
-		}
-	}
+func parseDropIndex(statement tokenizer.Statement) tokenizer.Statement {
+	setSpecialNameIfTokenExists(statement, 1, specialNames["INDEX"])
+	setSpecialNameIfTokenExists(statement, 2, specialNames["INDEX_NAME"])
+	return statement
+}
 
+// @in		ANALYZE [Product]
+func parseAnalyze(statement tokenizer.Statement) tokenizer.Statement {
+	setSpecialNameIfTokenExists(statement, 1, specialNames["TABLE_NAME"])
 	return statement
 }
 
 func parseInsert(statement tokenizer.Statement) tokenizer.Statement {
-
 	if strings.EqualFold(statement.Tokens[1].Special, "into") {
 		setSpecialNameIfTokenExists(statement, 1, specialNames["INTO"])
 	}
 
 	setSpecialNameIfTokenExists(statement, 2, specialNames["TABLE_NAME"])
 
-	setSpecialNameIfTokenExists(statement, 3, specialNames["VALUES"])
-
-	for i := 4; i < len(statement.Tokens); i++ {
-		setSpecialNameIfTokenExists(statement, i, specialNames["VALUE"])
-	}
-
-	// @in		values(1,	'Gizmo',  19.99);
-	// @out 	(1,	'Gizmo',  19.99);
-	// statement.Tokens[3].Special = strings.Replace(statement.Tokens[3].Special, "values", "", 1)
-
 	return statement
 }
 
@@ -359,20 +441,18 @@ func parseUpdate(statement tokenizer.Statement) tokenizer.Statement {
 }
 
 func parseDelete(statement tokenizer.Statement) tokenizer.Statement {
-
 	setSpecialNameIfTokenExists(statement, 1, specialNames["FROM"])
 	setSpecialNameIfTokenExists(statement, 2, specialNames["TABLE_NAME"])
-	setSpecialNameIfTokenExists(statement, 3, specialNames["WHERE"])
-	setSpecialNameIfTokenExists(statement, 4, specialNames["COL_NAME"])
+	return statement
+}
 
-	if strings.EqualFold(statement.Tokens[5].Special, "=") {
-		setSpecialNameIfTokenExists(statement, 5, specialNames["EQUALS"])
-	} else if strings.EqualFold(statement.Tokens[5].Special, ">") {
-		setSpecialNameIfTokenExists(statement, 5, specialNames["GREATER_THAN"])
+// @in		TRUNCATE [TABLE] Product
+func parseTruncate(statement tokenizer.Statement) tokenizer.Statement {
+	if len(statement.Tokens) > 1 && statement.Tokens[1].Name == names["TABLE"] {
+		setSpecialNameIfTokenExists(statement, 2, specialNames["TABLE_NAME"])
+	} else {
+		setSpecialNameIfTokenExists(statement, 1, specialNames["TABLE_NAME"])
 	}
-
-	setSpecialNameIfTokenExists(statement, 6, specialNames["COL_VALUE"])
-
 	return statement
 }
 
@@ -381,7 +461,7 @@ func parseDelete(statement tokenizer.Statement) tokenizer.Statement {
 //
 
 func setSpecialNameIfTokenExists(statement tokenizer.Statement, i int, name string) {
-	if len(statement.Tokens) >= i {
+	if len(statement.Tokens) > i {
 		statement.Tokens[i].Name = name
 	}
 }
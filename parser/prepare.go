@@ -0,0 +1,179 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"errors"
+	"sqlit/tokenizer"
+	"strings"
+)
+
+// Prepare is the parse tree of PREPARE name AS <statement>, the statement is kept as SQL
+type Prepare struct {
+	Name      string
+	Statement string
+}
+
+// Execute is the parse tree of EXECUTE name [(value {, value})]
+type Execute struct {
+	Name string
+	Args []Expr
+}
+
+// ParsePrepare parses the raw SQL of a PREPARE statement
+func ParsePrepare(raw string) (*Prepare, error) {
+	rest, ok := cutWord(strings.TrimSpace(raw), "PREPARE")
+	if ok == false {
+		return nil, errors.New("!Failed to parse statement because PREPARE was expected.")
+	}
+
+	rest = strings.TrimSpace(rest)
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, errors.New("!Failed to parse statement because a prepared statement needs a name.")
+	}
+	name := fields[0]
+
+	rest, ok = cutWord(strings.TrimSpace(rest[len(name):]), "AS")
+	if ok == false || len(strings.TrimSpace(rest)) == 0 {
+		return nil, errors.New("!Failed to parse statement because PREPARE " + name + " needs AS and a statement.")
+	}
+
+	return &Prepare{Name: name, Statement: strings.TrimSpace(rest)}, nil
+}
+
+// ParseExecute parses the raw SQL of an EXECUTE statement
+func ParseExecute(raw string) (*Execute, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+
+	err = p.expectKeyword("EXECUTE")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Name != tokenizer.Word {
+		return nil, p.unexpected()
+	}
+	execute := &Execute{Name: p.next().Special}
+
+	if p.acceptSymbol("(") && p.acceptSymbol(")") == false {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			execute.Args = append(execute.Args, arg)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	return execute, nil
+}
+
+// Bind makes a copy of a query with each parameter replaced by the value bound to it,
+// including the parameters of its subqueries
+func (query *Select) Bind(args []*Literal) *Select {
+	bound := query.Rewrite(bindArgs(args))
+	bound.Parameters = 0
+	return bound
+}
+
+// bindArgs makes a rewrite for RewriteExpr that replaces each parameter with the value bound to it,
+// including the parameters of subqueries
+func bindArgs(args []*Literal) func(Expr) Expr {
+	return RewriteSubqueries(func(e Expr) Expr {
+		if x, ok := e.(*Parameter); ok && x.Number <= len(args) {
+			return args[x.Number-1]
+		}
+		return e
+	})
+}
+
+// RewriteSubqueries makes a rewrite for RewriteExpr that rewrites the expressions of subqueries too,
+// which RewriteExpr leaves alone
+func RewriteSubqueries(rewrite func(Expr) Expr) func(Expr) Expr {
+	var deep func(Expr) Expr
+	deep = func(e Expr) Expr {
+		if x, ok := e.(*SubqueryExpr); ok {
+			return &SubqueryExpr{Kind: x.Kind, Operand: x.Operand, Query: x.Query.Rewrite(deep)}
+		}
+		return rewrite(e)
+	}
+	return deep
+}
+
+// Rewrite makes a copy of a query with every expression rewritten by RewriteExpr,
+// derived tables in FROM, common tables and compound SELECTs are rewritten the same way
+func (query *Select) Rewrite(rewrite func(Expr) Expr) *Select {
+	each := func(expr Expr) Expr {
+		return RewriteExpr(expr, rewrite)
+	}
+
+	rewritten := &Select{Distinct: query.Distinct, Where: each(query.Where), Having: each(query.Having), Parameters: query.Parameters}
+
+	for _, column := range query.Columns {
+		rewritten.Columns = append(rewritten.Columns, ResultColumn{Expr: each(column.Expr), Alias: column.Alias})
+	}
+
+	for _, table := range query.From {
+		table.On = each(table.On)
+		if table.Subquery != nil {
+			table.Subquery = table.Subquery.Rewrite(rewrite)
+		}
+		rewritten.From = append(rewritten.From, table)
+	}
+
+	for _, expr := range query.GroupBy {
+		rewritten.GroupBy = append(rewritten.GroupBy, each(expr))
+	}
+
+	for _, term := range query.OrderBy {
+		rewritten.OrderBy = append(rewritten.OrderBy, OrderingTerm{Expr: each(term.Expr), Descending: term.Descending})
+	}
+
+	for _, table := range query.With {
+		rewritten.With = append(rewritten.With, CommonTable{Name: table.Name, Columns: table.Columns, Query: table.Query.Rewrite(rewrite)})
+	}
+	rewritten.Recursive = query.Recursive
+
+	for _, compound := range query.Compound {
+		rewritten.Compound = append(rewritten.Compound, CompoundSelect{Operator: compound.Operator, All: compound.All, Query: compound.Query.Rewrite(rewrite)})
+	}
+
+	return rewritten
+}
+
+//
+//			Helper functions
+//
+
+// cutWord removes a leading keyword from some SQL, if it's there
+func cutWord(sql string, word string) (string, bool) {
+	if len(sql) < len(word) || strings.EqualFold(sql[:len(word)], word) == false {
+		return sql, false
+	}
+
+	rest := sql[len(word):]
+	if len(rest) > 0 && strings.TrimSpace(rest[:1]) != "" {
+		return sql, false
+	}
+
+	return rest, true
+}
@@ -0,0 +1,1584 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"errors"
+	"sqlit/tokenizer"
+	"strconv"
+	"strings"
+)
+
+// Queries are too free-form to label token by token, so a SELECT gets a real
+// parse tree: ParseSelect runs a recursive descent over the lexemes of its raw SQL.
+// https://www.sqlite.org/draft/syntaxdiagrams.html#select-stmt
+
+// Select is the parse tree of a SELECT statement
+type Select struct {
+	Distinct bool
+	Columns  []ResultColumn
+	From     []TableRef
+	Where    Expr
+	GroupBy  []Expr
+	Having   Expr
+	OrderBy  []OrderingTerm
+
+	// With are the common tables the query can read, Recursive ones can read themselves
+	With      []CommonTable
+	Recursive bool
+
+	// Compound are the SELECTs combined with this one, left to right.
+	// ORDER BY then sorts the combined rows, by the names of their columns
+	Compound []CompoundSelect
+
+	// Parameters is how many values have to be bound before the query can run
+	Parameters int
+}
+
+// A CommonTable is a query named by WITH, which can be read like a table. Columns renames its columns
+type CommonTable struct {
+	Name    string
+	Columns []string
+	Query   *Select
+}
+
+// A CompoundSelect is a SELECT combined with the ones before it by UNION, INTERSECT or EXCEPT,
+// All keeps duplicate rows
+type CompoundSelect struct {
+	Operator string
+	All      bool
+	Query    *Select
+}
+
+// Explain is the parse tree of EXPLAIN [ANALYZE] <query>
+type Explain struct {
+	Analyze bool
+	Query   *Select
+}
+
+// A ResultColumn is a projected expression, a Star expands to many columns
+type ResultColumn struct {
+	Expr  Expr
+	Alias string
+}
+
+// A TableRef is a table in the FROM clause. Every table after the first is joined
+// onto the ones before it, Join is CROSS, INNER or LEFT. A derived table is a
+// Subquery instead, known by its Alias
+type TableRef struct {
+	Name     string
+	Alias    string
+	Join     string
+	On       Expr
+	Subquery *Select
+}
+
+// An OrderingTerm is a single ORDER BY expression
+type OrderingTerm struct {
+	Expr       Expr
+	Descending bool
+}
+
+// Expr is a node of an expression tree, String gives it back as SQL
+type Expr interface {
+	String() string
+}
+
+// ColumnRef is a column, optionally qualified by its table's name or alias
+type ColumnRef struct {
+	Table  string
+	Column string
+}
+
+// Literal is a constant, Kind is one of tokenizer's Number or String, or NULL
+type Literal struct {
+	Kind  string
+	Value string
+}
+
+// Star is * or table.*
+type Star struct {
+	Table string
+}
+
+// UnaryExpr is -x or NOT x
+type UnaryExpr struct {
+	Operator string
+	Operand  Expr
+}
+
+// BinaryExpr is an arithmetic, comparison or logical operator between two expressions
+type BinaryExpr struct {
+	Operator string
+	Left     Expr
+	Right    Expr
+}
+
+// IsNullExpr is x IS NULL or x IS NOT NULL
+type IsNullExpr struct {
+	Operand Expr
+	Not     bool
+}
+
+// PatternExpr is x LIKE, ILIKE, GLOB or REGEXP pattern, with an optional ESCAPE character for
+// LIKE and ILIKE. Not is set for the NOT forms, like x NOT LIKE pattern
+type PatternExpr struct {
+	Operator string
+	Operand  Expr
+	Pattern  Expr
+	Escape   Expr
+	Not      bool
+}
+
+// Parameter is a placeholder, ? or $n, for the value bound to it when a prepared statement
+// is executed. Parameters are numbered from 1, a ? is numbered one past the highest before it
+type Parameter struct {
+	Number int
+}
+
+// SubqueryExpr is a SELECT used as a value. Kind is SCALAR for (SELECT ...), which is
+// the single value it selects, EXISTS for EXISTS (SELECT ...), or IN for Operand IN (SELECT ...)
+type SubqueryExpr struct {
+	Kind    string
+	Operand Expr
+	Query   *Select
+}
+
+// InExpr is x IN (a, b, ...), x NOT IN is NOT over it
+type InExpr struct {
+	Operand Expr
+	List    []Expr
+}
+
+// FuncCall is a function applied to its arguments, COUNT(*) has a Star argument
+type FuncCall struct {
+	Name     string
+	Args     []Expr
+	Distinct bool
+	Over     *Window
+}
+
+// CastExpr is CAST(x AS type), x converted to the family of a column type
+type CastExpr struct {
+	Operand  Expr
+	TypeName string
+}
+
+// ExtractExpr is EXTRACT(field FROM x), a part of a date or time like its YEAR
+type ExtractExpr struct {
+	Field   string
+	Operand Expr
+}
+
+// CollateExpr is x COLLATE collation, x compared and sorted with a collation other than its column's
+type CollateExpr struct {
+	Operand   Expr
+	Collation string
+}
+
+// CaseExpr is CASE [operand] WHEN ... THEN ... [ELSE ...] END. With an Operand, each When
+// is a value to compare it to, otherwise each When is a condition
+type CaseExpr struct {
+	Operand Expr
+	Whens   []WhenClause
+	Else    Expr
+}
+
+// WhenClause is WHEN When THEN Then, one branch of a CASE
+type WhenClause struct {
+	When Expr
+	Then Expr
+}
+
+func (e *ColumnRef) String() string {
+	if e.Table != "" {
+		return e.Table + "." + e.Column
+	}
+	return e.Column
+}
+
+func (e *Literal) String() string {
+	if e.Kind == tokenizer.String {
+		return "'" + strings.Replace(e.Value, "'", "''", -1) + "'"
+	}
+	return e.Value
+}
+
+func (e *Star) String() string {
+	if e.Table != "" {
+		return e.Table + ".*"
+	}
+	return "*"
+}
+
+func (e *UnaryExpr) String() string {
+	if e.Operator == "NOT" {
+		return "NOT " + e.Operand.String()
+	}
+	return e.Operator + e.Operand.String()
+}
+
+func (e *BinaryExpr) String() string {
+	return operandString(e.Left) + " " + e.Operator + " " + operandString(e.Right)
+}
+
+func (e *IsNullExpr) String() string {
+	if e.Not {
+		return e.Operand.String() + " IS NOT NULL"
+	}
+	return e.Operand.String() + " IS NULL"
+}
+
+func (e *PatternExpr) String() string {
+	sql := operandString(e.Operand)
+	if e.Not {
+		sql += " NOT"
+	}
+	sql += " " + e.Operator + " " + operandString(e.Pattern)
+	if e.Escape != nil {
+		sql += " ESCAPE " + e.Escape.String()
+	}
+	return sql
+}
+
+func (e *Parameter) String() string {
+	return "$" + strconv.Itoa(e.Number)
+}
+
+func (e *SubqueryExpr) String() string {
+	switch e.Kind {
+	case "EXISTS":
+		return "EXISTS (" + e.Query.String() + ")"
+	case "IN":
+		return operandString(e.Operand) + " IN (" + e.Query.String() + ")"
+	}
+	return "(" + e.Query.String() + ")"
+}
+
+func (e *InExpr) String() string {
+	var list []string
+	for _, expr := range e.List {
+		list = append(list, expr.String())
+	}
+	return operandString(e.Operand) + " IN (" + strings.Join(list, ", ") + ")"
+}
+
+func (e *CastExpr) String() string {
+	return "CAST(" + e.Operand.String() + " AS " + e.TypeName + ")"
+}
+
+func (e *ExtractExpr) String() string {
+	return "EXTRACT(" + e.Field + " FROM " + e.Operand.String() + ")"
+}
+
+func (e *CollateExpr) String() string {
+	return operandString(e.Operand) + " COLLATE " + e.Collation
+}
+
+func (e *CaseExpr) String() string {
+	sql := "CASE"
+	if e.Operand != nil {
+		sql += " " + e.Operand.String()
+	}
+	for _, when := range e.Whens {
+		sql += " WHEN " + when.When.String() + " THEN " + when.Then.String()
+	}
+	if e.Else != nil {
+		sql += " ELSE " + e.Else.String()
+	}
+	return sql + " END"
+}
+
+// String gives a query back as SQL
+func (query *Select) String() string {
+	sql := ""
+
+	for i, table := range query.With {
+		if i == 0 {
+			sql = "WITH "
+			if query.Recursive {
+				sql += "RECURSIVE "
+			}
+		} else {
+			sql += ", "
+		}
+
+		sql += table.Name
+		if len(table.Columns) > 0 {
+			sql += "(" + strings.Join(table.Columns, ", ") + ")"
+		}
+		sql += " AS (" + table.Query.String() + ") "
+	}
+
+	var columns []string
+	for _, column := range query.Columns {
+		if column.Alias != "" {
+			columns = append(columns, column.Expr.String()+" AS "+column.Alias)
+		} else {
+			columns = append(columns, column.Expr.String())
+		}
+	}
+	sql += "SELECT "
+	if query.Distinct {
+		sql += "DISTINCT "
+	}
+	sql += strings.Join(columns, ", ") + " FROM "
+
+	for i, table := range query.From {
+		switch {
+		case i == 0:
+		case table.Join == "CROSS":
+			sql += ", "
+		default:
+			sql += " " + table.Join + " JOIN "
+		}
+
+		if table.Subquery != nil {
+			sql += "(" + table.Subquery.String() + ")"
+		} else {
+			sql += table.Name
+		}
+		if table.Alias != "" {
+			sql += " AS " + table.Alias
+		}
+		if table.On != nil {
+			sql += " ON " + table.On.String()
+		}
+	}
+
+	if query.Where != nil {
+		sql += " WHERE " + query.Where.String()
+	}
+
+	if len(query.GroupBy) > 0 {
+		var groupBy []string
+		for _, expr := range query.GroupBy {
+			groupBy = append(groupBy, expr.String())
+		}
+		sql += " GROUP BY " + strings.Join(groupBy, ", ")
+	}
+
+	if query.Having != nil {
+		sql += " HAVING " + query.Having.String()
+	}
+
+	for _, compound := range query.Compound {
+		sql += " " + compound.Operator
+		if compound.All {
+			sql += " ALL"
+		}
+		sql += " " + compound.Query.String()
+	}
+
+	if len(query.OrderBy) > 0 {
+		var orderBy []string
+		for _, term := range query.OrderBy {
+			if term.Descending {
+				orderBy = append(orderBy, term.Expr.String()+" DESC")
+			} else {
+				orderBy = append(orderBy, term.Expr.String())
+			}
+		}
+		sql += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+
+	return sql
+}
+
+// Tables lists every table of the database a query reads, including those its subqueries read
+func (query *Select) Tables() []string {
+	var tables []string
+
+	visit := func(expr Expr) {
+		tables = append(tables, SubqueryTables(expr)...)
+	}
+
+	for _, table := range query.From {
+		if table.Subquery != nil {
+			tables = append(tables, table.Subquery.Tables()...)
+		} else {
+			tables = append(tables, table.Name)
+		}
+		visit(table.On)
+	}
+
+	for _, column := range query.Columns {
+		visit(column.Expr)
+	}
+	for _, expr := range query.GroupBy {
+		visit(expr)
+	}
+	for _, term := range query.OrderBy {
+		visit(term.Expr)
+	}
+	visit(query.Where)
+	visit(query.Having)
+
+	for _, compound := range query.Compound {
+		tables = append(tables, compound.Query.Tables()...)
+	}
+
+	for _, table := range query.With {
+		tables = append(tables, table.Query.Tables()...)
+	}
+
+	// a common table isn't one of the database's
+	var read []string
+	for _, table := range tables {
+		if query.CommonTable(table) == nil {
+			read = append(read, table)
+		}
+	}
+
+	return read
+}
+
+// SubqueryTables lists every table of the database the subqueries of some expressions read
+func SubqueryTables(exprs ...Expr) []string {
+	var tables []string
+	for _, expr := range exprs {
+		WalkExpr(expr, func(e Expr) {
+			if subquery, ok := e.(*SubqueryExpr); ok {
+				tables = append(tables, subquery.Query.Tables()...)
+			}
+		})
+	}
+	return tables
+}
+
+// CommonTable finds the common table of WITH with a name, if there is one
+func (query *Select) CommonTable(name string) *CommonTable {
+	for i := range query.With {
+		if strings.EqualFold(query.With[i].Name, name) {
+			return &query.With[i]
+		}
+	}
+	return nil
+}
+
+func (e *FuncCall) String() string {
+	var args []string
+	for _, arg := range e.Args {
+		args = append(args, arg.String())
+	}
+	call := e.Name + "(" + strings.Join(args, ", ") + ")"
+	if e.Distinct {
+		call = e.Name + "(DISTINCT " + strings.Join(args, ", ") + ")"
+	}
+
+	if e.Over != nil {
+		call += " OVER " + e.Over.String()
+	}
+	return call
+}
+
+// Aggregates are the functions that fold many rows into one value
+var Aggregates = map[string]bool{
+	"COUNT": true,
+	"SUM":   true,
+	"AVG":   true,
+	"MIN":   true,
+	"MAX":   true,
+}
+
+// reservedWords can't be used as an alias without AS
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true,
+	"HAVING": true, "ORDER": true, "ASC": true, "DESC": true, "AS": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "OUTER": true, "CROSS": true,
+	"ON": true, "AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true,
+	"IN": true, "EXISTS": true, "UNION": true, "INTERSECT": true, "EXCEPT": true,
+	"ALL": true, "DISTINCT": true, "CASE": true, "WHEN": true, "THEN": true,
+	"ELSE": true, "END": true, "LIKE": true, "ILIKE": true, "GLOB": true,
+	"REGEXP": true, "ESCAPE": true, "COLLATE": true,
+}
+
+// patternOperators are the operators that match text against a pattern
+var patternOperators = []string{"LIKE", "ILIKE", "GLOB", "REGEXP"}
+
+// ParseSelect parses the raw SQL of a SELECT statement
+func ParseSelect(raw string) (*Select, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+
+	query, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	query.Parameters = p.parameters
+	return query, nil
+}
+
+// ParseExplain parses the raw SQL of an EXPLAIN statement
+func ParseExplain(raw string) (*Explain, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+
+	err = p.expectKeyword("EXPLAIN")
+	if err != nil {
+		return nil, err
+	}
+
+	explain := &Explain{Analyze: p.acceptKeyword("ANALYZE")}
+
+	if p.isQueryAt(0) == false {
+		return nil, errors.New("!Failed to explain statement because only queries have a plan.")
+	}
+
+	explain.Query, err = p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	explain.Query.Parameters = p.parameters
+	return explain, nil
+}
+
+// queryParser walks a statement's lexemes
+type queryParser struct {
+	tokens   []tokenizer.Token
+	position int
+
+	// parameters is the highest numbered parameter so far
+	parameters int
+}
+
+// @in		[WITH [RECURSIVE] name [(column {, column})] AS (query) {, ...}]
+// @in		core {UNION [ALL] | INTERSECT [ALL] | EXCEPT [ALL] core} [ORDER BY ...]
+func (p *queryParser) parseSelect() (*Select, error) {
+	var with []CommonTable
+	recursive := false
+
+	if p.acceptKeyword("WITH") {
+		recursive = p.acceptKeyword("RECURSIVE")
+
+		for {
+			table, err := p.parseCommonTable()
+			if err != nil {
+				return nil, err
+			}
+			with = append(with, table)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+	}
+
+	query, err := p.parseSelectCore()
+	if err != nil {
+		return nil, err
+	}
+	query.With = with
+	query.Recursive = recursive
+
+	for {
+		compound := CompoundSelect{}
+
+		switch {
+		case p.acceptKeyword("UNION"):
+			compound.Operator = "UNION"
+		case p.acceptKeyword("INTERSECT"):
+			compound.Operator = "INTERSECT"
+		case p.acceptKeyword("EXCEPT"):
+			compound.Operator = "EXCEPT"
+		}
+		if compound.Operator == "" {
+			break
+		}
+
+		compound.All = p.acceptKeyword("ALL")
+		compound.Query, err = p.parseSelectCore()
+		if err != nil {
+			return nil, err
+		}
+		query.Compound = append(query.Compound, compound)
+	}
+
+	if p.acceptKeyword("ORDER") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return nil, err
+		}
+
+		query.OrderBy, err = p.parseOrderingTerms()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return query, nil
+}
+
+// @in		expr [ASC | DESC] {, expr [ASC | DESC]}
+func (p *queryParser) parseOrderingTerms() ([]OrderingTerm, error) {
+	var terms []OrderingTerm
+
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		term := OrderingTerm{Expr: expr}
+		if p.acceptKeyword("DESC") {
+			term.Descending = true
+		} else {
+			p.acceptKeyword("ASC")
+		}
+		terms = append(terms, term)
+
+		if p.acceptSymbol(",") == false {
+			return terms, nil
+		}
+	}
+}
+
+// @in		name [(column {, column})] AS (query)
+func (p *queryParser) parseCommonTable() (CommonTable, error) {
+	if p.peek().Name != tokenizer.Word || reservedWords[strings.ToUpper(p.peek().Special)] {
+		return CommonTable{}, p.unexpected()
+	}
+	table := CommonTable{Name: p.next().Special}
+
+	if p.acceptSymbol("(") {
+		for {
+			if p.peek().Name != tokenizer.Word {
+				return CommonTable{}, p.unexpected()
+			}
+			table.Columns = append(table.Columns, p.next().Special)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+
+		err := p.expectSymbol(")")
+		if err != nil {
+			return CommonTable{}, err
+		}
+	}
+
+	err := p.expectKeyword("AS")
+	if err != nil {
+		return CommonTable{}, err
+	}
+
+	err = p.expectSymbol("(")
+	if err != nil {
+		return CommonTable{}, err
+	}
+
+	table.Query, err = p.parseSelect()
+	if err != nil {
+		return CommonTable{}, err
+	}
+
+	return table, p.expectSymbol(")")
+}
+
+// @in		SELECT ... FROM ... [WHERE ...] [GROUP BY ...] [HAVING ...]
+func (p *queryParser) parseSelectCore() (*Select, error) {
+	err := p.expectKeyword("SELECT")
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Select{}
+
+	// result columns, SELECT ALL is the same as leaving it out
+	if p.acceptKeyword("DISTINCT") {
+		query.Distinct = true
+	} else {
+		p.acceptKeyword("ALL")
+	}
+
+	for {
+		column, err := p.parseResultColumn()
+		if err != nil {
+			return nil, err
+		}
+		query.Columns = append(query.Columns, column)
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	// FROM table [alias] {, table [alias] | [INNER | LEFT [OUTER] | CROSS] JOIN table [alias] [ON expr]}
+	err = p.expectKeyword("FROM")
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := p.parseTableRef()
+	if err != nil {
+		return nil, err
+	}
+	query.From = append(query.From, table)
+
+	for {
+		join := ""
+		comma := false
+
+		if p.acceptSymbol(",") {
+			join = "CROSS"
+			comma = true
+		} else if p.acceptKeyword("CROSS") {
+			join = "CROSS"
+		} else if p.acceptKeyword("INNER") {
+			join = "INNER"
+		} else if p.acceptKeyword("LEFT") {
+			p.acceptKeyword("OUTER")
+			join = "LEFT"
+		} else if p.isKeyword("JOIN") {
+			join = "INNER"
+		} else {
+			break
+		}
+
+		if comma == false {
+			err = p.expectKeyword("JOIN")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		table, err := p.parseTableRef()
+		if err != nil {
+			return nil, err
+		}
+		table.Join = join
+
+		if p.acceptKeyword("ON") {
+			table.On, err = p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+		} else if join == "LEFT" {
+			return nil, errors.New("!Failed to parse query because LEFT JOIN " + table.Name + " has no ON clause.")
+		}
+
+		query.From = append(query.From, table)
+	}
+
+	if p.acceptKeyword("WHERE") {
+		query.Where, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("GROUP") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			query.GroupBy = append(query.GroupBy, expr)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("HAVING") {
+		query.Having, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return query, nil
+}
+
+// @in		* | table.* | expr [[AS] alias]
+func (p *queryParser) parseResultColumn() (ResultColumn, error) {
+	if p.acceptSymbol("*") {
+		return ResultColumn{Expr: &Star{}}, nil
+	}
+
+	if p.peek().Name == tokenizer.Word && p.peekAt(1).Special == "." && p.peekAt(2).Special == "*" {
+		table := p.next().Special
+		p.next()
+		p.next()
+		return ResultColumn{Expr: &Star{Table: table}}, nil
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return ResultColumn{}, err
+	}
+
+	alias, err := p.parseAlias()
+	return ResultColumn{Expr: expr, Alias: alias}, err
+}
+
+// @in		table [[AS] alias] | (SELECT ...) [AS] alias
+func (p *queryParser) parseTableRef() (TableRef, error) {
+	if p.isSymbol("(") && p.isQueryAt(1) {
+		p.next()
+
+		subquery, err := p.parseSelect()
+		if err != nil {
+			return TableRef{}, err
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return TableRef{}, err
+		}
+
+		alias, err := p.parseAlias()
+		if err != nil {
+			return TableRef{}, err
+		}
+		if alias == "" {
+			return TableRef{}, errors.New("!Failed to parse query because a subquery in FROM needs an alias.")
+		}
+
+		return TableRef{Name: alias, Alias: alias, Subquery: subquery}, nil
+	}
+
+	if p.peek().Name != tokenizer.Word {
+		return TableRef{}, p.unexpected()
+	}
+
+	table := TableRef{Name: p.next().Special}
+
+	alias, err := p.parseAlias()
+	table.Alias = alias
+	return table, err
+}
+
+func (p *queryParser) parseAlias() (string, error) {
+	if p.acceptKeyword("AS") {
+		if p.peek().Name != tokenizer.Word {
+			return "", p.unexpected()
+		}
+		return p.next().Special, nil
+	}
+
+	if p.peek().Name == tokenizer.Word && reservedWords[strings.ToUpper(p.peek().Special)] == false {
+		return p.next().Special, nil
+	}
+
+	return "", nil
+}
+
+//
+//			Expressions, from loosest to tightest binding
+//
+
+func (p *queryParser) parseExpr() (Expr, error) {
+	return p.parseOr()
+}
+
+func (p *queryParser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Operator: "OR", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Operator: "AND", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (Expr, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Operator: "NOT", Operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		err = p.expectKeyword("NULL")
+		if err != nil {
+			return nil, err
+		}
+		return &IsNullExpr{Operand: left, Not: not}, nil
+	}
+
+	if p.isKeyword("IN") || (p.isKeyword("NOT") && strings.EqualFold(p.peekAt(1).Special, "IN")) {
+		not := p.acceptKeyword("NOT")
+		p.next()
+
+		in, err := p.parseIn(left)
+		if err != nil {
+			return nil, err
+		}
+
+		if not {
+			return &UnaryExpr{Operator: "NOT", Operand: in}, nil
+		}
+		return in, nil
+	}
+
+	for _, operator := range patternOperators {
+		if p.isKeyword(operator) || (p.isKeyword("NOT") && strings.EqualFold(p.peekAt(1).Special, operator)) {
+			not := p.acceptKeyword("NOT")
+			p.next()
+			return p.parsePattern(operator, left, not)
+		}
+	}
+
+	for _, operator := range []string{"=", "!=", "<>", "<=", ">=", "<", ">"} {
+		if p.acceptSymbol(operator) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+
+			if operator == "<>" {
+				operator = "!="
+			}
+			return &BinaryExpr{Operator: operator, Left: left, Right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isSymbol("+") || p.isSymbol("-") || p.isSymbol("||") {
+		operator := p.next().Special
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Operator: operator, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isSymbol("*") || p.isSymbol("/") || p.isSymbol("%") {
+		operator := p.next().Special
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Operator: operator, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (Expr, error) {
+	if p.acceptSymbol("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Operator: "-", Operand: operand}, nil
+	}
+
+	if p.acceptSymbol("+") {
+		return p.parseUnary()
+	}
+
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("COLLATE") {
+		collation, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		expr = &CollateExpr{Operand: expr, Collation: strings.ToUpper(collation)}
+	}
+
+	return expr, nil
+}
+
+func (p *queryParser) parsePrimary() (Expr, error) {
+	token := p.peek()
+
+	switch token.Name {
+	case tokenizer.Number, tokenizer.String:
+		p.next()
+		return &Literal{Kind: token.Name, Value: token.Special}, nil
+
+	case tokenizer.Parameter:
+		p.next()
+
+		number := p.parameters + 1
+		if token.Special != "?" {
+			number, _ = strconv.Atoi(token.Special[1:])
+			if number < 1 {
+				return nil, errors.New("!Failed to parse query because parameter " + token.Special + " isn't numbered from 1.")
+			}
+		}
+
+		if number > p.parameters {
+			p.parameters = number
+		}
+		return &Parameter{Number: number}, nil
+
+	case tokenizer.Symbol:
+		if p.isSymbol("(") && p.isQueryAt(1) {
+			p.next()
+			return p.parseSubquery("SCALAR", nil)
+		}
+
+		if p.acceptSymbol("(") {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expectSymbol(")")
+		}
+
+	case tokenizer.Word:
+		if p.acceptKeyword("NULL") {
+			return &Literal{Kind: "NULL", Value: "NULL"}, nil
+		}
+
+		if p.acceptKeyword("EXISTS") {
+			err := p.expectSymbol("(")
+			if err != nil {
+				return nil, err
+			}
+			return p.parseSubquery("EXISTS", nil)
+		}
+
+		if p.acceptKeyword("CASE") {
+			return p.parseCase()
+		}
+
+		if p.isKeyword("CAST") && p.peekAt(1).Special == "(" {
+			p.next()
+			p.next()
+			return p.parseCast()
+		}
+
+		if p.isKeyword("EXTRACT") && p.peekAt(1).Special == "(" {
+			p.next()
+			p.next()
+			return p.parseExtract()
+		}
+
+		if reservedWords[strings.ToUpper(token.Special)] {
+			break
+		}
+		p.next()
+
+		if p.acceptSymbol("(") {
+			call, err := p.parseFuncCall(strings.ToUpper(token.Special))
+			if err != nil {
+				return nil, err
+			}
+
+			if p.acceptKeyword("OVER") {
+				call.Over, err = p.parseWindow()
+				if err != nil {
+					return nil, err
+				}
+			}
+			return call, nil
+		}
+
+		if p.acceptSymbol(".") {
+			if p.peek().Name != tokenizer.Word {
+				return nil, p.unexpected()
+			}
+			return &ColumnRef{Table: token.Special, Column: p.next().Special}, nil
+		}
+
+		return &ColumnRef{Column: token.Special}, nil
+	}
+
+	return nil, p.unexpected()
+}
+
+// @in		SELECT ... )	with the ( already consumed
+func (p *queryParser) parseSubquery(kind string, operand Expr) (Expr, error) {
+	query, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubqueryExpr{Kind: kind, Operand: operand, Query: query}, p.expectSymbol(")")
+}
+
+// @in		pattern [ESCAPE expr]	with the operator already consumed
+func (p *queryParser) parsePattern(operator string, operand Expr, not bool) (Expr, error) {
+	pattern, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &PatternExpr{Operator: operator, Operand: operand, Pattern: pattern, Not: not}
+	if p.isKeyword("ESCAPE") {
+		if operator != "LIKE" && operator != "ILIKE" {
+			return nil, p.unexpected()
+		}
+		p.next()
+
+		e.Escape, err = p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// @in		( SELECT ... ) | ( expr {, expr} )	with IN already consumed
+func (p *queryParser) parseIn(operand Expr) (Expr, error) {
+	err := p.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isQueryAt(0) {
+		return p.parseSubquery("IN", operand)
+	}
+
+	in := &InExpr{Operand: operand}
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		in.List = append(in.List, expr)
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	return in, p.expectSymbol(")")
+}
+
+// @in		[operand] WHEN expr THEN expr {WHEN expr THEN expr} [ELSE expr] END	with CASE already consumed
+func (p *queryParser) parseCase() (Expr, error) {
+	e := &CaseExpr{}
+
+	if p.isKeyword("WHEN") == false {
+		operand, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		e.Operand = operand
+	}
+
+	for p.acceptKeyword("WHEN") {
+		when, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectKeyword("THEN")
+		if err != nil {
+			return nil, err
+		}
+
+		then, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		e.Whens = append(e.Whens, WhenClause{When: when, Then: then})
+	}
+
+	if len(e.Whens) == 0 {
+		return nil, errors.New("!Failed to parse query because CASE needs at least one WHEN.")
+	}
+
+	if p.acceptKeyword("ELSE") {
+		otherwise, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		e.Else = otherwise
+	}
+
+	return e, p.expectKeyword("END")
+}
+
+// @in		expr AS type[(n {, n})] )	with CAST( already consumed
+func (p *queryParser) parseCast() (Expr, error) {
+	operand, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("AS")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Name != tokenizer.Word {
+		return nil, p.unexpected()
+	}
+	typeName := strings.ToLower(p.next().Special)
+
+	// a length or precision, like varchar(20)
+	if p.acceptSymbol("(") {
+		var sizes []string
+		for {
+			if p.peek().Name != tokenizer.Number {
+				return nil, p.unexpected()
+			}
+			sizes = append(sizes, p.next().Special)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return nil, err
+		}
+		typeName += "(" + strings.Join(sizes, ",") + ")"
+	}
+
+	return &CastExpr{Operand: operand, TypeName: typeName}, p.expectSymbol(")")
+}
+
+// @in		field FROM expr )	with EXTRACT( already consumed
+func (p *queryParser) parseExtract() (Expr, error) {
+	if p.peek().Name != tokenizer.Word {
+		return nil, p.unexpected()
+	}
+	field := strings.ToUpper(p.next().Special)
+
+	err := p.expectKeyword("FROM")
+	if err != nil {
+		return nil, err
+	}
+
+	operand, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExtractExpr{Field: field, Operand: operand}, p.expectSymbol(")")
+}
+
+// @in		name( [* | expr {, expr}] )	with name( already consumed
+func (p *queryParser) parseFuncCall(name string) (*FuncCall, error) {
+	call := &FuncCall{Name: name}
+
+	if p.acceptSymbol("*") {
+		call.Args = append(call.Args, &Star{})
+		return call, p.expectSymbol(")")
+	}
+
+	if p.acceptSymbol(")") {
+		return call, nil
+	}
+
+	// only an aggregate can fold just the distinct values of its argument
+	if p.acceptKeyword("DISTINCT") {
+		if Aggregates[name] == false {
+			return nil, errors.New("!Failed to parse query because DISTINCT can't be used in " + name + ".")
+		}
+		if p.isSymbol("*") {
+			return nil, errors.New("!Failed to parse query because " + name + "(DISTINCT *) can't be used.")
+		}
+		call.Distinct = true
+	}
+
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	return call, p.expectSymbol(")")
+}
+
+//
+//			Helper functions
+//
+
+// HasAggregate checks if an expression folds rows together, an aggregate over a window doesn't
+func HasAggregate(expr Expr) bool {
+	found := false
+	WalkExpr(expr, func(e Expr) {
+		if call, ok := e.(*FuncCall); ok && Aggregates[call.Name] && call.Over == nil {
+			found = true
+		}
+	})
+	return found
+}
+
+// WalkExpr visits every node of an expression tree, parents before children.
+// It doesn't go into subqueries, which are queries of their own
+func WalkExpr(expr Expr, visit func(Expr)) {
+	if expr == nil {
+		return
+	}
+
+	visit(expr)
+
+	switch e := expr.(type) {
+	case *UnaryExpr:
+		WalkExpr(e.Operand, visit)
+	case *BinaryExpr:
+		WalkExpr(e.Left, visit)
+		WalkExpr(e.Right, visit)
+	case *IsNullExpr:
+		WalkExpr(e.Operand, visit)
+	case *PatternExpr:
+		WalkExpr(e.Operand, visit)
+		WalkExpr(e.Pattern, visit)
+		WalkExpr(e.Escape, visit)
+	case *FuncCall:
+		for _, arg := range e.Args {
+			WalkExpr(arg, visit)
+		}
+		if e.Over != nil {
+			for _, expr := range e.Over.PartitionBy {
+				WalkExpr(expr, visit)
+			}
+			for _, term := range e.Over.OrderBy {
+				WalkExpr(term.Expr, visit)
+			}
+		}
+	case *InExpr:
+		WalkExpr(e.Operand, visit)
+		for _, expr := range e.List {
+			WalkExpr(expr, visit)
+		}
+	case *SubqueryExpr:
+		WalkExpr(e.Operand, visit)
+	case *CastExpr:
+		WalkExpr(e.Operand, visit)
+	case *ExtractExpr:
+		WalkExpr(e.Operand, visit)
+	case *CollateExpr:
+		WalkExpr(e.Operand, visit)
+	case *CaseExpr:
+		WalkExpr(e.Operand, visit)
+		for _, when := range e.Whens {
+			WalkExpr(when.When, visit)
+			WalkExpr(when.Then, visit)
+		}
+		WalkExpr(e.Else, visit)
+	}
+}
+
+// RewriteExpr rebuilds an expression tree from the bottom up, replacing each node with what
+// rewrite returns for it. The original tree is left as it was. Like WalkExpr, it doesn't go into subqueries
+func RewriteExpr(expr Expr, rewrite func(Expr) Expr) Expr {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *UnaryExpr:
+		expr = &UnaryExpr{Operator: e.Operator, Operand: RewriteExpr(e.Operand, rewrite)}
+	case *BinaryExpr:
+		expr = &BinaryExpr{Operator: e.Operator, Left: RewriteExpr(e.Left, rewrite), Right: RewriteExpr(e.Right, rewrite)}
+	case *IsNullExpr:
+		expr = &IsNullExpr{Operand: RewriteExpr(e.Operand, rewrite), Not: e.Not}
+	case *PatternExpr:
+		expr = &PatternExpr{Operator: e.Operator, Operand: RewriteExpr(e.Operand, rewrite), Pattern: RewriteExpr(e.Pattern, rewrite),
+			Escape: RewriteExpr(e.Escape, rewrite), Not: e.Not}
+	case *FuncCall:
+		call := &FuncCall{Name: e.Name, Distinct: e.Distinct}
+		for _, arg := range e.Args {
+			call.Args = append(call.Args, RewriteExpr(arg, rewrite))
+		}
+		if e.Over != nil {
+			call.Over = &Window{Frame: e.Over.Frame}
+			for _, expr := range e.Over.PartitionBy {
+				call.Over.PartitionBy = append(call.Over.PartitionBy, RewriteExpr(expr, rewrite))
+			}
+			for _, term := range e.Over.OrderBy {
+				call.Over.OrderBy = append(call.Over.OrderBy, OrderingTerm{Expr: RewriteExpr(term.Expr, rewrite), Descending: term.Descending})
+			}
+		}
+		expr = call
+	case *InExpr:
+		in := &InExpr{Operand: RewriteExpr(e.Operand, rewrite)}
+		for _, item := range e.List {
+			in.List = append(in.List, RewriteExpr(item, rewrite))
+		}
+		expr = in
+	case *SubqueryExpr:
+		expr = &SubqueryExpr{Kind: e.Kind, Operand: RewriteExpr(e.Operand, rewrite), Query: e.Query}
+	case *CastExpr:
+		expr = &CastExpr{Operand: RewriteExpr(e.Operand, rewrite), TypeName: e.TypeName}
+	case *ExtractExpr:
+		expr = &ExtractExpr{Field: e.Field, Operand: RewriteExpr(e.Operand, rewrite)}
+	case *CollateExpr:
+		expr = &CollateExpr{Operand: RewriteExpr(e.Operand, rewrite), Collation: e.Collation}
+	case *CaseExpr:
+		c := &CaseExpr{Operand: RewriteExpr(e.Operand, rewrite), Else: RewriteExpr(e.Else, rewrite)}
+		for _, when := range e.Whens {
+			c.Whens = append(c.Whens, WhenClause{When: RewriteExpr(when.When, rewrite), Then: RewriteExpr(when.Then, rewrite)})
+		}
+		expr = c
+	}
+
+	return rewrite(expr)
+}
+
+// operandString parenthesizes nested operators, so String keeps an expression's grouping
+func operandString(expr Expr) string {
+	if _, ok := expr.(*BinaryExpr); ok {
+		return "(" + expr.String() + ")"
+	}
+	return expr.String()
+}
+
+func (p *queryParser) done() bool {
+	return p.position >= len(p.tokens)
+}
+
+// peek returns the current lexeme without consuming it, an empty token past the end
+func (p *queryParser) peek() tokenizer.Token {
+	return p.peekAt(0)
+}
+
+func (p *queryParser) peekAt(offset int) tokenizer.Token {
+	if p.position+offset >= len(p.tokens) {
+		return tokenizer.Token{}
+	}
+	return p.tokens[p.position+offset]
+}
+
+func (p *queryParser) next() tokenizer.Token {
+	token := p.peek()
+	p.position++
+	return token
+}
+
+// isQueryAt checks if a query starts at a lexeme, with SELECT or WITH
+func (p *queryParser) isQueryAt(offset int) bool {
+	token := p.peekAt(offset)
+	return token.Name == tokenizer.Word && (strings.EqualFold(token.Special, "SELECT") || strings.EqualFold(token.Special, "WITH"))
+}
+
+func (p *queryParser) isKeyword(word string) bool {
+	return p.peek().Name == tokenizer.Word && strings.EqualFold(p.peek().Special, word)
+}
+
+func (p *queryParser) acceptKeyword(word string) bool {
+	if p.isKeyword(word) {
+		p.position++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expectKeyword(word string) error {
+	if p.acceptKeyword(word) == false {
+		return errors.New("!Failed to parse query because " + word + " was expected " + p.describePosition() + ".")
+	}
+	return nil
+}
+
+func (p *queryParser) isSymbol(symbol string) bool {
+	return p.peek().Name == tokenizer.Symbol && p.peek().Special == symbol
+}
+
+func (p *queryParser) acceptSymbol(symbol string) bool {
+	if p.isSymbol(symbol) {
+		p.position++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expectSymbol(symbol string) error {
+	if p.acceptSymbol(symbol) == false {
+		return errors.New("!Failed to parse query because " + symbol + " was expected " + p.describePosition() + ".")
+	}
+	return nil
+}
+
+func (p *queryParser) unexpected() error {
+	if p.done() {
+		return errors.New("!Failed to parse query because it ends unexpectedly.")
+	}
+	return errors.New("!Failed to parse query because of unexpected " + p.peek().Special + ".")
+}
+
+func (p *queryParser) describePosition() string {
+	if p.done() {
+		return "at the end"
+	}
+	return "before " + p.peek().Special
+}
@@ -0,0 +1,132 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"sqlit/tokenizer"
+)
+
+// Update is the parse tree of UPDATE table SET column = value {, column = value} [WHERE condition].
+// Each value is an expression of the row as it was before the update, like price * 2 or UPPER(name).
+// Without a condition, every row is updated
+type Update struct {
+	Table       string
+	Assignments []Assignment
+	Where       Expr
+
+	// Parameters is how many parameters the statement takes when it's prepared
+	Parameters int
+}
+
+// Tables lists every table the subqueries of an update's values and condition read
+func (update *Update) Tables() []string {
+	exprs := []Expr{update.Where}
+	for _, assignment := range update.Assignments {
+		exprs = append(exprs, assignment.Value)
+	}
+	return SubqueryTables(exprs...)
+}
+
+// Assignment is column = value, one column an UPDATE sets
+type Assignment struct {
+	Column string
+	Value  Expr
+}
+
+// ParseUpdate parses the raw SQL of an UPDATE statement
+func ParseUpdate(raw string) (*Update, error) {
+	tokens, err := tokenizer.Lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	update := &Update{}
+
+	err = p.expectKeyword("UPDATE")
+	if err != nil {
+		return nil, err
+	}
+
+	update.Table, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("SET")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		column, value, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		update.Assignments = append(update.Assignments, Assignment{Column: column, Value: value})
+
+		if p.acceptSymbol(",") == false {
+			break
+		}
+	}
+
+	if p.acceptKeyword("WHERE") {
+		update.Where, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.done() == false {
+		return nil, p.unexpected()
+	}
+
+	update.Parameters = p.parameters
+	return update, nil
+}
+
+// Bind makes a copy of an update with each parameter replaced by the value bound to it,
+// including the parameters of its subqueries
+func (update *Update) Bind(args []*Literal) *Update {
+	bound := update.Rewrite(bindArgs(args))
+	bound.Parameters = 0
+	return bound
+}
+
+// Rewrite makes a copy of an update with its assignments and condition rewritten by RewriteExpr
+func (update *Update) Rewrite(rewrite func(Expr) Expr) *Update {
+	rewritten := *update
+	rewritten.Assignments = nil
+	for _, assignment := range update.Assignments {
+		rewritten.Assignments = append(rewritten.Assignments, Assignment{Column: assignment.Column, Value: RewriteExpr(assignment.Value, rewrite)})
+	}
+	rewritten.Where = RewriteExpr(update.Where, rewrite)
+	return &rewritten
+}
+
+//
+//			Helper functions
+//
+
+// @in		column = expr
+func (p *queryParser) parseAssignment() (string, Expr, error) {
+	column, err := p.parseName()
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = p.expectSymbol("=")
+	if err != nil {
+		return "", nil, err
+	}
+
+	value, err := p.parseExpr()
+	return column, value, err
+}
+
+func (p *queryParser) parseName() (string, error) {
+	if p.peek().Name != tokenizer.Word {
+		return "", p.unexpected()
+	}
+	return p.next().Special, nil
+}
@@ -0,0 +1,221 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package parser
+
+import (
+	"errors"
+	"sqlit/tokenizer"
+	"strconv"
+	"strings"
+)
+
+// A Window is the OVER clause of a window function: the rows of each partition, in order.
+// Without a Frame, an aggregate folds the partition up to the current row and its peers
+// if it's ordered, or the whole partition if it isn't
+type Window struct {
+	PartitionBy []Expr
+	OrderBy     []OrderingTerm
+	Frame       *Frame
+}
+
+// A Frame is ROWS BETWEEN Start AND End, the rows around the current one an aggregate folds
+type Frame struct {
+	Start FrameBound
+	End   FrameBound
+}
+
+// A FrameBound is UNBOUNDED PRECEDING, n PRECEDING, CURRENT ROW, n FOLLOWING or UNBOUNDED FOLLOWING,
+// Offset is n, and is negative for PRECEDING
+type FrameBound struct {
+	Unbounded bool
+	Offset    int
+}
+
+// WindowFunctions are the functions that only work over a window, besides the aggregates
+var WindowFunctions = map[string]bool{
+	"ROW_NUMBER": true,
+	"RANK":       true,
+	"DENSE_RANK": true,
+	"LAG":        true,
+	"LEAD":       true,
+}
+
+func (w *Window) String() string {
+	var parts []string
+
+	if len(w.PartitionBy) > 0 {
+		var partitionBy []string
+		for _, expr := range w.PartitionBy {
+			partitionBy = append(partitionBy, expr.String())
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(partitionBy, ", "))
+	}
+
+	if len(w.OrderBy) > 0 {
+		var orderBy []string
+		for _, term := range w.OrderBy {
+			if term.Descending {
+				orderBy = append(orderBy, term.Expr.String()+" DESC")
+			} else {
+				orderBy = append(orderBy, term.Expr.String())
+			}
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(orderBy, ", "))
+	}
+
+	if w.Frame != nil {
+		parts = append(parts, "ROWS BETWEEN "+w.Frame.Start.String()+" AND "+w.Frame.End.String())
+	}
+
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func (b FrameBound) String() string {
+	switch {
+	case b.Unbounded && b.Offset < 0:
+		return "UNBOUNDED PRECEDING"
+	case b.Unbounded:
+		return "UNBOUNDED FOLLOWING"
+	case b.Offset < 0:
+		return strconv.Itoa(-b.Offset) + " PRECEDING"
+	case b.Offset > 0:
+		return strconv.Itoa(b.Offset) + " FOLLOWING"
+	}
+	return "CURRENT ROW"
+}
+
+// HasWindow checks if an expression calls a window function
+func HasWindow(expr Expr) bool {
+	found := false
+	WalkExpr(expr, func(e Expr) {
+		if call, ok := e.(*FuncCall); ok && call.Over != nil {
+			found = true
+		}
+	})
+	return found
+}
+
+// @in		( [PARTITION BY expr {, expr}] [ORDER BY expr [ASC | DESC] {, ...}] [frame] )	with OVER already consumed
+func (p *queryParser) parseWindow() (*Window, error) {
+	err := p.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+
+	window := &Window{}
+
+	if p.acceptKeyword("PARTITION") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			window.PartitionBy = append(window.PartitionBy, expr)
+
+			if p.acceptSymbol(",") == false {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("ORDER") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return nil, err
+		}
+
+		window.OrderBy, err = p.parseOrderingTerms()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isKeyword("ROWS") || p.isKeyword("RANGE") {
+		window.Frame, err = p.parseFrame()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return window, p.expectSymbol(")")
+}
+
+// @in		ROWS start | ROWS BETWEEN start AND end
+func (p *queryParser) parseFrame() (*Frame, error) {
+	if p.acceptKeyword("ROWS") == false {
+		return nil, errors.New("!Failed to parse query because only ROWS frames are supported.")
+	}
+
+	// ROWS start is short for ROWS BETWEEN start AND CURRENT ROW
+	if p.acceptKeyword("BETWEEN") == false {
+		start, err := p.parseFrameBound()
+		if err != nil {
+			return nil, err
+		}
+		return &Frame{Start: start}, nil
+	}
+
+	start, err := p.parseFrameBound()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("AND")
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := p.parseFrameBound()
+	if err != nil {
+		return nil, err
+	}
+
+	if start.Unbounded && start.Offset > 0 || end.Unbounded && end.Offset < 0 {
+		return nil, errors.New("!Failed to parse query because ROWS BETWEEN " + start.String() + " AND " + end.String() + " is backwards.")
+	}
+
+	return &Frame{Start: start, End: end}, nil
+}
+
+// @in		UNBOUNDED PRECEDING | UNBOUNDED FOLLOWING | CURRENT ROW | n PRECEDING | n FOLLOWING
+func (p *queryParser) parseFrameBound() (FrameBound, error) {
+	bound := FrameBound{}
+	sign := 1
+
+	switch {
+	case p.acceptKeyword("CURRENT"):
+		return bound, p.expectKeyword("ROW")
+
+	case p.acceptKeyword("UNBOUNDED"):
+		bound.Unbounded = true
+		bound.Offset = 1
+
+	case p.peek().Name == tokenizer.Number:
+		offset, err := strconv.Atoi(p.peek().Special)
+		if err != nil || offset < 0 {
+			return bound, errors.New("!Failed to parse query because " + p.peek().Special + " is not a number of rows.")
+		}
+		p.next()
+		bound.Offset = offset
+
+	default:
+		return bound, p.unexpected()
+	}
+
+	if p.acceptKeyword("PRECEDING") {
+		sign = -1
+	} else {
+		err := p.expectKeyword("FOLLOWING")
+		if err != nil {
+			return bound, err
+		}
+	}
+
+	bound.Offset *= sign
+	return bound, nil
+}
@@ -0,0 +1,229 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+// Package sqldriver registers sqlit with database/sql under the name "sqlit".
+// The data source name is the path of a database inside a data directory,
+// each connection is its own engine session already using that database:
+//
+//	import _ "sqlit/sqldriver"
+//
+//	db, err := sql.Open("sqlit", "/path/to/tmp/mydb")
+//
+// Writes in a sql.Tx are queued until it commits, so their Result's
+// RowsAffected and LastInsertId return an error, and the transaction's
+// own queries don't see them.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"path/filepath"
+	"sqlit/diskio"
+	"sqlit/engine"
+	"strings"
+)
+
+func init() {
+	sql.Register("sqlit", &Driver{})
+}
+
+// Driver opens connections to sqlit databases
+type Driver struct{}
+
+// Open starts a session on the data directory holding the named database, and uses it
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	dir := filepath.Dir(filepath.Clean(name))
+	database := filepath.Base(filepath.Clean(name))
+
+	db, err := engine.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec("USE " + database)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{db: db}, nil
+}
+
+// conn is a single engine session
+type conn struct {
+	db *engine.DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{s}, nil
+}
+
+func (c *conn) Close() error {
+	return c.db.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction, switching the session to the requested isolation level first
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		return nil, errors.New("!Failed to begin transaction because read only transactions are not supported.")
+	}
+
+	if level, ok := isolationLevels[sql.IsolationLevel(opts.Isolation)]; ok {
+		_, err := c.db.Exec("SET TRANSACTION ISOLATION LEVEL " + level)
+		if err != nil {
+			return nil, err
+		}
+	} else if sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		return nil, errors.New("!Failed to begin transaction because isolation level " + sql.IsolationLevel(opts.Isolation).String() + " is not supported.")
+	}
+
+	t, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx{t}, nil
+}
+
+// isolationLevels maps database/sql's isolation levels onto the ones diskio enforces
+var isolationLevels = map[sql.IsolationLevel]string{
+	sql.LevelReadCommitted:  diskio.ReadCommitted,
+	sql.LevelRepeatableRead: diskio.RepeatableRead,
+	sql.LevelSerializable:   diskio.Serializable,
+}
+
+// tx is a transaction on a connection's session
+type tx struct {
+	tx *engine.Tx
+}
+
+func (t *tx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// stmt is a prepared statement, its parameters are ? or $1, $2 and so on
+type stmt struct {
+	stmt *engine.Stmt
+}
+
+func (s *stmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *stmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	r, err := s.stmt.Exec(values(args)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Queued {
+		return queuedResult{}, nil
+	}
+	return result{r}, nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	r, err := s.stmt.Query(values(args)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows{r}, nil
+}
+
+// result reports what an engine statement did
+type result struct {
+	result engine.Result
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.result.LastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.result.RowsAffected, nil
+}
+
+// queuedResult is the result of a write queued in a transaction, which won't know what it did until it commits
+type queuedResult struct{}
+
+func (queuedResult) LastInsertId() (int64, error) {
+	return 0, errors.New("!Failed to get the last insert id because the statement is queued until its transaction commits.")
+}
+
+func (queuedResult) RowsAffected() (int64, error) {
+	return 0, errors.New("!Failed to count the rows affected because the statement is queued until its transaction commits.")
+}
+
+// rows adapts an engine cursor to database/sql
+type rows struct {
+	rows *engine.Rows
+}
+
+func (r *rows) Columns() []string {
+	var names []string
+	for _, column := range r.rows.Columns() {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// ColumnTypeDatabaseTypeName reports a column's declared type, e.g. "VARCHAR(20)"
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return strings.ToUpper(r.rows.Columns()[index].Type)
+}
+
+func (r *rows) Close() error {
+	return r.rows.Close()
+}
+
+// Next reads the next record into dest, io.EOF means there are none left and anything else that reading one failed
+func (r *rows) Next(dest []driver.Value) error {
+	if r.rows.Next() == false {
+		if r.rows.Err() != nil {
+			return r.rows.Err()
+		}
+		return io.EOF
+	}
+
+	values, err := r.rows.Values()
+	if err != nil {
+		return err
+	}
+
+	for i := range dest {
+		dest[i] = values[i]
+	}
+
+	return nil
+}
+
+//
+//			Helper functions
+//
+
+func values(args []driver.Value) []interface{} {
+	var values []interface{}
+	for _, arg := range args {
+		values = append(values, arg)
+	}
+	return values
+}
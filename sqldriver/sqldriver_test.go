@@ -0,0 +1,230 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sqlit/engine"
+	"sync"
+	"testing"
+)
+
+// openTestDB creates a database with a table t, and opens it through database/sql
+func openTestDB(t *testing.T) *sql.DB {
+	dir, err := ioutil.TempDir("", "sqlit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	setup, err := engine.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"CREATE DATABASE test", "USE test", "CREATE TABLE t (id int, name varchar(20))"} {
+		if _, err := setup.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setup.Close()
+
+	db, err := sql.Open("sqlit", filepath.Join(dir, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// Statements report what they did, and their rows scan into Go values
+func TestExecAndQuery(t *testing.T) {
+	db := openTestDB(t)
+
+	result, err := db.Exec("INSERT INTO t VALUES (?, ?), (?, ?)", 1, "one", 2, "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 2 {
+		t.Errorf("inserted %d records, expected 2", affected)
+	}
+	if id, _ := result.LastInsertId(); id != 2 {
+		t.Errorf("last insert id is %d, expected 2", id)
+	}
+
+	var name string
+	err = db.QueryRow("SELECT name FROM t WHERE id = ?", 2).Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "two" {
+		t.Errorf("read back %q, expected \"two\"", name)
+	}
+
+	if _, err := db.Exec("INSERT INTO missing VALUES (1)"); err == nil {
+		t.Errorf("inserting into a missing table should fail")
+	}
+}
+
+// A rolled back transaction leaves nothing behind, a committed one applies its writes
+func TestTransactions(t *testing.T) {
+	db := openTestDB(t)
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO t VALUES (1, 'rolled back')"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err = db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := tx.Exec("INSERT INTO t VALUES (2, 'committed')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queued.RowsAffected(); err == nil {
+		t.Errorf("a queued write reported the rows it affects before it ran")
+	}
+	if _, err := queued.LastInsertId(); err == nil {
+		t.Errorf("a queued write reported its insert id before it ran")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("table has %d records, expected 1", count)
+	}
+
+	if _, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true}); err == nil {
+		t.Errorf("read only transactions should fail")
+	}
+}
+
+// A transaction cancelled by its context is rolled back from another goroutine while it's still in use
+func TestCancelledTransaction(t *testing.T) {
+	db := openTestDB(t)
+	db.SetMaxOpenConns(1)
+
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx.ExecContext(ctx, "INSERT INTO t VALUES (1, 'cancelled')")
+			tx.Commit()
+		}()
+		cancel()
+		wg.Wait()
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM t").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count > 20 {
+		t.Errorf("table has %d records, expected at most 20", count)
+	}
+}
+
+// Columns report their names and declared types, and NULLs scan as invalid
+func TestColumnsAndNulls(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec("INSERT INTO t VALUES (1, NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query("SELECT id, name FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types[0].Name() != "id" || types[0].DatabaseTypeName() != "INT" || types[1].DatabaseTypeName() != "VARCHAR(20)" {
+		t.Errorf("columns are %s %s, %s %s", types[0].Name(), types[0].DatabaseTypeName(), types[1].Name(), types[1].DatabaseTypeName())
+	}
+
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		if id != 1 || name.Valid {
+			t.Errorf("read back %d %v, expected 1 and NULL", id, name)
+		}
+	}
+}
+
+// A data source name whose database doesn't exist can't be connected to
+func TestOpenMissingDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlit", filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err == nil {
+		t.Errorf("connecting to a missing database should fail")
+	}
+}
+
+// A query that fails part way through its rows reports why, rather than ending early
+func TestRowsReportErrors(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec("INSERT INTO t VALUES (1, '1'), (2, 'two')"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{
+		"SELECT CAST(name AS int) FROM t ORDER BY id",
+		"SELECT id FROM t WHERE id = (SELECT id FROM t)",
+	} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for rows.Next() {
+		}
+		if rows.Err() == nil {
+			t.Errorf("%s: the error reading its rows was dropped", query)
+		}
+		rows.Close()
+	}
+}
@@ -0,0 +1,65 @@
+-- COLLATE and LIKE
+
+CREATE DATABASE CS457_COLLATE;
+USE CS457_COLLATE;
+CREATE TABLE Person (id int, name varchar(20) COLLATE BINARY, nick varchar(20), code varchar(10) COLLATE RTRIM);
+INSERT INTO Person VALUES (1, 'Joe', 'JoJo', 'ab  '), (2, 'joe', 'jojo', 'AB'), (3, 'Ann', 'annie', 'ab');
+SELECT id FROM Person WHERE name = 'joe';
+SELECT id FROM Person WHERE name = 'joe' COLLATE NOCASE;
+SELECT id FROM Person WHERE nick = 'JOJO';
+SELECT id FROM Person WHERE code = 'ab';
+SELECT id FROM Person WHERE name LIKE 'j%';
+SELECT id FROM Person WHERE name ILIKE 'j%';
+SELECT id FROM Person WHERE name LIKE 'j%' COLLATE NOCASE;
+SELECT id FROM Person WHERE nick LIKE 'JO%';
+SELECT id FROM Person WHERE nick LIKE 'JO%' COLLATE BINARY;
+SELECT id FROM Person WHERE code LIKE '%b';
+SELECT id FROM Person WHERE name NOT LIKE 'J%';
+SELECT name FROM Person ORDER BY name;
+SELECT id FROM Person WHERE name = 'joe' COLLATE UNICODE;
+CREATE TABLE Bad (name varchar(20) COLLATE UNICODE);
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_COLLATE created.
+-- Using database CS457_COLLATE
+-- Table Person created.
+-- 3 new records inserted.
+-- id int
+-- 2
+-- id int
+-- 1
+-- 2
+-- id int
+-- 1
+-- 2
+-- id int
+-- 1
+-- 3
+-- id int
+-- 2
+-- id int
+-- 1
+-- 2
+-- id int
+-- 1
+-- 2
+-- id int
+-- 1
+-- 2
+-- id int
+-- id int
+-- 1
+-- 3
+-- id int
+-- 2
+-- 3
+-- name varchar(20)
+-- Ann
+-- Joe
+-- joe
+-- !Failed to query because UNICODE isn't a collation.
+-- !Failed to create table Bad because UNICODE isn't a collation.
+-- All done.
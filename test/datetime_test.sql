@@ -0,0 +1,50 @@
+-- DATE, TIME and TIMESTAMP
+
+CREATE DATABASE CS457_DATETIME;
+USE CS457_DATETIME;
+CREATE TABLE Shift (id int, day date, starts time, logged timestamp);
+INSERT INTO Shift VALUES (1, '2024-01-31', '09:30', '2024-01-31 17:45:10'), (2, '2019-04-01', '13:45:00', '2019-04-01T13:45:00Z');
+INSERT INTO Shift VALUES (3, '2019-02-30', '10:00', '2019-02-28 10:00');
+INSERT INTO Shift VALUES (3, '2019-02-28', '25:00', '2019-02-28 10:00');
+SELECT * FROM Shift;
+SELECT id FROM Shift WHERE day > '2020-01-01';
+SELECT id, DATE_ADD(day, 1, 'month'), DATE_ADD(day, 1, 'year'), DATE_ADD(day, -2, 'day') FROM Shift ORDER BY id;
+SELECT DATE_ADD(logged, 1, 'month'), DATE_ADD(starts, 90, 'minute') FROM Shift WHERE id = 1;
+SELECT DATE_ADD(CAST('2024-02-29' AS date), 1, 'year'), DATE_ADD(CAST('2024-03-31' AS date), -1, 'month') FROM Shift WHERE id = 1;
+SELECT DATE_ADD(day, 1, 'hour') FROM Shift;
+SELECT DATE_ADD(starts, 1, 'day') FROM Shift;
+SELECT DATE_ADD(day, 1, 'fortnight') FROM Shift;
+SELECT STRFTIME('%d/%m/%Y %H:%M', logged) FROM Shift ORDER BY id;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_DATETIME created.
+-- Using database CS457_DATETIME
+-- Table Shift created.
+-- 2 new records inserted.
+-- !Failed to write '2019-02-30' to a date column because it is not a valid date.
+-- !Failed to write '25:00' to a time column because it is not a valid time.
+-- id int|day date|starts time|logged timestamp
+-- 1|2024-01-31|09:30:00|2024-01-31 17:45:10
+-- 2|2019-04-01|13:45:00|2019-04-01 13:45:00
+-- id int
+-- 1
+-- id int|DATE_ADD(day, 1, 'month') date|DATE_ADD(day, 1, 'year') date|DATE_ADD(day, -2, 'day') date
+-- 1|2024-02-29|2025-01-31|2024-01-29
+-- 2|2019-05-01|2020-04-01|2019-03-30
+-- DATE_ADD(logged, 1, 'month') timestamp|DATE_ADD(starts, 90, 'minute') time
+-- 2024-02-29 17:45:10|11:00:00
+-- DATE_ADD(CAST('2024-02-29' AS date), 1, 'year') date|DATE_ADD(CAST('2024-03-31' AS date), -1, 'month') date
+-- 2025-02-28|2024-02-29
+-- DATE_ADD(day, 1, 'hour') date
+-- !Failed to query because a date can't be moved by hours, it has to be cast to a timestamp first.
+-- DATE_ADD(starts, 1, 'day') time
+-- !Failed to query because a time can't be moved by days, it has to be cast to a timestamp first.
+-- DATE_ADD(day, 1, 'fortnight') date
+-- !Failed to query because fortnight isn't a unit of time.
+-- STRFTIME('%d/%m/%Y %H:%M', logged) varchar
+-- 31/01/2024 17:45
+-- 01/04/2019 13:45
+-- All done.
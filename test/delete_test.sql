@@ -0,0 +1,45 @@
+-- DELETE and TRUNCATE
+
+CREATE DATABASE CS457_DELETE;
+USE CS457_DELETE;
+CREATE TABLE Product (pid int, name varchar(20), price float);
+INSERT INTO Product VALUES (1, 'Gizmo', 19.99), (2, 'PowerGizmo', 29.99), (3, 'SingleTouch', 149.99), (4, 'MultiTouch', 199.99);
+DELETE FROM Product WHERE price > 150 OR name = 'Gizmo';
+DELETE FROM Product WHERE nope = 1;
+DELETE FROM Missing;
+SELECT * FROM Product;
+DELETE FROM Product;
+SELECT * FROM Product;
+INSERT INTO Product VALUES (5, 'SuperGizmo', 49.99);
+TRUNCATE TABLE Product;
+TRUNCATE Missing;
+TRUNCATE TABLE;
+SELECT * FROM Product;
+INSERT INTO Product VALUES (6, 'Again', 9.99);
+SELECT * FROM Product;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_DELETE created.
+-- Using database CS457_DELETE
+-- Table Product created.
+-- 4 new records inserted.
+-- 2 record(s) deleted.
+-- !Failed to query because column nope does not exist.
+-- !Failed to query table Missing because it does not exist.
+-- pid int|name varchar(20)|price float
+-- 2|PowerGizmo|29.99
+-- 3|SingleTouch|149.99
+-- 2 record(s) deleted.
+-- pid int|name varchar(20)|price float
+-- 1 new record inserted.
+-- Table Product truncated.
+-- !Failed to truncate table Missing because it does not exist.
+-- !Failed to truncate table because no table is named.
+-- pid int|name varchar(20)|price float
+-- 1 new record inserted.
+-- pid int|name varchar(20)|price float
+-- 6|Again|9.99
+-- All done.
@@ -0,0 +1,48 @@
+-- SELECT DISTINCT and COUNT(DISTINCT col)
+
+CREATE DATABASE CS457_DISTINCT;
+USE CS457_DISTINCT;
+CREATE TABLE Sale (id int, region varchar(10), product varchar(10), amount float);
+INSERT INTO Sale VALUES (1, 'East', 'Gizmo', 10), (2, 'East', 'Gizmo', 10), (3, 'West', 'Gizmo', 20), (4, 'West', 'Widget', NULL), (5, 'East', 'Widget', 10), (6, NULL, 'Widget', 5);
+SELECT DISTINCT COALESCE(region, 'none') AS region FROM Sale ORDER BY region;
+SELECT DISTINCT region, product FROM Sale ORDER BY region, product;
+SELECT DISTINCT amount, amount IS NULL FROM Sale ORDER BY amount;
+SELECT COUNT(*), COUNT(region), COUNT(DISTINCT region), COUNT(DISTINCT amount) FROM Sale;
+SELECT region, COUNT(DISTINCT product), SUM(DISTINCT amount), SUM(amount) FROM Sale GROUP BY region ORDER BY region;
+SELECT COUNT(DISTINCT *) FROM Sale;
+SELECT UPPER(DISTINCT region) FROM Sale;
+SELECT DISTINCT nope FROM Sale;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_DISTINCT created.
+-- Using database CS457_DISTINCT
+-- Table Sale created.
+-- 6 new records inserted.
+-- region varchar
+-- East
+-- none
+-- West
+-- region varchar(10)|product varchar(10)
+-- |Widget
+-- East|Gizmo
+-- East|Widget
+-- West|Gizmo
+-- West|Widget
+-- amount float|amount IS NULL int
+-- |1
+-- 5|0
+-- 10|0
+-- 20|0
+-- COUNT(*) int|COUNT(region) int|COUNT(DISTINCT region) int|COUNT(DISTINCT amount) int
+-- 6|5|2|3
+-- region varchar(10)|COUNT(DISTINCT product) int|SUM(DISTINCT amount) float|SUM(amount) float
+-- |1|5|5
+-- East|2|10|30
+-- West|2|20|20
+-- !Failed to parse query because COUNT(DISTINCT *) can't be used.
+-- !Failed to parse query because DISTINCT can't be used in UPPER.
+-- !Failed to query because column nope does not exist.
+-- All done.
@@ -0,0 +1,47 @@
+-- EXPLAIN and EXPLAIN ANALYZE
+
+CREATE DATABASE CS457_EXPLAIN;
+USE CS457_EXPLAIN;
+CREATE TABLE Employee (id int, name varchar(10), dept int, salary float);
+CREATE TABLE Dept (id int, title varchar(10));
+INSERT INTO Employee VALUES (1, 'Joe', 1, 50000), (2, 'Amy', 1, 65000), (3, 'Gus', 2, 42000), (4, 'Zed', 3, 30000);
+INSERT INTO Dept VALUES (1, 'Sales'), (2, 'Ops');
+EXPLAIN SELECT name FROM Employee WHERE salary > 40000 ORDER BY name;
+EXPLAIN SELECT e.name, d.title FROM Employee e LEFT JOIN Dept d ON e.dept = d.id;
+EXPLAIN SELECT dept, COUNT(*) FROM Employee GROUP BY dept;
+EXPLAIN SELECT nope FROM Employee;
+EXPLAIN SELECT name FROM Missing;
+EXPLAIN DELETE FROM Employee;
+SELECT COUNT(*) FROM Employee;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_EXPLAIN created.
+-- Using database CS457_EXPLAIN
+-- Table Employee created.
+-- Table Dept created.
+-- 4 new records inserted.
+-- 2 new records inserted.
+-- plan varchar
+-- Project name  (estimated rows=2)
+--   -> Sort BY name  (estimated rows=2)
+--     -> Filter salary > 40000  (estimated rows=2)
+--       -> Full Scan Employee  (estimated rows=4)
+-- plan varchar
+-- Project name, title  (estimated rows=4)
+--   -> Nested Loop Left Join ON e.dept = d.id  (estimated rows=4)
+--     -> Full Scan Employee AS e  (estimated rows=4)
+--     -> Full Scan Dept AS d  (estimated rows=2)
+-- plan varchar
+-- Project dept, COUNT(*)  (estimated rows=1)
+--   -> Aggregate GROUP BY dept: COUNT(*)  (estimated rows=1)
+--     -> Sort BY dept  (estimated rows=4)
+--       -> Full Scan Employee  (estimated rows=4)
+-- !Failed to query because column nope does not exist.
+-- !Failed to query table Missing because it does not exist.
+-- !Failed to explain statement because only queries have a plan.
+-- COUNT(*) int
+-- 4
+-- All done.
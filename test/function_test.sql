@@ -0,0 +1,59 @@
+-- Functions, CAST and CASE
+
+CREATE DATABASE CS457_FUNCTION;
+USE CS457_FUNCTION;
+CREATE TABLE Product (pid int, name varchar(20), price float, note varchar(20));
+INSERT INTO Product VALUES (1, 'Gizmo', 19.99, '  spaced  '), (2, 'PowerGizmo', 29.5, NULL), (3, 'SingleTouch', -149.99, 'xxtouchxx');
+SELECT UPPER(name), LOWER(name), LENGTH(name), SUBSTR(name, 2, 3), SUBSTR(name, -5) FROM Product ORDER BY pid;
+SELECT '[' || TRIM(note) || ']', '[' || LTRIM(note) || ']', RTRIM(note, 'x'), REPLACE(name, 'Gizmo', 'Gadget') FROM Product WHERE pid <> 2 ORDER BY pid;
+SELECT ABS(price), ROUND(price), ROUND(price, 1), FLOOR(price), CEIL(price), MOD(pid, 2) FROM Product ORDER BY pid;
+SELECT COALESCE(note, name, 'none'), IFNULL(note, 'none'), NULLIF(pid, 2), UPPER(note) IS NULL FROM Product ORDER BY pid;
+SELECT CAST(price AS int), CAST(pid AS varchar(5)) || '!', CAST('42' AS float) + 1 FROM Product ORDER BY pid;
+SELECT name, CASE WHEN price > 20 THEN 'pricey' WHEN price > 0 THEN 'cheap' END, CASE pid WHEN 1 THEN 'one' WHEN 2 THEN 'two' ELSE 'many' END FROM Product ORDER BY LENGTH(name) DESC;
+UPDATE Product SET name = UPPER(SUBSTR(name, 1, 1)) || LOWER(SUBSTR(name, 2)) WHERE pid = 2;
+SELECT name FROM Product WHERE pid = 2;
+SELECT CAST(name AS int) FROM Product;
+SELECT NOPE(name) FROM Product;
+SELECT SUBSTR(name) FROM Product;
+SELECT CASE END FROM Product;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_FUNCTION created.
+-- Using database CS457_FUNCTION
+-- Table Product created.
+-- 3 new records inserted.
+-- UPPER(name) varchar|LOWER(name) varchar|LENGTH(name) int|SUBSTR(name, 2, 3) varchar|SUBSTR(name, -5) varchar
+-- GIZMO|gizmo|5|izm|Gizmo
+-- POWERGIZMO|powergizmo|10|owe|Gizmo
+-- SINGLETOUCH|singletouch|11|ing|Touch
+-- ('[' || TRIM(note)) || ']' varchar|('[' || LTRIM(note)) || ']' varchar|RTRIM(note, 'x') varchar|REPLACE(name, 'Gizmo', 'Gadget') varchar
+-- [spaced]|[spaced  ]|  spaced  |Gadget
+-- [xxtouchxx]|[xxtouchxx]|xxtouch|SingleTouch
+-- ABS(price) float|ROUND(price) float|ROUND(price, 1) float|FLOOR(price) int|CEIL(price) int|MOD(pid, 2) int
+-- 19.99|20|20|19|20|1
+-- 29.5|30|29.5|29|30|0
+-- 149.99|-150|-150|-150|-149|1
+-- COALESCE(note, name, 'none') varchar|IFNULL(note, 'none') varchar|NULLIF(pid, 2) int|UPPER(note) IS NULL int
+--   spaced  |  spaced  |1|0
+-- PowerGizmo|none||1
+-- xxtouchxx|xxtouchxx|3|0
+-- CAST(price AS int) int|CAST(pid AS varchar(5)) || '!' varchar|CAST('42' AS float) + 1 float
+-- 19|1!|43
+-- 29|2!|43
+-- -149|3!|43
+-- name varchar(20)|CASE WHEN price > 20 THEN 'pricey' WHEN price > 0 THEN 'cheap' END varchar|CASE pid WHEN 1 THEN 'one' WHEN 2 THEN 'two' ELSE 'many' END varchar
+-- SingleTouch||many
+-- PowerGizmo|pricey|two
+-- Gizmo|cheap|one
+-- 1 record(s) modified.
+-- name varchar(20)
+-- Powergizmo
+-- CAST(name AS int) int
+-- !Failed to query because 'Gizmo' can't be cast to int.
+-- !Failed to query because function NOPE does not exist.
+-- !Failed to query because SUBSTR takes 2 to 3 arguments.
+-- !Failed to parse query because of unexpected END.
+-- All done.
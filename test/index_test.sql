@@ -0,0 +1,60 @@
+-- Indexes and ANALYZE
+
+CREATE DATABASE CS457_INDEX;
+USE CS457_INDEX;
+CREATE TABLE Product (pid int, name varchar(20), price float);
+INSERT INTO Product VALUES (1, 'Gizmo', 19.99), (2, 'PowerGizmo', 29.99), (3, 'SingleTouch', 149.99), (4, 'MultiTouch', 203.99);
+CREATE INDEX ProductPrice ON Product (price);
+CREATE INDEX ProductPid ON Product ( pid );
+CREATE INDEX ProductPrice ON Product (price);
+CREATE INDEX ProductNope ON Product (nope);
+CREATE INDEX ProductMissing ON Missing (pid);
+CREATE INDEX ON Product (name);
+CREATE INDEX ProductName Product (name);
+CREATE INDEX ProductName ON Product;
+ANALYZE Product;
+EXPLAIN SELECT name FROM Product WHERE price = 29.99;
+SELECT name FROM Product WHERE price = 29.99;
+SELECT name FROM Product WHERE pid > 2;
+UPDATE Product SET price = 39.99 WHERE pid = 2;
+SELECT name FROM Product WHERE price = 39.99;
+DROP INDEX ProductPrice;
+DROP INDEX ProductPrice;
+DROP INDEX;
+SELECT name FROM Product WHERE price = 39.99;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_INDEX created.
+-- Using database CS457_INDEX
+-- Table Product created.
+-- 4 new records inserted.
+-- Index ProductPrice created.
+-- Index ProductPid created.
+-- !Failed to create index ProductPrice because it already exists.
+-- !Failed to create index ProductNope because column nope does not exist.
+-- !Failed to create index ProductMissing because table Missing does not exist.
+-- !Failed to parse statement because CREATE INDEX needs a name. Indexes are created with CREATE INDEX name ON table (column).
+-- !Failed to parse statement because ON was expected after CREATE INDEX ProductName. Indexes are created with CREATE INDEX name ON table (column).
+-- !Failed to parse statement because CREATE INDEX ProductName needs a table and a column to index. Indexes are created with CREATE INDEX name ON table (column).
+-- Table Product analyzed.
+-- plan varchar
+-- Project name  (estimated rows=1)
+--   -> Filter price = 29.99  (estimated rows=1)
+--     -> Full Scan Product  (estimated rows=4)
+-- name varchar(20)
+-- PowerGizmo
+-- name varchar(20)
+-- SingleTouch
+-- MultiTouch
+-- 1 record(s) modified.
+-- name varchar(20)
+-- PowerGizmo
+-- Index ProductPrice deleted.
+-- !Failed to delete index ProductPrice because it does not exist.
+-- !Failed to parse statement because DROP INDEX needs the name of an index.
+-- name varchar(20)
+-- PowerGizmo
+-- All done.
@@ -0,0 +1,59 @@
+-- INSERT
+
+CREATE DATABASE CS457_INSERT;
+USE CS457_INSERT;
+CREATE TABLE Product (pid int, name varchar(20), price float);
+CREATE TABLE Archive (pid int, name varchar(20), price float);
+INSERT INTO Product VALUES (1, 'Gizmo', 19.99), (2, 'PowerGizmo', 29.99), (3, 'SingleTouch', 149.99);
+INSERT INTO Product (name, pid) VALUES ('NoPrice', 4);
+INSERT INTO Product (pid, nope) VALUES (5, 'x');
+INSERT INTO Product (pid, pid) VALUES (5, 6);
+INSERT INTO Product VALUES (5, 'Short');
+SELECT * FROM Product;
+INSERT INTO Archive SELECT * FROM Product WHERE price > 20;
+INSERT INTO Archive (pid, name) SELECT pid, name FROM Product WHERE price IS NULL;
+INSERT INTO Archive SELECT pid, name FROM Product;
+INSERT INTO Archive SELECT pid, nope, price FROM Product;
+INSERT INTO Archive SELECT * FROM Missing;
+SELECT * FROM Archive;
+BEGIN TRANSACTION;
+INSERT INTO Archive VALUES (6, 'Queued', 1.5);
+INSERT INTO Archive SELECT pid, nope, price FROM Product;
+COMMIT;
+SELECT * FROM Archive;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_INSERT created.
+-- Using database CS457_INSERT
+-- Table Product created.
+-- Table Archive created.
+-- 3 new records inserted.
+-- 1 new record inserted.
+-- !Failed to insert into table Product because it has no column nope.
+-- !Failed to insert into table Product because column pid is named twice.
+-- !Failed to insert into table Product because a row has 2 value(s) for 3 column(s).
+-- pid int|name varchar(20)|price float
+-- 1|Gizmo|19.99
+-- 2|PowerGizmo|29.99
+-- 3|SingleTouch|149.99
+-- 4|NoPrice|
+-- 2 new records inserted.
+-- 1 new record inserted.
+-- !Failed to insert into table Archive because a row has 2 value(s) for 3 column(s).
+-- !Failed to query because column nope does not exist.
+-- !Failed to query table Missing because it does not exist.
+-- pid int|name varchar(20)|price float
+-- 2|PowerGizmo|29.99
+-- 3|SingleTouch|149.99
+-- 4|NoPrice|
+-- Transaction starts.
+-- !Failed to query because column nope does not exist.
+-- Transaction abort.
+-- pid int|name varchar(20)|price float
+-- 2|PowerGizmo|29.99
+-- 3|SingleTouch|149.99
+-- 4|NoPrice|
+-- All done.
@@ -0,0 +1,67 @@
+-- Isolation levels
+
+-- This script includes the commands to be executed by two processes, P1 and P2
+
+-- On P1:
+CREATE DATABASE CS457_ISOLATION;
+USE CS457_ISOLATION;
+create table Seats(seat int, status int);
+insert into Seats values(22,0);
+set transaction isolation level snapshot;
+set transaction isolation level repeatable read;
+begin transaction;
+set transaction isolation level serializable; -- only between transactions
+select * from Seats; -- holds a shared lock until commit
+
+-- On P2:
+USE CS457_ISOLATION;
+select * from Seats; -- reads under READ COMMITTED take no lock
+update Seats set status = 2 where seat = 22;
+set transaction isolation level serializable;
+begin transaction;
+select * from Seats; -- reads under SERIALIZABLE need an exclusive lock
+commit; --there should be nothing to commit; it's an "abort"
+
+-- On P1:
+commit;
+set transaction isolation level read committed;
+begin transaction;
+select * from Seats;
+
+-- On P2:
+update Seats set status = 2 where seat = 22;
+select * from Seats;
+
+---------------------
+-- Expected output --
+---------------------
+
+-- On P1:
+-- Database CS457_ISOLATION created.
+-- Using database CS457_ISOLATION
+-- Table Seats created.
+-- 1 new record inserted.
+-- !Failed to set isolation level because SNAPSHOT is not supported.
+-- Isolation level set to REPEATABLE READ.
+-- Transaction starts.
+-- !Failed to set isolation level because a transaction is in progress.
+-- seat int|status int
+-- 22|0
+-- Transaction committed.
+-- Isolation level set to READ COMMITTED.
+-- Transaction starts.
+-- seat int|status int
+-- 22|0
+
+-- On P2:
+-- Using database CS457_ISOLATION
+-- seat int|status int
+-- 22|0
+-- Error: Table Seats is locked!
+-- Isolation level set to SERIALIZABLE.
+-- Transaction starts.
+-- Error: Table Seats is locked!
+-- Transaction abort.
+-- 1 record(s) modified.
+-- seat int|status int
+-- 22|2
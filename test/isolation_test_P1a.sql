@@ -0,0 +1,9 @@
+CREATE DATABASE CS457_ISOLATION;
+USE CS457_ISOLATION;
+create table Seats(seat int, status int);
+insert into Seats values(22,0);
+set transaction isolation level snapshot;
+set transaction isolation level repeatable read;
+begin transaction;
+set transaction isolation level serializable;
+select * from Seats;
@@ -0,0 +1,4 @@
+commit;
+set transaction isolation level read committed;
+begin transaction;
+select * from Seats;
@@ -0,0 +1,7 @@
+USE CS457_ISOLATION;
+select * from Seats;
+update Seats set status = 2 where seat = 22;
+set transaction isolation level serializable;
+begin transaction;
+select * from Seats;
+commit;
@@ -0,0 +1,58 @@
+-- Table locks
+
+-- This script includes the commands to be executed by two processes, P1 and P2
+
+-- On P1:
+CREATE DATABASE CS457_LOCKS;
+USE CS457_LOCKS;
+create table Seats(seat int, status int);
+insert into Seats values(22,0);
+update Later set status = 1; -- fails before it locks anything
+begin transaction;
+update Seats set status = 1 where seat = 22;
+
+-- On P2:
+USE CS457_LOCKS;
+create table Later(seat int, status int);
+insert into Later values(1,0); -- Later isn't locked by P1's failed update
+begin transaction;
+update Seats set status = 2 where seat = 22;
+commit; --there should be nothing to commit; it's an "abort"
+select * from Seats;
+
+-- On P1:
+commit;
+select * from Seats;
+
+-- On P2:
+update Seats set status = 2 where seat = 22;
+select * from Seats;
+
+---------------------
+-- Expected output --
+---------------------
+
+-- On P1:
+-- Database CS457_LOCKS created.
+-- Using database CS457_LOCKS
+-- Table Seats created.
+-- 1 new record inserted.
+-- !Failed to query table Later because it does not exist.
+-- Transaction starts.
+-- 1 record(s) modified.
+-- Transaction committed.
+-- seat int|status int
+-- 22|1
+
+-- On P2:
+-- Using database CS457_LOCKS
+-- Table Later created.
+-- 1 new record inserted.
+-- Transaction starts.
+-- Error: Table Seats is locked!
+-- Transaction abort.
+-- seat int|status int
+-- 22|0
+-- 1 record(s) modified.
+-- seat int|status int
+-- 22|2
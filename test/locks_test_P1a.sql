@@ -0,0 +1,7 @@
+CREATE DATABASE CS457_LOCKS;
+USE CS457_LOCKS;
+create table Seats(seat int, status int);
+insert into Seats values(22,0);
+update Later set status = 1;
+begin transaction;
+update Seats set status = 1 where seat = 22;
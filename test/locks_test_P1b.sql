@@ -0,0 +1,2 @@
+commit;
+select * from Seats;
@@ -0,0 +1,7 @@
+USE CS457_LOCKS;
+create table Later(seat int, status int);
+insert into Later values(1,0);
+begin transaction;
+update Seats set status = 2 where seat = 22;
+commit;
+select * from Seats;
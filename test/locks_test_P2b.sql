@@ -0,0 +1,2 @@
+update Seats set status = 2 where seat = 22;
+select * from Seats;
@@ -0,0 +1,76 @@
+-- LIKE, GLOB and REGEXP
+
+CREATE DATABASE CS457_PATTERN;
+USE CS457_PATTERN;
+CREATE TABLE Product (pid int, name varchar(20), code varchar(10));
+INSERT INTO Product VALUES (1, 'Gizmo', 'A-100%'), (2, 'PowerGizmo', 'B-200'), (3, 'SingleTouch', 'c-300'), (4, 'MultiTouch2', NULL);
+SELECT pid FROM Product WHERE name LIKE '%gizmo';
+SELECT pid FROM Product WHERE name LIKE '_izm_';
+SELECT pid FROM Product WHERE code LIKE '%!%' ESCAPE '!';
+SELECT pid FROM Product WHERE name NOT LIKE '%Touch%';
+SELECT pid FROM Product WHERE code NOT LIKE '%' OR code IS NULL;
+SELECT pid FROM Product WHERE name GLOB '*Touch*';
+SELECT pid FROM Product WHERE name GLOB '*touch*';
+SELECT pid FROM Product WHERE code GLOB '[A-B]-?00*';
+SELECT pid FROM Product WHERE code GLOB '[^A-B]*';
+SELECT pid FROM Product WHERE name REGEXP '[0-9]+$';
+SELECT pid FROM Product WHERE name REGEXP '^(Power|Single)';
+SELECT pid FROM Product WHERE name NOT REGEXP 'o';
+SELECT name, code LIKE 'a%', code GLOB 'a*', code REGEXP '^[a-c]' FROM Product ORDER BY pid;
+UPDATE Product SET code = 'none' WHERE code IS NULL AND name REGEXP 'Touch';
+DELETE FROM Product WHERE name NOT LIKE 'gizmo%' AND name REGEXP 'izmo$';
+SELECT * FROM Product;
+SELECT pid FROM Product WHERE name REGEXP '(';
+SELECT pid FROM Product WHERE name LIKE 'a' ESCAPE 'ab';
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_PATTERN created.
+-- Using database CS457_PATTERN
+-- Table Product created.
+-- 4 new records inserted.
+-- pid int
+-- 1
+-- 2
+-- pid int
+-- 1
+-- pid int
+-- 1
+-- pid int
+-- 1
+-- 2
+-- pid int
+-- 4
+-- pid int
+-- 3
+-- 4
+-- pid int
+-- pid int
+-- 1
+-- 2
+-- pid int
+-- 3
+-- pid int
+-- 4
+-- pid int
+-- 2
+-- 3
+-- pid int
+-- name varchar(20)|code LIKE 'a%' int|code GLOB 'a*' int|code REGEXP '^[a-c]' int
+-- Gizmo|1|0|0
+-- PowerGizmo|0|0|0
+-- SingleTouch|0|0|1
+-- MultiTouch2|||
+-- 1 record(s) modified.
+-- 1 record(s) deleted.
+-- pid int|name varchar(20)|code varchar(10)
+-- 1|Gizmo|A-100%
+-- 3|SingleTouch|c-300
+-- 4|MultiTouch2|none
+-- pid int
+-- !Failed to query because '(' is not a valid regular expression.
+-- pid int
+-- !Failed to query because the ESCAPE of LIKE has to be a single character.
+-- All done.
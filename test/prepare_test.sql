@@ -0,0 +1,49 @@
+-- Prepared statements
+
+-- Bound values are stored as they are, even with the characters records are written with
+CREATE DATABASE CS457_PREPARE;
+USE CS457_PREPARE;
+CREATE TABLE Note (id int, body varchar(40));
+PREPARE add AS INSERT INTO Note VALUES (?, ?);
+EXECUTE add(1, 'a|b');
+EXECUTE add(2, 'back\slash');
+EXECUTE add(3, 'ends with \');
+EXECUTE add(4, '\N');
+EXECUTE add(5);
+SELECT * FROM Note;
+PREPARE find AS SELECT id FROM Note WHERE body = ?;
+EXECUTE find('a|b');
+EXECUTE find('back\slash');
+EXECUTE missing(1);
+UPDATE Note SET body = 'c|d' WHERE id = 1;
+SELECT * FROM Note;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_PREPARE created.
+-- Using database CS457_PREPARE
+-- Table Note created.
+-- Statement add prepared.
+-- 1 new record inserted.
+-- 1 new record inserted.
+-- 1 new record inserted.
+-- !Failed to write '\N' to column body because it's how NULL is persisted.
+-- !Failed to execute statement because it takes 2 parameter(s), not 1.
+-- id int|body varchar(40)
+-- 1|a|b
+-- 2|back\slash
+-- 3|ends with \
+-- Statement find prepared.
+-- id int
+-- 1
+-- id int
+-- 2
+-- !Failed to execute statement missing because it does not exist.
+-- 1 record(s) modified.
+-- id int|body varchar(40)
+-- 1|c|d
+-- 2|back\slash
+-- 3|ends with \
+-- All done.
@@ -0,0 +1,57 @@
+-- Queries
+
+CREATE DATABASE CS457_QUERY;
+USE CS457_QUERY;
+CREATE TABLE Employee (id int, name varchar(10), dept int, salary float);
+CREATE TABLE Dept (id int, title varchar(10));
+INSERT INTO Employee VALUES (1, 'Joe', 1, 50000), (2, 'Amy', 1, 65000), (3, 'Gus', 2, 42000), (4, 'Zed', 3, 30000), (5, 'Bea', 2, NULL);
+INSERT INTO Dept VALUES (1, 'Sales'), (2, 'Ops'), (4, 'Legal');
+SELECT name, salary * 1.1 AS raised FROM Employee WHERE salary >= 42000 AND dept != 3 ORDER BY raised DESC;
+SELECT e.name, d.title FROM Employee e, Dept d WHERE e.dept = d.id ORDER BY e.id;
+SELECT e.name, d.title FROM Employee e LEFT OUTER JOIN Dept d ON e.dept = d.id ORDER BY 1;
+SELECT dept, COUNT(*), COUNT(salary), AVG(salary), MAX(name) FROM Employee GROUP BY dept HAVING COUNT(*) > 1 ORDER BY dept;
+SELECT name FROM Employee WHERE salary IS NULL OR name || '!' = 'Zed!';
+SELECT DISTINCT dept FROM Employee ORDER BY dept;
+SELECT nope FROM Employee;
+SELECT name FROM Missing;
+SELECT name FROM Employee WHERE;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_QUERY created.
+-- Using database CS457_QUERY
+-- Table Employee created.
+-- Table Dept created.
+-- 5 new records inserted.
+-- 3 new records inserted.
+-- name varchar(10)|raised float
+-- Amy|71500
+-- Joe|55000
+-- Gus|46200
+-- name varchar(10)|title varchar(10)
+-- Joe|Sales
+-- Amy|Sales
+-- Gus|Ops
+-- Bea|Ops
+-- name varchar(10)|title varchar(10)
+-- Amy|Sales
+-- Bea|Ops
+-- Gus|Ops
+-- Joe|Sales
+-- Zed|
+-- dept int|COUNT(*) int|COUNT(salary) int|AVG(salary) float|MAX(name) varchar(10)
+-- 1|2|2|57500|Joe
+-- 2|2|1|42000|Gus
+-- name varchar(10)
+-- Zed
+-- Bea
+-- dept int
+-- 1
+-- 2
+-- 3
+-- !Failed to query because column nope does not exist.
+-- !Failed to query table Missing because it does not exist.
+-- !Failed to parse query because it ends unexpectedly.
+-- All done.
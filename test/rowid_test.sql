@@ -0,0 +1,49 @@
+-- UPDATE and DELETE by row
+
+-- Records are found by their WHERE, and then only those records are rewritten, wherever identical ones are
+CREATE DATABASE CS457_ROWID;
+USE CS457_ROWID;
+CREATE TABLE Seat (row int, seat int, status varchar(10), note varchar(10));
+INSERT INTO Seat VALUES (1, 1, 'free', 'aisle'), (1, 2, 'free', 'aisle'), (2, 1, 'free', 'aisle'), (2, 1, 'free', 'aisle');
+UPDATE Seat SET status = 'taken' WHERE row = 1 AND seat = 2;
+UPDATE Seat SET note = 'window' WHERE status = 'taken';
+SELECT * FROM Seat;
+DELETE FROM Seat WHERE row = 2 AND seat = 1;
+SELECT * FROM Seat;
+UPDATE Seat SET status = 'free' WHERE row = 9;
+DELETE FROM Seat WHERE note = 'none';
+UPDATE Seat SET seat = seat + 10, note = status WHERE status = 'free' OR note = 'window';
+SELECT * FROM Seat;
+DELETE FROM Seat WHERE 1 / 0 = 1;
+SELECT * FROM Seat;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_ROWID created.
+-- Using database CS457_ROWID
+-- Table Seat created.
+-- 4 new records inserted.
+-- 1 record(s) modified.
+-- 1 record(s) modified.
+-- row int|seat int|status varchar(10)|note varchar(10)
+-- 1|1|free|aisle
+-- 1|2|taken|window
+-- 2|1|free|aisle
+-- 2|1|free|aisle
+-- 2 record(s) deleted.
+-- row int|seat int|status varchar(10)|note varchar(10)
+-- 1|1|free|aisle
+-- 1|2|taken|window
+-- 0 record(s) modified.
+-- 0 record(s) deleted.
+-- 2 record(s) modified.
+-- row int|seat int|status varchar(10)|note varchar(10)
+-- 1|11|free|free
+-- 1|12|taken|taken
+-- 0 record(s) deleted.
+-- row int|seat int|status varchar(10)|note varchar(10)
+-- 1|11|free|free
+-- 1|12|taken|taken
+-- All done.
@@ -0,0 +1,92 @@
+-- SAVEPOINT, RELEASE and ROLLBACK TO
+
+CREATE DATABASE CS457_SAVEPOINT;
+USE CS457_SAVEPOINT;
+CREATE TABLE Account (id int, balance int);
+INSERT INTO Account VALUES (1, 100), (2, 50);
+SAVEPOINT outside;
+BEGIN TRANSACTION;
+UPDATE Account SET balance = balance - 10 WHERE id = 1;
+SAVEPOINT s1;
+UPDATE Account SET balance = balance + 10 WHERE id = 2;
+SAVEPOINT s2;
+DELETE FROM Account WHERE id = 2;
+ROLLBACK TO s2;
+ROLLBACK TO s2;
+INSERT INTO Account VALUES (3, 0);
+RELEASE s2;
+COMMIT;
+SELECT * FROM Account;
+BEGIN TRANSACTION;
+UPDATE Account SET balance = 0;
+SAVEPOINT s1;
+RELEASE s1;
+ROLLBACK TO s1;
+COMMIT;
+SELECT * FROM Account;
+BEGIN TRANSACTION;
+SAVEPOINT again;
+INSERT INTO Account VALUES (4, 0);
+SAVEPOINT again;
+INSERT INTO Account VALUES (5, 0);
+ROLLBACK TO again;
+RELEASE again;
+COMMIT;
+SELECT * FROM Account;
+BEGIN TRANSACTION;
+DELETE FROM Account;
+ROLLBACK;
+SELECT * FROM Account;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_SAVEPOINT created.
+-- Using database CS457_SAVEPOINT
+-- Table Account created.
+-- 2 new records inserted.
+-- !Failed to use savepoint outside because no transaction is in progress.
+-- Transaction starts.
+-- Savepoint s1 created.
+-- Savepoint s2 created.
+-- Rolled back to savepoint s2, 1 operation(s) discarded.
+-- Rolled back to savepoint s2, 0 operation(s) discarded.
+-- Savepoint s2 released.
+-- 1 record(s) modified.
+-- 1 record(s) modified.
+-- 1 new record inserted.
+-- Transaction committed.
+-- id int|balance int
+-- 1|90
+-- 2|60
+-- 3|0
+-- Transaction starts.
+-- Savepoint s1 created.
+-- Savepoint s1 released.
+-- !Failed to use savepoint s1 because it does not exist.
+-- Transaction abort.
+-- id int|balance int
+-- 1|90
+-- 2|60
+-- 3|0
+-- Transaction starts.
+-- Savepoint again created.
+-- Savepoint again created.
+-- Rolled back to savepoint again, 1 operation(s) discarded.
+-- Savepoint again released.
+-- 1 new record inserted.
+-- Transaction committed.
+-- id int|balance int
+-- 1|90
+-- 2|60
+-- 3|0
+-- 4|0
+-- Transaction starts.
+-- Transaction rolled back.
+-- id int|balance int
+-- 1|90
+-- 2|60
+-- 3|0
+-- 4|0
+-- All done.
@@ -0,0 +1,56 @@
+-- UNION, INTERSECT and EXCEPT
+
+CREATE DATABASE CS457_SETOP;
+USE CS457_SETOP;
+CREATE TABLE A (n int, name varchar(20), day date);
+CREATE TABLE B (x float, label varchar(20), at timestamp);
+INSERT INTO A VALUES (1, 'one', '2019-04-01'), (2, 'two', '2019-04-02'), (2, 'two', '2019-04-02');
+INSERT INTO B VALUES (2, 'two', '2019-04-02 10:00'), (3.5, 'three', '2019-04-03 10:00');
+SELECT n, name FROM A UNION SELECT x, label FROM B ORDER BY 1;
+SELECT n, name FROM A UNION ALL SELECT x, label FROM B;
+SELECT n FROM A INTERSECT SELECT x FROM B;
+SELECT n FROM A EXCEPT SELECT x FROM B;
+SELECT name FROM A INTERSECT ALL SELECT name FROM A;
+SELECT n, name FROM A UNION SELECT x FROM B;
+SELECT n FROM A UNION SELECT label FROM B;
+SELECT day FROM A UNION SELECT at FROM B;
+SELECT day FROM A UNION SELECT n FROM A;
+SELECT day FROM A UNION SELECT CAST(at AS date) FROM B ORDER BY day;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_SETOP created.
+-- Using database CS457_SETOP
+-- Table A created.
+-- Table B created.
+-- 3 new records inserted.
+-- 2 new records inserted.
+-- n float|name varchar(20)
+-- 1|one
+-- 2|two
+-- 3.5|three
+-- n float|name varchar(20)
+-- 1|one
+-- 2|two
+-- 2|two
+-- 2|two
+-- 3.5|three
+-- n float
+-- 2
+-- n float
+-- 1
+-- name varchar(20)
+-- one
+-- two
+-- two
+-- !Failed to query because the queries of UNION select 2 and 1 columns.
+-- !Failed to query because column n (int) of UNION can't be combined with label (varchar(20)).
+-- !Failed to query because column day (date) of UNION can't be combined with at (timestamp).
+-- !Failed to query because column day (date) of UNION can't be combined with n (int).
+-- day date
+-- 2019-04-01
+-- 2019-04-02
+-- 2019-04-03
+-- All done.
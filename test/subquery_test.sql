@@ -0,0 +1,66 @@
+-- Subqueries
+
+CREATE DATABASE CS457_SUBQUERY;
+USE CS457_SUBQUERY;
+CREATE TABLE Employee (id int, name varchar(10), dept int, salary float);
+CREATE TABLE Dept (id int, title varchar(10));
+INSERT INTO Employee VALUES (1, 'Joe', 1, 50000), (2, 'Amy', 1, 65000), (3, 'Gus', 2, 42000), (4, 'Zed', 3, 30000);
+INSERT INTO Dept VALUES (1, 'Sales'), (2, 'Ops'), (4, 'Legal');
+SELECT name FROM Employee WHERE salary = (SELECT MAX(salary) FROM Employee);
+SELECT name, (SELECT title FROM Dept WHERE Dept.id = Employee.dept) AS title FROM Employee ORDER BY id;
+SELECT name FROM Employee E WHERE salary = (SELECT MAX(salary) FROM Employee F WHERE F.dept = E.dept) ORDER BY name;
+SELECT name FROM Employee WHERE dept IN (SELECT id FROM Dept) ORDER BY name;
+SELECT name FROM Employee WHERE dept NOT IN (SELECT id FROM Dept);
+SELECT name FROM Employee WHERE id IN (1, 3);
+SELECT title FROM Dept D WHERE EXISTS (SELECT * FROM Employee E WHERE E.dept = D.id) ORDER BY title;
+SELECT title FROM Dept D WHERE NOT EXISTS (SELECT * FROM Employee E WHERE E.dept = D.id);
+SELECT t.dept, t.total FROM (SELECT dept, SUM(salary) AS total FROM Employee GROUP BY dept) AS t WHERE t.total > 40000 ORDER BY t.dept;
+SELECT name FROM Employee WHERE salary = (SELECT salary FROM Employee);
+SELECT * FROM (SELECT name FROM Employee);
+SELECT name FROM Employee WHERE dept IN (SELECT id, title FROM Dept);
+SELECT name FROM Employee WHERE dept IN (SELECT id FROM Missing);
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_SUBQUERY created.
+-- Using database CS457_SUBQUERY
+-- Table Employee created.
+-- Table Dept created.
+-- 4 new records inserted.
+-- 3 new records inserted.
+-- name varchar(10)
+-- Amy
+-- name varchar(10)|title varchar(10)
+-- Joe|Sales
+-- Amy|Sales
+-- Gus|Ops
+-- Zed|
+-- name varchar(10)
+-- Amy
+-- Gus
+-- Zed
+-- name varchar(10)
+-- Amy
+-- Gus
+-- Joe
+-- name varchar(10)
+-- Zed
+-- name varchar(10)
+-- Joe
+-- Gus
+-- title varchar(10)
+-- Ops
+-- Sales
+-- title varchar(10)
+-- Legal
+-- dept int|total float
+-- 1|115000
+-- 2|42000
+-- name varchar(10)
+-- !Failed to query because subquery (SELECT salary FROM Employee) returned more than one row.
+-- !Failed to parse query because a subquery in FROM needs an alias.
+-- !Failed to query because subquery dept IN (SELECT id, title FROM Dept) has to select one column.
+-- !Failed to query table Missing because it does not exist.
+-- All done.
@@ -0,0 +1,60 @@
+-- UPDATE
+
+CREATE DATABASE CS457_UPDATE;
+USE CS457_UPDATE;
+CREATE TABLE Product (pid int, name varchar(20), price float);
+CREATE TABLE Sale (pid int, price float);
+INSERT INTO Product VALUES (1, 'Gizmo', 19.99), (2, 'PowerGizmo', 29.99), (3, 'SingleTouch', 149.99), (4, 'MultiTouch', 199.99);
+INSERT INTO Sale VALUES (1, 9.99), (3, 99.99);
+UPDATE Product SET price = price * 2, name = UPPER(name) WHERE pid = 2;
+UPDATE Product SET name = 'Tiny' WHERE price < 100 AND name LIKE '%gizmo';
+UPDATE Product SET price = (SELECT s.price FROM Sale s WHERE s.pid = Product.pid) WHERE pid IN (SELECT pid FROM Sale);
+UPDATE Product SET price = (SELECT MAX(price) FROM Product) WHERE NOT EXISTS (SELECT * FROM Sale WHERE Sale.pid = Product.pid) AND pid > 3;
+SELECT * FROM Product;
+PREPARE reprice AS UPDATE Product SET price = ? WHERE pid IN (SELECT pid FROM Sale WHERE price < ?);
+EXECUTE reprice(5, 50);
+SELECT * FROM Product;
+UPDATE Product SET nope = 1;
+UPDATE Product SET price = 1 WHERE nope = 1;
+UPDATE Product SET price = 1 WHERE pid IN (SELECT pid FROM Missing);
+UPDATE Product SET price = (SELECT pid, price FROM Sale);
+UPDATE Missing SET price = 1;
+SELECT * FROM Product;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_UPDATE created.
+-- Using database CS457_UPDATE
+-- Table Product created.
+-- Table Sale created.
+-- 4 new records inserted.
+-- 2 new records inserted.
+-- 1 record(s) modified.
+-- 2 record(s) modified.
+-- 2 record(s) modified.
+-- 1 record(s) modified.
+-- pid int|name varchar(20)|price float
+-- 1|Tiny|9.99
+-- 2|Tiny|59.98
+-- 3|SingleTouch|99.99
+-- 4|MultiTouch|199.99
+-- Statement reprice prepared.
+-- 1 record(s) modified.
+-- pid int|name varchar(20)|price float
+-- 1|Tiny|5
+-- 2|Tiny|59.98
+-- 3|SingleTouch|99.99
+-- 4|MultiTouch|199.99
+-- !Failed to update table Product because it has no column nope.
+-- !Failed to query because column nope does not exist.
+-- !Failed to query table Missing because it does not exist.
+-- !Failed to query because subquery (SELECT pid, price FROM Sale) has to select one column.
+-- !Failed to query table Missing because it does not exist.
+-- pid int|name varchar(20)|price float
+-- 1|Tiny|5
+-- 2|Tiny|59.98
+-- 3|SingleTouch|99.99
+-- 4|MultiTouch|199.99
+-- All done.
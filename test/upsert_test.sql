@@ -0,0 +1,75 @@
+-- ON CONFLICT
+
+CREATE DATABASE CS457_UPSERT;
+USE CS457_UPSERT;
+CREATE TABLE Stock (pid int, name varchar(20), qty int);
+INSERT INTO Stock VALUES (1, 'Gizmo', 5), (2, 'PowerGizmo', 1);
+INSERT INTO Stock VALUES (1, 'Gizmo', 3) ON CONFLICT (pid) DO NOTHING;
+INSERT INTO Stock VALUES (1, 'Gizmo', 3), (3, 'SingleTouch', 2) ON CONFLICT (pid) DO UPDATE SET qty = qty + excluded.qty;
+INSERT INTO Stock VALUES (4, 'MultiTouch', 1), (4, 'MultiTouch', 6), (4, 'MultiTouch', 2) ON CONFLICT (pid) DO UPDATE SET qty = qty + excluded.qty;
+INSERT INTO Stock VALUES (5, 'SuperGizmo', 1), (5, 'SuperGizmo', 9) ON CONFLICT (pid) DO NOTHING;
+INSERT INTO Stock VALUES (2, 'PowerGizmo', 7) ON CONFLICT (pid) DO UPDATE SET qty = excluded.qty WHERE excluded.qty < qty;
+INSERT INTO Stock VALUES (2, 'PowerGizmo', 0) ON CONFLICT (pid) DO UPDATE SET qty = excluded.qty WHERE excluded.qty < qty;
+SELECT * FROM Stock;
+INSERT OR REPLACE INTO Stock VALUES (1, 'Gizmo', 0), (6, 'Widget', 1), (6, 'Widget', 1);
+INSERT OR REPLACE INTO Stock VALUES (1, 'Gizmo', 8), (7, 'Gadget', NULL), (7, 'Gadget', NULL);
+INSERT OR IGNORE INTO Stock VALUES (1, 'Gizmo', 8), (6, 'Widget', 1), (8, 'Doohickey', 1), (8, 'Doohickey', 1);
+SELECT * FROM Stock;
+INSERT OR REPLACE INTO Stock VALUES (1, 'Gizmo', 0) ON CONFLICT (pid) DO NOTHING;
+INSERT OR UPDATE INTO Stock VALUES (1, 'Gizmo', 0);
+INSERT INTO Stock VALUES (1, 'Gizmo', 0) ON CONFLICT DO NOTHING;
+INSERT INTO Stock VALUES (1, 'Gizmo', 0) ON CONFLICT (nope) DO NOTHING;
+INSERT INTO Stock VALUES (1, 'Gizmo', 0) ON CONFLICT (pid) DO UPDATE SET nope = 1;
+SELECT * FROM Stock;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_UPSERT created.
+-- Using database CS457_UPSERT
+-- Table Stock created.
+-- 2 new records inserted.
+-- 0 new records inserted.
+-- 1 new record inserted. 1 record(s) modified.
+-- 1 new record inserted. 2 record(s) modified.
+-- 1 new record inserted.
+-- 0 new records inserted. 0 record(s) modified.
+-- 0 new records inserted. 1 record(s) modified.
+-- pid int|name varchar(20)|qty int
+-- 1|Gizmo|8
+-- 2|PowerGizmo|0
+-- 3|SingleTouch|2
+-- 4|MultiTouch|9
+-- 5|SuperGizmo|1
+-- 2 new records inserted. 1 record(s) modified.
+-- 2 new records inserted. 1 record(s) modified.
+-- 1 new record inserted.
+-- pid int|name varchar(20)|qty int
+-- 1|Gizmo|8
+-- 2|PowerGizmo|0
+-- 3|SingleTouch|2
+-- 4|MultiTouch|9
+-- 5|SuperGizmo|1
+-- 1|Gizmo|0
+-- 6|Widget|1
+-- 7|Gadget|
+-- 7|Gadget|
+-- 8|Doohickey|1
+-- !Failed to parse query because INSERT OR REPLACE and INSERT OR IGNORE can't have an ON CONFLICT too.
+-- !Failed to parse query because of unexpected UPDATE.
+-- !Failed to parse query because ON CONFLICT has to name the columns that conflict.
+-- !Failed to insert into table Stock because it has no column nope.
+-- !Failed to update table Stock because it has no column nope.
+-- pid int|name varchar(20)|qty int
+-- 1|Gizmo|8
+-- 2|PowerGizmo|0
+-- 3|SingleTouch|2
+-- 4|MultiTouch|9
+-- 5|SuperGizmo|1
+-- 1|Gizmo|0
+-- 6|Widget|1
+-- 7|Gadget|
+-- 7|Gadget|
+-- 8|Doohickey|1
+-- All done.
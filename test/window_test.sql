@@ -0,0 +1,47 @@
+-- Window functions
+
+CREATE DATABASE CS457_WINDOW;
+USE CS457_WINDOW;
+CREATE TABLE Employee (id int, name varchar(10), dept int, salary float);
+INSERT INTO Employee VALUES (1, 'Joe', 1, 50000), (2, 'Amy', 1, 65000), (3, 'Gus', 2, 42000), (4, 'Zed', 2, 42000), (5, 'Bea', 2, 70000);
+SELECT name, dept, ROW_NUMBER() OVER (PARTITION BY dept ORDER BY salary DESC) AS n, RANK() OVER (ORDER BY salary DESC) AS r, DENSE_RANK() OVER (ORDER BY salary DESC) AS d FROM Employee ORDER BY id;
+SELECT name, LAG(name) OVER (ORDER BY id) AS before, LEAD(name, 2, 'none') OVER (ORDER BY id) AS after FROM Employee ORDER BY id;
+SELECT name, SUM(salary) OVER (ORDER BY id) AS running, SUM(salary) OVER (PARTITION BY dept) AS total, AVG(salary) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS smooth FROM Employee ORDER BY id;
+SELECT dept, COUNT(*), RANK() OVER (ORDER BY COUNT(*) DESC) FROM Employee GROUP BY dept ORDER BY dept;
+SELECT name FROM Employee WHERE ROW_NUMBER() OVER (ORDER BY id) = 1;
+SELECT SUM(salary) OVER (ORDER BY id RANGE BETWEEN 1 PRECEDING AND CURRENT ROW) FROM Employee;
+SELECT SUM(salary) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED FOLLOWING AND CURRENT ROW) FROM Employee;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_WINDOW created.
+-- Using database CS457_WINDOW
+-- Table Employee created.
+-- 5 new records inserted.
+-- name varchar(10)|dept int|n int|r int|d int
+-- Joe|1|2|3|3
+-- Amy|1|1|2|2
+-- Gus|2|2|4|4
+-- Zed|2|3|4|4
+-- Bea|2|1|1|1
+-- name varchar(10)|before varchar(10)|after varchar(10)
+-- Joe||Gus
+-- Amy|Joe|Zed
+-- Gus|Amy|Bea
+-- Zed|Gus|none
+-- Bea|Zed|none
+-- name varchar(10)|running float|total float|smooth float
+-- Joe|50000|115000|57500
+-- Amy|115000|115000|52333.3333333333
+-- Gus|157000|154000|49666.6666666667
+-- Zed|199000|154000|51333.3333333333
+-- Bea|269000|154000|56000
+-- dept int|COUNT(*) int|RANK() OVER (ORDER BY COUNT(*) DESC) int
+-- 1|2|2
+-- 2|3|1
+-- !Failed to query because window functions can't be used in WHERE.
+-- !Failed to parse query because only ROWS frames are supported.
+-- !Failed to parse query because ROWS BETWEEN UNBOUNDED FOLLOWING AND CURRENT ROW is backwards.
+-- All done.
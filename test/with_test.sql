@@ -0,0 +1,49 @@
+-- WITH and WITH RECURSIVE
+
+CREATE DATABASE CS457_WITH;
+USE CS457_WITH;
+CREATE TABLE Org (id int, name varchar(10), boss int);
+INSERT INTO Org VALUES (1, 'Ann', 0), (2, 'Bob', 1), (3, 'Cat', 1), (4, 'Dan', 2), (5, 'Eve', 4);
+WITH Managers AS (SELECT DISTINCT boss FROM Org) SELECT name FROM Org WHERE id IN (SELECT boss FROM Managers) ORDER BY name;
+WITH Reports (boss, total) AS (SELECT boss, COUNT(*) FROM Org GROUP BY boss), Busy AS (SELECT boss FROM Reports WHERE total > 1) SELECT o.name FROM Org o, Busy b WHERE o.id = b.boss;
+WITH RECURSIVE chain(id, name, depth) AS (SELECT id, name, 0 FROM Org WHERE boss = 0 UNION ALL SELECT o.id, o.name, c.depth + 1 FROM Org o, chain c WHERE o.boss = c.id) SELECT name, depth FROM chain ORDER BY depth, name;
+WITH RECURSIVE up(id, name) AS (SELECT id, name FROM Org WHERE name = 'Eve' UNION SELECT o.id, o.name FROM Org o, up u, Org c WHERE c.id = u.id AND o.id = c.boss) SELECT name FROM up ORDER BY id;
+WITH RECURSIVE counter(n) AS (SELECT id FROM Org WHERE id = 1 UNION SELECT n % 3 + 1 FROM counter) SELECT n FROM counter ORDER BY n;
+WITH RECURSIVE forever(n) AS (SELECT id FROM Org WHERE id = 1 UNION ALL SELECT n + 1 FROM forever) SELECT COUNT(*) FROM forever;
+WITH RECURSIVE bad(n) AS (SELECT n FROM bad) SELECT * FROM bad;
+WITH Missing AS (SELECT * FROM Nope) SELECT * FROM Missing;
+
+.EXIT
+
+-- Expected output
+--
+-- Database CS457_WITH created.
+-- Using database CS457_WITH
+-- Table Org created.
+-- 5 new records inserted.
+-- name varchar(10)
+-- Ann
+-- Bob
+-- Dan
+-- name varchar(10)
+-- Ann
+-- name varchar(10)|depth int
+-- Ann|0
+-- Bob|1
+-- Cat|1
+-- Dan|2
+-- Eve|3
+-- name varchar(10)
+-- Ann
+-- Bob
+-- Dan
+-- Eve
+-- n int
+-- 1
+-- 2
+-- 3
+-- COUNT(*) int
+-- !Failed to query because forever was still recursing after 10000 steps.
+-- !Failed to query because recursive bad has to be a query that doesn't read bad, UNION [ALL] a query that does.
+-- !Failed to query table Nope because it does not exist.
+-- All done.
@@ -0,0 +1,149 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package tokenizer
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Lexeme classes, the Names of the tokens Lex produces
+const (
+	Word   = "WORD"
+	Number = "NUMBER"
+	String = "STRING"
+	Symbol = "SYMBOL"
+
+	// Parameter is a placeholder for a value bound when a prepared statement is executed,
+	// its Special is ? or $ and a number
+	Parameter = "PARAMETER"
+)
+
+// symbols are matched longest first
+var symbols = []string{"<=", ">=", "!=", "<>", "||", "=", "<", ">", "(", ")", ",", ".", "*", "+", "-", "/", "%", ";"}
+
+// Lex breaks a string of SQL into lexemes for the query parser. Unlike TokenizeStatement
+// it understands quoting, so words, numbers, 'string literals' and symbols each come out whole.
+// A string literal's Special is its unquoted contents
+func Lex(rawStatement string) ([]Token, error) {
+	var tokens []Token
+	runes := []rune(rawStatement)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		// an inline comment runs to the end of the line
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '\'':
+			literal, end, err := lexQuoted(runes, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Name: String, Special: literal})
+			i = end
+
+		// a double quoted identifier is a word, whatever it contains
+		case r == '"':
+			identifier, end, err := lexQuoted(runes, i, '"')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Name: Word, Special: identifier})
+			i = end
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			if i < len(runes) && runes[i] == '.' {
+				i++
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, Token{Name: Number, Special: string(runes[start:i])})
+
+		case r == '?':
+			tokens = append(tokens, Token{Name: Parameter, Special: "?"})
+			i++
+
+		case r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			start := i
+			i++
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, Token{Name: Parameter, Special: string(runes[start:i])})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, Token{Name: Word, Special: string(runes[start:i])})
+
+		default:
+			// no symbol is longer than two characters, so the rest of the statement isn't copied to match one
+			end := i + 2
+			if end > len(runes) {
+				end = len(runes)
+			}
+
+			symbol := matchSymbol(string(runes[i:end]))
+			if symbol == "" {
+				return nil, errors.New("!Failed to read statement because " + string(r) + " is not recognized.")
+			}
+			tokens = append(tokens, Token{Name: Symbol, Special: symbol})
+			i += len([]rune(symbol))
+		}
+	}
+
+	// a trailing delimiter isn't part of the statement
+	if len(tokens) > 0 && tokens[len(tokens)-1].Special == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	return tokens, nil
+}
+
+// lexQuoted reads a quoted lexeme starting at runes[start], a doubled quote is an escaped quote.
+// It returns the unquoted contents and the index just past the closing quote
+func lexQuoted(runes []rune, start int, quote rune) (string, int, error) {
+	var contents []rune
+
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			contents = append(contents, runes[i])
+			continue
+		}
+
+		if i+1 < len(runes) && runes[i+1] == quote {
+			contents = append(contents, quote)
+			i++
+			continue
+		}
+
+		return string(contents), i + 1, nil
+	}
+
+	return "", 0, errors.New("!Failed to read statement because a quote is never closed.")
+}
+
+func matchSymbol(rest string) string {
+	for _, symbol := range symbols {
+		if strings.HasPrefix(rest, symbol) {
+			return symbol
+		}
+	}
+	return ""
+}
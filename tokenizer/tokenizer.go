@@ -15,7 +15,7 @@ type Token struct {
 }
 
 // Names are general classes for tokens
-var Names = [14]string{
+var Names = [17]string{
 	"CREATE",
 	"DROP",
 	"USE",
@@ -29,22 +29,26 @@ var Names = [14]string{
 	"LITERAL",
 	"BEGIN",
 	"COMMIT",
+	"ROLLBACK",
+	"SAVEPOINT",
+	"RELEASE",
 	"special",
 }
 
-// A Statement is an array of tokens and a general class of meaning
+// A Statement is an array of tokens and a general class of meaning.
+// Raw keeps the SQL it came from, for statements that get a full parse
 type Statement struct {
 	Tokens []Token
 	Type   string
+	Raw    string
 }
 
 // TokenizeStatement breaks a string of SQL into a statement
 func TokenizeStatement(rawStatement string) Statement {
-	fmt.Println(rawStatement)
+	raw := rawStatement
 
 	rawStatement = strings.Replace(rawStatement, "(", " (", 1)
 	rawStatement = strings.Replace(rawStatement, ",", ", ", 1)
-	rawStatement = strings.Replace(rawStatement, ".", " ", 2)
 
 	rawWords := strings.Fields(rawStatement)
 
@@ -56,7 +60,7 @@ func TokenizeStatement(rawStatement string) Statement {
 		tokens = append(tokens, TokenizeWord(rawWord))
 	}
 
-	statement := Statement{Tokens: tokens}
+	statement := Statement{Tokens: tokens, Raw: raw}
 
 	return statement
 }
@@ -0,0 +1,224 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+// Package vm runs statements compiled into bytecode, the way SQLite's virtual
+// machine does. A Program is a list of instructions over numbered registers,
+// which hold values, and numbered cursors, which step through the rows of a
+// table or of an operator pipeline. The generator compiles each statement into
+// a Program, and a VM runs it a row at a time.
+package vm
+
+import (
+	"fmt"
+	"sqlit/diskio"
+	"sqlit/executor"
+	"strconv"
+)
+
+// An Opcode is what an instruction does
+type Opcode uint8
+
+// Every instruction has up to three integer operands and a fourth of any kind. Jumps go to P2,
+// and a result goes in P3 for binary operations and in P2 for everything else. r[n] is register n
+const (
+	// Goto jumps to P2. Halt ends the program
+	Goto Opcode = iota
+	Halt
+
+	// Integer, Real and String load the constant P4 into r[P2], Null loads NULL. Copy copies r[P1] into r[P2]
+	Integer
+	Real
+	String
+	Null
+	Copy
+
+	// OpenRead opens cursor P1 over a table, where P4 is the scan or index scan that reads it.
+	// OpenPipeline opens cursor P1 over the rows of any other operator P4, such as a sort.
+	// OpenWrite opens cursor P1 for writing the table named P4. It can append records, and
+	// step through the table's records to update or delete them
+	OpenRead
+	OpenPipeline
+	OpenWrite
+
+	// Rewind moves cursor P1 to its first row, or jumps to P2 if there isn't one. Next moves
+	// it to its next row and jumps to P2, unless it has run out. NullRow makes every column
+	// of cursor P1 NULL until it moves again. Column reads column P2 of cursor P1 into r[P3]
+	Rewind
+	Next
+	NullRow
+	Column
+
+	// ResultRow hands back r[P1] through r[P1+P2-1] as a row of the result
+	ResultRow
+
+	// Add, Subtract, Multiply, Divide, Remainder and Concat compute r[P1] op r[P2] into r[P3].
+	// Compare compares r[P1] to r[P2] with the operator P4, And and Or combine them
+	Add
+	Subtract
+	Multiply
+	Divide
+	Remainder
+	Concat
+	Compare
+	And
+	Or
+
+	// Not and Negative compute their operator of r[P1] into r[P2], IsNull and NotNull test if it's NULL
+	Not
+	Negative
+	IsNull
+	NotNull
+
+	// If jumps to P2 if r[P1] is true, IfNot if it's false or NULL
+	If
+	IfNot
+
+	// Function evaluates the expression P4 against the row of cursor P1 into r[P2],
+	// for an expression the executor compiled rather than the program
+	Function
+
+	// MakeRecord makes a record of r[P1] through r[P1+P2-1] into r[P3]. Insert appends the record
+	// in r[P2] to the table of write cursor P1. Update sets the fields P4 of the record cursor P1
+	// is at to r[P2] onwards, and Delete deletes it. A program's writes are buffered until it halts,
+	// the records it inserts are appended together and a table it updated is rewritten once
+	MakeRecord
+	Insert
+	Update
+	Delete
+)
+
+var opcodeNames = []string{
+	"Goto", "Halt",
+	"Integer", "Real", "String", "Null", "Copy",
+	"OpenRead", "OpenPipeline", "OpenWrite",
+	"Rewind", "Next", "NullRow", "Column",
+	"ResultRow",
+	"Add", "Subtract", "Multiply", "Divide", "Remainder", "Concat", "Compare", "And", "Or",
+	"Not", "Negative", "IsNull", "NotNull",
+	"If", "IfNot",
+	"Function",
+	"MakeRecord", "Insert", "Update", "Delete",
+}
+
+// String is the opcode's name
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return "Opcode" + strconv.Itoa(int(op))
+}
+
+// An Instruction is one step of a program, Comment says what it's for in a dump
+type Instruction struct {
+	Opcode  Opcode
+	P1      int
+	P2      int
+	P3      int
+	P4      interface{}
+	Comment string
+}
+
+// An Expression is the P4 of a Function instruction, an expression compiled to evaluate a row
+type Expression struct {
+	Expr     string
+	Evaluate func(row []string) (executor.Value, error)
+}
+
+func (e *Expression) String() string {
+	return e.Expr
+}
+
+// A Program is a compiled statement. Columns describes the rows it results in, if any
+type Program struct {
+	Instructions []Instruction
+	Columns      []diskio.ColumnDef
+	Registers    int
+	Cursors      int
+
+	// labels are where forward jumps go, filled in as they're placed
+	labels []int
+}
+
+// Add appends an instruction and returns its address. A jump to a label
+// is written with the label as P2, it's resolved once the label is placed
+func (p *Program) Add(opcode Opcode, p1 int, p2 int, p3 int, p4 interface{}, comment string) int {
+	p.Instructions = append(p.Instructions, Instruction{Opcode: opcode, P1: p1, P2: p2, P3: p3, P4: p4, Comment: comment})
+	return len(p.Instructions) - 1
+}
+
+// NewRegisters allocates n consecutive registers, and returns the first
+func (p *Program) NewRegisters(n int) int {
+	first := p.Registers
+	p.Registers += n
+	return first
+}
+
+// NewCursor allocates a cursor
+func (p *Program) NewCursor() int {
+	p.Cursors++
+	return p.Cursors - 1
+}
+
+// NewLabel creates a label, which stands in for the address of an instruction that hasn't been
+// added yet. Labels are negative, so they can't be mistaken for addresses
+func (p *Program) NewLabel() int {
+	p.labels = append(p.labels, -1)
+	return -len(p.labels)
+}
+
+// Place puts a label at the next instruction to be added
+func (p *Program) Place(label int) {
+	p.labels[-label-1] = len(p.Instructions)
+}
+
+// Address is where the next instruction will be added
+func (p *Program) Address() int {
+	return len(p.Instructions)
+}
+
+// Finish resolves every jump to a label into a jump to its address
+func (p *Program) Finish() {
+	for i := range p.Instructions {
+		instruction := &p.Instructions[i]
+		if jumps(instruction.Opcode) && instruction.P2 < 0 {
+			instruction.P2 = p.labels[-instruction.P2-1]
+		}
+	}
+}
+
+// Dump lists the program one instruction per line, like SQLite's EXPLAIN
+func (p *Program) Dump() []string {
+	lines := []string{fmt.Sprintf("%-4s  %-12s  %-4s  %-4s  %-4s  %-24s  %s", "addr", "opcode", "p1", "p2", "p3", "p4", "comment")}
+
+	for address, instruction := range p.Instructions {
+		p4 := ""
+		switch value := instruction.P4.(type) {
+		case nil:
+		case string:
+			p4 = value
+		case fmt.Stringer:
+			p4 = value.String()
+		case interface{ Explain() string }:
+			p4 = value.Explain()
+		default:
+			p4 = fmt.Sprint(value)
+		}
+
+		lines = append(lines, fmt.Sprintf("%-4d  %-12s  %-4d  %-4d  %-4d  %-24s  %s",
+			address, instruction.Opcode, instruction.P1, instruction.P2, instruction.P3, p4, instruction.Comment))
+	}
+
+	return lines
+}
+
+//
+//			Helper functions
+//
+
+// jumps checks if an opcode's P2 is an address
+func jumps(opcode Opcode) bool {
+	switch opcode {
+	case Goto, Rewind, Next, If, IfNot:
+		return true
+	}
+	return false
+}
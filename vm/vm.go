@@ -0,0 +1,371 @@
+/* UNR CS 457 | SPRING 2019 | emerson@nevada.unr.edu */
+
+package vm
+
+import (
+	"errors"
+	"io"
+	"sqlit/diskio"
+	"sqlit/executor"
+)
+
+// A VM runs a program on behalf of a session. Each call to Next runs it until its next
+// ResultRow, so a query's rows are only computed as they're stepped through
+type VM struct {
+	session   *diskio.Session
+	program   *Program
+	registers []executor.Value
+	cursors   []*cursor
+	pc        int
+	halted    bool
+
+	// Changes counts the records the program inserted, updated or deleted, LastInsertID is the row id of the last one inserted
+	Changes      int
+	LastInsertID int
+}
+
+// a cursor steps through the rows of an operator, or writes a table. A write cursor steps
+// through the table's records with a rewriter, and holds the records inserted until the program halts
+type cursor struct {
+	source   executor.Operator
+	columns  []diskio.ColumnDef
+	row      []string
+	open     bool
+	eof      bool
+	null     bool
+	table    string
+	rewriter *diskio.TableRewriter
+	inserted [][]string
+}
+
+// New readies a program to be run
+func New(session *diskio.Session, program *Program) *VM {
+	return &VM{
+		session:   session,
+		program:   program,
+		registers: make([]executor.Value, program.Registers),
+		cursors:   make([]*cursor, program.Cursors),
+	}
+}
+
+// Next runs the program until it has a row, and returns io.EOF once it halts
+func (m *VM) Next() ([]string, error) {
+	for m.halted == false {
+		row, err := m.step()
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		if row != nil {
+			return row, nil
+		}
+	}
+
+	return nil, io.EOF
+}
+
+// Run runs the program to the end, for statements that don't result in rows
+func (m *VM) Run() error {
+	for {
+		_, err := m.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Close halts the program and closes its cursors, any writes it hasn't made yet are dropped
+func (m *VM) Close() error {
+	m.halted = true
+
+	for _, c := range m.cursors {
+		if c != nil {
+			c.close()
+			c.inserted = nil
+		}
+	}
+	return nil
+}
+
+// halt makes the program's writes and closes it. Each table it updated or deleted from is rewritten, and then
+// the records inserted into each are appended
+func (m *VM) halt() error {
+	for _, c := range m.cursors {
+		if c == nil || c.rewriter == nil {
+			continue
+		}
+
+		err := c.rewriter.Commit()
+		c.rewriter = nil
+		if err != nil {
+			m.Close()
+			return err
+		}
+	}
+
+	for _, c := range m.cursors {
+		if c == nil || len(c.inserted) == 0 {
+			continue
+		}
+
+		id, err := diskio.InsertRecords(m.session, c.table, c.inserted)
+		c.inserted = nil
+		if err != nil {
+			m.Close()
+			return err
+		}
+		m.LastInsertID = id
+	}
+
+	return m.Close()
+}
+
+// step executes one instruction, returning a row if it was a ResultRow
+func (m *VM) step() ([]string, error) {
+	if m.pc >= len(m.program.Instructions) {
+		return nil, m.halt()
+	}
+
+	instruction := m.program.Instructions[m.pc]
+	p1, p2, p3 := instruction.P1, instruction.P2, instruction.P3
+	r := m.registers
+	m.pc++
+
+	switch instruction.Opcode {
+	case Goto:
+		m.pc = p2
+
+	case Halt:
+		err := m.halt()
+		if err != nil {
+			return nil, err
+		}
+
+	case Integer:
+		r[p2] = int64(p1)
+	case Real:
+		r[p2] = instruction.P4.(float64)
+	case String:
+		r[p2] = instruction.P4.(string)
+	case Null:
+		r[p2] = nil
+	case Copy:
+		r[p2] = r[p1]
+
+	case OpenRead, OpenPipeline, OpenWrite:
+		// a cursor opened inside a loop is opened again each time around
+		if c := m.cursors[p1]; c != nil {
+			c.close()
+		}
+
+		if instruction.Opcode == OpenWrite {
+			m.cursors[p1] = &cursor{table: instruction.P4.(string)}
+		} else {
+			m.cursors[p1] = &cursor{source: instruction.P4.(executor.Operator)}
+		}
+
+	case Rewind:
+		c := m.cursors[p1]
+		c.close()
+
+		err := c.rewind(m.session)
+		if err != nil {
+			return nil, err
+		}
+
+		more, err := c.advance()
+		if err != nil {
+			return nil, err
+		}
+		if more == false {
+			m.pc = p2
+		}
+
+	case Next:
+		more, err := m.cursors[p1].advance()
+		if err != nil {
+			return nil, err
+		}
+		if more {
+			m.pc = p2
+		}
+
+	case NullRow:
+		m.cursors[p1].null = true
+
+	case Column:
+		c := m.cursors[p1]
+		if c.null || c.row == nil || p2 >= len(c.row) {
+			r[p3] = nil
+		} else {
+			r[p3] = executor.ReadValue(c.row[p2], c.columns[p2].TypeName)
+		}
+
+	case ResultRow:
+		row := make([]string, p2)
+		for i := range row {
+			row[i] = executor.FormatValue(r[p1+i])
+		}
+		return row, nil
+
+	case Add, Subtract, Multiply, Divide, Remainder:
+		value, err := executor.Arithmetic(arithmeticOperators[instruction.Opcode], r[p1], r[p2])
+		if err != nil {
+			return nil, err
+		}
+		r[p3] = value
+	case Concat:
+		r[p3] = executor.Concat(r[p1], r[p2])
+	case Compare:
+		r[p3] = executor.Compare(instruction.P4.(string), r[p1], r[p2], "")
+	case And:
+		r[p3] = executor.Logic("AND", r[p1], r[p2])
+	case Or:
+		r[p3] = executor.Logic("OR", r[p1], r[p2])
+
+	case Not:
+		r[p2] = executor.Not(r[p1])
+	case Negative:
+		value, err := executor.Negate(r[p1])
+		if err != nil {
+			return nil, err
+		}
+		r[p2] = value
+	case IsNull:
+		r[p2] = boolValue(r[p1] == nil)
+	case NotNull:
+		r[p2] = boolValue(r[p1] != nil)
+
+	case If:
+		if result, known := executor.Truth(r[p1]); known && result {
+			m.pc = p2
+		}
+	case IfNot:
+		if result, known := executor.Truth(r[p1]); known == false || result == false {
+			m.pc = p2
+		}
+
+	case Function:
+		value, err := instruction.P4.(*Expression).Evaluate(m.cursors[p1].row)
+		if err != nil {
+			return nil, err
+		}
+		r[p2] = value
+
+	case MakeRecord:
+		record := make([]string, p2)
+		for i := range record {
+			record[i] = executor.FormatValue(r[p1+i])
+		}
+		r[p3] = record
+
+	case Insert:
+		c := m.cursors[p1]
+		c.inserted = append(c.inserted, r[p2].([]string))
+		m.Changes++
+
+	case Update:
+		c := m.cursors[p1]
+		record := append([]string{}, c.row...)
+		for i, field := range instruction.P4.([]int) {
+			record[field] = executor.FormatValue(r[p2+i])
+		}
+		c.rewriter.Replace(record)
+		m.Changes++
+
+	case Delete:
+		m.cursors[p1].rewriter.Replace(nil)
+		m.Changes++
+
+	default:
+		return nil, errors.New("!Failed to execute statement because opcode " + instruction.Opcode.String() + " isn't supported.")
+	}
+
+	return nil, nil
+}
+
+// rewind opens a cursor at its first row. A write cursor starts a rewrite of its table
+func (c *cursor) rewind(session *diskio.Session) error {
+	if c.table == "" {
+		err := c.source.Open()
+		if err != nil {
+			return err
+		}
+		c.columns = c.source.Columns()
+	} else {
+		rewriter, err := diskio.RewriteTable(session, c.table)
+		if err != nil {
+			return err
+		}
+		c.rewriter = rewriter
+		c.columns, err = diskio.ReadColumnDefs(session, c.table)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.open = true
+	c.eof = false
+	return nil
+}
+
+// close closes a cursor's source, a write cursor throws away the rewrite of its table unless it was committed
+func (c *cursor) close() {
+	if c.open && c.source != nil {
+		c.source.Close()
+	}
+	if c.rewriter != nil {
+		c.rewriter.Close()
+		c.rewriter = nil
+	}
+	c.open = false
+}
+
+// advance moves a cursor to its next row, once it has run out it stays out
+func (c *cursor) advance() (bool, error) {
+	c.null = false
+	if c.eof {
+		return false, nil
+	}
+
+	var row []string
+	var err error
+	if c.rewriter != nil {
+		row, err = c.rewriter.Next()
+	} else {
+		row, err = c.source.Next()
+	}
+	if err == io.EOF {
+		c.eof = true
+		c.row = nil
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	c.row = row
+	return true, nil
+}
+
+//
+//			Helper functions
+//
+
+var arithmeticOperators = map[Opcode]string{
+	Add:       "+",
+	Subtract:  "-",
+	Multiply:  "*",
+	Divide:    "/",
+	Remainder: "%",
+}
+
+func boolValue(b bool) executor.Value {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}